@@ -1,15 +1,22 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
 	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/models"
 	"github.com/armanceau/go-url-shortener/internal/repository"
 	"github.com/armanceau/go-url-shortener/internal/services"
-	"github.com/glebarez/sqlite" // Pure go SQLite driver
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 )
@@ -17,6 +24,40 @@ import (
 // longURLFlag stockera la valeur du flag --url
 var longURLFlag string
 
+// maxUsesFlag stockera la valeur du flag --max-uses (0 = illimité)
+var maxUsesFlag int
+
+// redirectStatusFlag et interstitialFlag stockeront les réglages de redirection du lien
+var (
+	redirectStatusFlag int
+	interstitialFlag   bool
+)
+
+// Flags de transmission du chemin et des paramètres de requête vers l'URL longue
+var (
+	passQueryFlag     bool
+	queryConflictFlag string
+	passPathFlag      bool
+)
+
+// utmFlags stockera les paramètres de campagne UTM du lien
+var utmFlags models.UTMParams
+
+// variantFlags stockera les variantes A/B, au format "name=A,weight=50,url=https://..."
+var (
+	variantFlags      []string
+	stickyVariantFlag bool
+)
+
+// ownerFlag et dedupeFlag stockeront le propriétaire du lien et le choix de réutiliser un lien existant
+var (
+	ownerFlag  string
+	dedupeFlag bool
+)
+
+// ruleFlags stockera les règles de ciblage, au format "os=ios,device=mobile,lang=fr,country=FR|BE,url=https://..."
+var ruleFlags []string
+
 // CreateCmd représente la commande 'create'
 var CreateCmd = &cobra.Command{
 	Use:   "create",
@@ -24,7 +65,20 @@ var CreateCmd = &cobra.Command{
 	Long: `Cette commande raccourcit une URL longue fournie et affiche le code court généré.
 
 Exemple:
-  url-shortener create --url="https://www.google.com/search?q=go+lang"`,
+  url-shortener create --url="https://www.google.com/search?q=go+lang"
+  url-shortener create --url="https://example.com/invitation" --max-uses=1
+  url-shortener create --url="https://example.com" --status=301
+  url-shortener create --url="https://example.com" --domain="go.marque.fr"
+  url-shortener create --url="https://example.com/page?utm_source=x" --owner="marketing" --dedupe
+  url-shortener create --url="https://site-inconnu.example" --interstitial
+  url-shortener create --url="https://docs.example.com" --pass-path --pass-query --query-conflict=request
+  url-shortener create --url="https://shop.example.com" --utm-source=newsletter --utm-medium=email --utm-campaign=soldes
+  url-shortener create --url="https://app.example.com" \
+    --rule="os=ios,url=https://apps.apple.com/app/id123" \
+    --rule="os=android,url=https://play.google.com/store/apps/details?id=com.example"
+  url-shortener create --url="https://example.com" --sticky-variant \
+    --variant="name=A,weight=50,url=https://example.com/landing-a" \
+    --variant="name=B,weight=50,url=https://example.com/landing-b"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Valider que le flag --url a été fourni
 		if longURLFlag == "" {
@@ -37,13 +91,39 @@ Exemple:
 			os.Exit(1)
 		}
 
+		if maxUsesFlag < 0 {
+			log.Printf("ERREUR: Le flag --max-uses doit être positif ou nul")
+			os.Exit(1)
+		}
+
+		// Analyser les règles de ciblage, dans l'ordre des flags
+		rules := make([]models.TargetingRule, 0, len(ruleFlags))
+		for _, spec := range ruleFlags {
+			rule, err := targeting.ParseRuleSpec(spec)
+			if err != nil {
+				log.Printf("ERREUR: Règle de ciblage invalide '%s': %v", spec, err)
+				os.Exit(1)
+			}
+			rules = append(rules, rule)
+		}
+
+		variants := make([]models.LinkVariant, 0, len(variantFlags))
+		for _, spec := range variantFlags {
+			variant, err := parseVariantSpec(spec)
+			if err != nil {
+				log.Printf("ERREUR: Variante invalide '%s': %v", spec, err)
+				os.Exit(1)
+			}
+			variants = append(variants, variant)
+		}
+
 		// Charger la configuration chargée globalement via cmd.cfg
 		if cmd2.Cfg == nil {
 			log.Fatalf("FATAL: Configuration not loaded")
 		}
 
-		// Initialiser la connexion à la base de données SQLite
-		db, err := gorm.Open(sqlite.Open(cmd2.Cfg.Database.Name), &gorm.Config{})
+		// Initialiser la connexion à la base de données configurée (SQLite ou PostgreSQL)
+		db, err := database.Open(cmd2.Cfg)
 		if err != nil {
 			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
 		}
@@ -64,27 +144,140 @@ Exemple:
 		linkRepo := repository.NewLinkRepository(db)
 		clickRepo := repository.NewClickRepository(db)
 		clickService := services.NewClickService(clickRepo)
-		linkService := services.NewLinkService(linkRepo, clickService)
+		codeGenerator, err := shortcode.New(cmd2.Cfg.ShortCode, repository.NewCounterRepository(db))
+		if err != nil {
+			log.Fatalf("FATAL: Configuration shortcode invalide: %v", err)
+		}
+		linkService := services.NewLinkService(linkRepo, clickService, codeGenerator, shortcode.NewPolicy(cmd2.Cfg.ShortCode), canonical.New(cmd2.Cfg.Dedupe.StripParams))
+		domainService := services.NewDomainService(repository.NewDomainRepository(db), cmd2.Cfg.Server.BaseURL)
+		domainID := resolveDomainFlag(domainService)
+
+		// Avec --dedupe, réutiliser le lien existant du propriétaire pour la même URL canonique
+		dedupe := cmd2.Cfg.Dedupe.Default
+		if cmd.Flags().Changed("dedupe") {
+			dedupe = dedupeFlag
+		}
+		if dedupe {
+			existing, err := linkService.FindDuplicateLink(domainID, ownerFlag, longURLFlag)
+			if err == nil {
+				fullShortURL, err := domainService.FullShortURL(existing)
+				if err != nil {
+					log.Printf("ERREUR: Impossible de construire l'URL courte: %v", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Lien existant réutilisé (URL identique):\n")
+				fmt.Printf("Code: %s\n", existing.ShortCode)
+				fmt.Printf("URL complète: %s\n", fullShortURL)
+				return
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("ERREUR: Impossible de rechercher un lien existant: %v", err)
+				os.Exit(1)
+			}
+		}
 
 		// Appeler le LinkService et la fonction CreateLink pour créer le lien court
-		link, err := linkService.CreateLink(longURLFlag)
+		link, err := linkService.CreateLink(longURLFlag, services.CreateLinkOptions{
+			DomainID:       domainID,
+			Owner:          ownerFlag,
+			MaxUses:        maxUsesFlag,
+			RedirectStatus: redirectStatusFlag,
+			Interstitial:   interstitialFlag,
+			PassQuery:      passQueryFlag,
+			QueryConflict:  queryConflictFlag,
+			PassPath:       passPathFlag,
+			UTM:            utmFlags,
+			TargetingRules: rules,
+			Variants:       variants,
+			StickyVariant:  stickyVariantFlag,
+		})
 		if err != nil {
 			log.Printf("ERREUR: Impossible de créer le lien court: %v", err)
 			os.Exit(1)
 		}
 
-		fullShortURL := fmt.Sprintf("%s/%s", cmd2.Cfg.Server.BaseURL, link.ShortCode)
+		fullShortURL, err := domainService.FullShortURL(link)
+		if err != nil {
+			log.Printf("ERREUR: Impossible de construire l'URL courte: %v", err)
+			os.Exit(1)
+		}
 		fmt.Printf("URL courte créée avec succès:\n")
 		fmt.Printf("Code: %s\n", link.ShortCode)
 		fmt.Printf("URL complète: %s\n", fullShortURL)
+		if link.IsLimited() {
+			fmt.Printf("Utilisations autorisées: %d\n", link.MaxUses)
+		}
+		fmt.Printf("Redirection: %d (page intermédiaire: %t)\n", link.EffectiveRedirectStatus(), link.Interstitial)
+		if !link.UTM.IsEmpty() {
+			fmt.Printf("Paramètres UTM: %s\n", link.UTM.Values().Encode())
+		}
+		for _, rule := range link.TargetingRules {
+			fmt.Printf("Règle %d: %s\n", rule.Position+1, formatRule(rule))
+		}
+		for _, variant := range link.Variants {
+			fmt.Printf("Variante %s (poids %d): %s\n", variant.Name, variant.Weight, variant.TargetURL)
+		}
 	},
 }
 
+// parseVariantSpec construit une variante à partir d'une spécification "name=A,weight=50,url=https://...".
+// La clé url doit être la dernière : tout ce qui la suit fait partie de l'URL.
+func parseVariantSpec(spec string) (models.LinkVariant, error) {
+	variant := models.LinkVariant{Weight: 1}
+
+	rest := spec
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "url=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return variant, fmt.Errorf("expected key=value, got '%s'", part)
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			variant.Name = value
+		case "weight":
+			weight, err := strconv.Atoi(value)
+			if err != nil {
+				return variant, fmt.Errorf("invalid weight '%s'", value)
+			}
+			variant.Weight = weight
+		case "url":
+			variant.TargetURL = value
+		default:
+			return variant, fmt.Errorf("unknown key '%s'", key)
+		}
+	}
+	return variant, nil
+}
+
 // init() s'exécute automatiquement lors de l'importation du package.
 // Il est utilisé pour définir les flags que cette commande accepte.
 func init() {
 	// Définir le flag --url pour la commande create
 	CreateCmd.Flags().StringVarP(&longURLFlag, "url", "u", "", "URL longue à raccourcir (requis)")
+	CreateCmd.Flags().StringVar(&ownerFlag, "owner", "", "Propriétaire du lien, périmètre de la déduplication")
+	CreateCmd.Flags().BoolVar(&dedupeFlag, "dedupe", false, "Réutilise le lien existant du propriétaire pour la même URL canonique (défaut: dedupe.default)")
+	CreateCmd.Flags().StringVar(&domainFlag, "domain", "", "Domaine personnalisé du lien (domaine par défaut si vide)")
+	CreateCmd.Flags().IntVar(&maxUsesFlag, "max-uses", 0, "Nombre maximal d'utilisations du lien (0 = illimité, 1 = usage unique)")
+	CreateCmd.Flags().IntVar(&redirectStatusFlag, "status", 0, "Code HTTP de redirection: 301, 302, 307 ou 308 (défaut 302)")
+	CreateCmd.Flags().BoolVar(&interstitialFlag, "interstitial", false, "Affiche une page intermédiaire avant la redirection")
+	CreateCmd.Flags().BoolVar(&passQueryFlag, "pass-query", false, "Transmet les paramètres de requête entrants à l'URL longue")
+	CreateCmd.Flags().StringVar(&queryConflictFlag, "query-conflict", "link", "Politique de conflit des paramètres: link, request ou append")
+	CreateCmd.Flags().BoolVar(&passPathFlag, "pass-path", false, "Ajoute les segments de chemin supplémentaires à l'URL longue")
+	CreateCmd.Flags().StringVar(&utmFlags.Source, "utm-source", "", "Paramètre utm_source appliqué à la redirection")
+	CreateCmd.Flags().StringVar(&utmFlags.Medium, "utm-medium", "", "Paramètre utm_medium appliqué à la redirection")
+	CreateCmd.Flags().StringVar(&utmFlags.Campaign, "utm-campaign", "", "Paramètre utm_campaign appliqué à la redirection")
+	CreateCmd.Flags().StringVar(&utmFlags.Term, "utm-term", "", "Paramètre utm_term appliqué à la redirection")
+	CreateCmd.Flags().StringVar(&utmFlags.Content, "utm-content", "", "Paramètre utm_content appliqué à la redirection")
+	CreateCmd.Flags().StringArrayVar(&variantFlags, "variant", nil, "Variante A/B \"name=...,weight=...,url=...\" (répétable)")
+	CreateCmd.Flags().BoolVar(&stickyVariantFlag, "sticky-variant", false, "Conserve la même variante pour un visiteur (cookie)")
+	CreateCmd.Flags().StringArrayVar(&ruleFlags, "rule", nil, "Règle de ciblage \"os=...,device=...,lang=...,country=FR|BE,url=...\" (répétable, évaluées dans l'ordre)")
 
 	// Marquer le flag comme requis
 	if err := CreateCmd.MarkFlagRequired("url"); err != nil {
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande domain add
+var (
+	domainHostFlag    string
+	domainBaseURLFlag string
+)
+
+// domainFlag stockera le domaine personnalisé ciblé par les commandes de liens (--domain)
+var domainFlag string
+
+// DomainCmd représente la commande 'domain', qui regroupe la gestion des domaines personnalisés.
+var DomainCmd = &cobra.Command{
+	Use:   "domain",
+	Short: "Gère les domaines personnalisés servis par l'instance.",
+	Long: `Chaque domaine personnalisé possède son propre espace de noms de codes courts.
+Le domaine d'une redirection est déduit de l'en-tête Host de la requête ;
+les hôtes inconnus utilisent le domaine par défaut (server.base_url).
+
+Exemple:
+  url-shortener domain add --host="go.marque.fr"
+  url-shortener domain add --host="lnk.marque.com" --base-url="https://lnk.marque.com"
+  url-shortener domain list`,
+}
+
+// DomainAddCmd représente la commande 'domain add'
+var DomainAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Ajoute un domaine personnalisé.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if domainHostFlag == "" {
+			log.Printf("ERREUR: Le flag --host est requis")
+			os.Exit(1)
+		}
+
+		db, closeDB := openDB()
+		defer closeDB()
+
+		domainService := services.NewDomainService(repository.NewDomainRepository(db), cmd2.Cfg.Server.BaseURL)
+		domain, err := domainService.CreateDomain(domainHostFlag, domainBaseURLFlag)
+		if err != nil {
+			log.Printf("ERREUR: Impossible d'ajouter le domaine '%s': %v", domainHostFlag, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Domaine ajouté avec succès:\n")
+		fmt.Printf("Hôte: %s\n", domain.Host)
+		fmt.Printf("URL de base: %s\n", domain.BaseURL)
+	},
+}
+
+// DomainListCmd représente la commande 'domain list'
+var DomainListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les domaines personnalisés.",
+	Run: func(cmd *cobra.Command, args []string) {
+		db, closeDB := openDB()
+		defer closeDB()
+
+		domainService := services.NewDomainService(repository.NewDomainRepository(db), cmd2.Cfg.Server.BaseURL)
+		domains, err := domainService.ListDomains()
+		if err != nil {
+			log.Printf("ERREUR: Impossible de lister les domaines: %v", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Domaine par défaut: %s\n", cmd2.Cfg.Server.BaseURL)
+		for _, domain := range domains {
+			fmt.Printf("%s -> %s\n", domain.Host, domain.BaseURL)
+		}
+	},
+}
+
+// openDB ouvre la base de données configurée pour une commande et renvoie sa fonction de fermeture.
+func openDB() (*gorm.DB, func()) {
+	if cmd2.Cfg == nil {
+		log.Fatalf("FATAL: Configuration not loaded")
+	}
+
+	db, err := database.Open(cmd2.Cfg)
+	if err != nil {
+		log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+
+	return db, func() {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Erreur lors de la fermeture de la base de données: %v", err)
+		}
+	}
+}
+
+// resolveDomainFlag renvoie le domaine désigné par --domain (domaine par défaut si vide) et arrête la commande s'il est inconnu.
+func resolveDomainFlag(domainService *services.DomainService) uint {
+	domainID, err := domainService.ResolveName(domainFlag)
+	if err != nil {
+		log.Printf("ERREUR: Domaine '%s' invalide: %v", domainFlag, err)
+		os.Exit(1)
+	}
+	return domainID
+}
+
+func init() {
+	DomainAddCmd.Flags().StringVar(&domainHostFlag, "host", "", "Nom d'hôte du domaine, ex: go.marque.fr (requis)")
+	DomainAddCmd.Flags().StringVar(&domainBaseURLFlag, "base-url", "", "URL de base des liens courts (https://<host> par défaut)")
+	if err := DomainAddCmd.MarkFlagRequired("host"); err != nil {
+		log.Fatalf("FATAL: Impossible de marquer le flag host comme requis: %v", err)
+	}
+
+	DomainCmd.AddCommand(DomainAddCmd, DomainListCmd)
+	cmd2.RootCmd.AddCommand(DomainCmd)
+}
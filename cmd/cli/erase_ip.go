@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande erase-ip
+var (
+	eraseIPFlag     string
+	eraseDryRunFlag bool
+)
+
+// EraseIPCmd représente la commande 'erase-ip'
+var EraseIPCmd = &cobra.Command{
+	Use:   "erase-ip",
+	Short: "Supprime tous les clics enregistrés pour une adresse IP.",
+	Long: `Cette commande supprime les clics bruts d'une adresse IP, pour répondre à une demande d'effacement.
+Les clics sont recherchés sous l'adresse complète et, en mode privacy.ip_mode=hash, sous son empreinte
+de chaque jour (le secret privacy.hash_secret doit être celui utilisé par le serveur).
+Les adresses tronquées, partagées par tout un réseau, et les agrégats de clics sont anonymes et conservés.
+
+Avec --dry-run, la commande affiche seulement le nombre de clics qui seraient supprimés.
+
+Exemple:
+  url-shortener erase-ip --ip=203.0.113.7 --dry-run
+  url-shortener erase-ip --ip=2001:db8::1`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ip := net.ParseIP(eraseIPFlag)
+		if ip == nil {
+			log.Printf("ERREUR: Adresse IP invalide '%s'", eraseIPFlag)
+			os.Exit(1)
+		}
+
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: Configuration not loaded")
+		}
+		anonymizer, err := privacy.New(cmd2.Cfg.Privacy)
+		if err != nil {
+			log.Fatalf("FATAL: Configuration privacy invalide: %v", err)
+		}
+		if anonymizer.Mode() == privacy.ModeHash && cmd2.Cfg.Privacy.HashSecret == "" {
+			log.Println("Attention: privacy.hash_secret vide, seules les adresses complètes peuvent être retrouvées.")
+		}
+
+		db, closeDB := openDB()
+		defer closeDB()
+
+		// Les empreintes dépendent du jour du clic : on les calcule depuis le plus ancien clic conservé
+		earliest, err := repository.NewRollupRepository(db).EarliestClickTime()
+		if err != nil {
+			log.Fatalf("FATAL: Impossible de lire le plus ancien clic: %v", err)
+		}
+		if earliest.IsZero() {
+			earliest = time.Now()
+		}
+		forms := anonymizer.StoredForms(ip.String(), earliest, time.Now())
+
+		// Les clics sont supprimés à travers le cache : les compteurs Redis des liens concernés sont remis à zéro
+		repos, closeCache := cachedRepositories(db)
+		defer closeCache()
+		clickService := services.NewClickService(repos.Clicks)
+		count, err := clickService.EraseClicksByIP(forms, eraseDryRunFlag)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'effacement des clics de %s: %v", ip, err)
+		}
+		if eraseDryRunFlag {
+			fmt.Printf("Essai à blanc: %d clic(s) de %s seraient supprimés.\n", count, ip)
+			return
+		}
+		fmt.Printf("%d clic(s) de %s supprimé(s).\n", count, ip)
+	},
+}
+
+func init() {
+	EraseIPCmd.Flags().StringVar(&eraseIPFlag, "ip", "", "Adresse IP dont les clics doivent être supprimés (requis)")
+	EraseIPCmd.Flags().BoolVar(&eraseDryRunFlag, "dry-run", false, "Affiche le nombre de clics qui seraient supprimés, sans rien modifier")
+
+	if err := EraseIPCmd.MarkFlagRequired("ip"); err != nil {
+		log.Fatalf("FATAL: Impossible de marquer le flag ip comme requis: %v", err)
+	}
+
+	cmd2.RootCmd.AddCommand(EraseIPCmd)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/export"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande export
+var (
+	exportFormatFlag string
+	exportSinceFlag  string
+	exportUntilFlag  string
+	exportOutFlag    string
+)
+
+// ExportCmd représente la commande 'export'
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exporte les liens ou les clics en CSV, JSON Lines ou Parquet.",
+	Long: `Cette commande exporte les liens (par date de création) ou les clics bruts (par date du clic)
+vers un fichier ou la sortie standard. Les données sont lues par lots et écrites au fil de l'eau.
+--since est inclus, --until exclu ; ils acceptent une date (2006-01-02, minuit UTC) ou un horodatage RFC 3339.
+
+Exemple:
+  url-shortener export links --format=csv --out=links.csv
+  url-shortener export clicks --format=parquet --since=2024-01-01 --until=2024-02-01 --out=clicks.parquet
+  url-shortener export clicks --format=jsonl | gzip > clicks.jsonl.gz`,
+}
+
+// ExportLinksCmd représente la commande 'export links'
+var ExportLinksCmd = &cobra.Command{
+	Use:   "links",
+	Short: "Exporte les liens.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport("lien(s)", (*services.ExportService).ExportLinks)
+	},
+}
+
+// ExportClicksCmd représente la commande 'export clicks'
+var ExportClicksCmd = &cobra.Command{
+	Use:   "clicks",
+	Short: "Exporte les clics bruts.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport("clic(s)", (*services.ExportService).ExportClicks)
+	},
+}
+
+// runExport vérifie les flags, ouvre la base et la destination, puis exécute l'export demandé.
+func runExport(label string, run func(s *services.ExportService, format string, since, until time.Time, w io.Writer) (int, error)) {
+	since, err := export.ParseTime(exportSinceFlag)
+	if err != nil {
+		log.Printf("ERREUR: --since invalide: %v", err)
+		os.Exit(1)
+	}
+	until, err := export.ParseTime(exportUntilFlag)
+	if err != nil {
+		log.Printf("ERREUR: --until invalide: %v", err)
+		os.Exit(1)
+	}
+	if !export.IsValidFormat(exportFormatFlag) {
+		log.Printf("ERREUR: --format invalide: %v", export.ErrInvalidFormat)
+		os.Exit(1)
+	}
+
+	db, closeDB := openDB()
+	defer closeDB()
+
+	repos := repository.NewGormRepositories(db)
+	exportService := services.NewExportService(repos.Links, repos.Clicks, services.NewDomainService(repos.Domains, cmd2.Cfg.Server.BaseURL))
+
+	out := os.Stdout
+	if exportOutFlag != "" && exportOutFlag != "-" {
+		out, err = os.Create(exportOutFlag)
+		if err != nil {
+			log.Fatalf("FATAL: Impossible de créer %s: %v", exportOutFlag, err)
+		}
+		defer out.Close()
+	}
+
+	buffered := bufio.NewWriter(out)
+	count, err := run(exportService, exportFormatFlag, since, until, buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		if out != os.Stdout {
+			out.Close()
+			os.Remove(exportOutFlag)
+		}
+		log.Fatalf("FATAL: Échec de l'export: %v", err)
+	}
+	if out != os.Stdout {
+		fmt.Printf("%d %s exporté(s) dans %s.\n", count, label, exportOutFlag)
+	}
+}
+
+func init() {
+	ExportCmd.PersistentFlags().StringVar(&exportFormatFlag, "format", export.FormatCSV, "Format d'export: csv, jsonl ou parquet")
+	ExportCmd.PersistentFlags().StringVar(&exportSinceFlag, "since", "", "Début de la période (inclus): 2006-01-02 ou RFC 3339")
+	ExportCmd.PersistentFlags().StringVar(&exportUntilFlag, "until", "", "Fin de la période (exclue): 2006-01-02 ou RFC 3339")
+	ExportCmd.PersistentFlags().StringVarP(&exportOutFlag, "out", "o", "", "Fichier de destination (sortie standard si vide ou \"-\")")
+
+	ExportCmd.AddCommand(ExportLinksCmd, ExportClicksCmd)
+
+	cmd2.RootCmd.AddCommand(ExportCmd)
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/importer"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande import
+var (
+	importFileFlag   string
+	importFormatFlag string
+	importDomainFlag string
+	importOwnerFlag  string
+	importAtomicFlag bool
+	importDryRunFlag bool
+)
+
+// ImportCmd représente la commande 'import'
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Importe en masse des liens depuis un fichier CSV ou JSON.",
+	Long: `Cette commande importe des liens depuis un export de ce service ou d'un autre raccourcisseur
+(Bitly, Rebrandly, YOURLS, Shlink, ...). Les colonnes sont reconnues par leur nom : l'URL longue
+(long_url, url, destination, ...) est requise ; le code court (short_code, keyword, slashtag, ou une URL courte
+complète), la date de création, le nombre de clics historiques, le domaine et le propriétaire sont repris s'ils sont présents.
+Chaque ligne est validée ; les codes fournis sont conservés, les autres générés.
+
+Par défaut, les lignes valides sont importées et les lignes invalides signalées.
+Avec --atomic, rien n'est importé si une seule ligne est invalide.
+
+Exemple:
+  url-shortener import --file=bitly-export.csv --dry-run
+  url-shortener import --file=links.json --domain=go.marque.fr --atomic
+  cat links.jsonl | url-shortener import --file=- --format=json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := importFormatFlag
+		if format == "" {
+			format = importer.DetectFormat(importFileFlag)
+		}
+		if !importer.IsValidFormat(format) {
+			log.Printf("ERREUR: Format d'import inconnu, précisez --format=csv ou --format=json")
+			os.Exit(1)
+		}
+
+		var in io.Reader = os.Stdin
+		if importFileFlag != "-" {
+			file, err := os.Open(importFileFlag)
+			if err != nil {
+				log.Fatalf("FATAL: Impossible d'ouvrir %s: %v", importFileFlag, err)
+			}
+			defer file.Close()
+			in = file
+		}
+		rows, err := importer.Read(format, in)
+		if err != nil {
+			log.Printf("ERREUR: Fichier illisible: %v", err)
+			os.Exit(1)
+		}
+
+		db, closeDB := openDB()
+		defer closeDB()
+
+		repos := repository.NewGormRepositories(db)
+		codeGenerator, err := shortcode.New(cmd2.Cfg.ShortCode, repos.Counters)
+		if err != nil {
+			log.Fatalf("FATAL: Configuration shortcode invalide: %v", err)
+		}
+		linkService := services.NewLinkService(repos.Links, services.NewClickService(repos.Clicks), codeGenerator, shortcode.NewPolicy(cmd2.Cfg.ShortCode), canonical.New(cmd2.Cfg.Dedupe.StripParams))
+		domainService := services.NewDomainService(repos.Domains, cmd2.Cfg.Server.BaseURL)
+		importService := services.NewImportService(linkService, domainService)
+
+		report, err := importService.Import(rows, services.ImportOptions{
+			Domain: importDomainFlag,
+			Owner:  importOwnerFlag,
+			Atomic: importAtomicFlag,
+			DryRun: importDryRunFlag,
+		})
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'import: %v", err)
+		}
+
+		for _, result := range report.Results {
+			if result.Err != nil {
+				fmt.Printf("Ligne %d: %v\n", result.Line, result.Err)
+			}
+		}
+		switch {
+		case importDryRunFlag:
+			fmt.Printf("Essai à blanc: %d ligne(s) valide(s), %d ligne(s) invalide(s) sur %d. Aucun lien importé.\n", report.Valid, report.Failed, len(rows))
+		case importAtomicFlag && report.Failed > 0:
+			fmt.Printf("%d ligne(s) invalide(s) sur %d: aucun lien importé (--atomic).\n", report.Failed, len(rows))
+		default:
+			fmt.Printf("%d lien(s) importé(s), %d ligne(s) rejetée(s) sur %d.\n", report.Imported, report.Failed, len(rows))
+		}
+		if report.Failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	ImportCmd.Flags().StringVarP(&importFileFlag, "file", "f", "", "Fichier à importer (\"-\" pour l'entrée standard) (requis)")
+	ImportCmd.Flags().StringVar(&importFormatFlag, "format", "", "Format du fichier: csv ou json (déduit de l'extension si vide)")
+	ImportCmd.Flags().StringVar(&importDomainFlag, "domain", "", "Domaine imposé à tous les liens (sinon colonne domain, puis domaine par défaut)")
+	ImportCmd.Flags().StringVar(&importOwnerFlag, "owner", "", "Propriétaire des liens dont la ligne n'en précise pas")
+	ImportCmd.Flags().BoolVar(&importAtomicFlag, "atomic", false, "N'importe rien si une seule ligne est invalide (transaction unique)")
+	ImportCmd.Flags().BoolVar(&importDryRunFlag, "dry-run", false, "Valide le fichier sans rien importer")
+	if err := ImportCmd.MarkFlagRequired("file"); err != nil {
+		log.Fatalf("FATAL: Impossible de marquer le flag file comme requis: %v", err)
+	}
+
+	cmd2.RootCmd.AddCommand(ImportCmd)
+}
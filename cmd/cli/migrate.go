@@ -3,56 +3,205 @@ package cli
 import (
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/migrations"
 	"github.com/armanceau/go-url-shortener/internal/models"
-	"github.com/glebarez/sqlite" // Pure go SQLite driver
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
+// migrateDryRunFlag stockera la valeur du flag --dry-run
+var migrateDryRunFlag bool
+
 // MigrateCmd représente la commande 'migrate'
 var MigrateCmd = &cobra.Command{
 	Use:   "migrate",
-	Short: "Exécute les migrations de la base de données pour créer ou mettre à jour les tables.",
-	Long: `Cette commande se connecte à la base de données configurée (SQLite)
-et exécute les migrations automatiques de GORM pour créer les tables 'links' et 'clicks'
-basées sur les modèles Go.`,
+	Short: "Exécute les migrations versionnées de la base de données.",
+	Long: `Cette commande se connecte à la base de données configurée (SQLite ou PostgreSQL)
+et applique les migrations versionnées du schéma. Les versions appliquées sont
+enregistrées dans la table 'schema_migrations'. Sans sous-commande, elle équivaut à 'migrate up'.
+
+Avec --dry-run, les migrations sont exécutées dans une transaction annulée à la fin :
+le SQL est affiché et la base reste inchangée.
+
+Exemple:
+  url-shortener migrate
+  url-shortener migrate up --dry-run
+  url-shortener migrate down 1
+  url-shortener migrate status`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrateUp()
+	},
+}
+
+// MigrateUpCmd représente la commande 'migrate up'
+var MigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Applique toutes les migrations en attente.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrateUp()
+	},
+}
+
+// MigrateDownCmd représente la commande 'migrate down N'
+var MigrateDownCmd = &cobra.Command{
+	Use:   "down N",
+	Short: "Annule les N dernières migrations appliquées.",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		// Charger la configuration chargée globalement via cmd.cfg
-		if cmd2.Cfg == nil {
-			log.Fatalf("FATAL: Configuration not loaded")
+		steps, err := strconv.Atoi(args[0])
+		if err != nil || steps <= 0 {
+			log.Printf("ERREUR: N doit être un entier strictement positif, reçu '%s'", args[0])
+			os.Exit(1)
 		}
 
-		// Initialiser la connexion à la BDD
-		db, err := gorm.Open(sqlite.Open(cmd2.Cfg.Database.Name), &gorm.Config{})
+		db, closeDB := openMigrationDB()
+		defer closeDB()
+
+		reverted, err := migrations.Down(db, steps, migrateDryRunFlag)
 		if err != nil {
-			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+			log.Fatalf("FATAL: Échec de l'annulation des migrations: %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("Aucune migration à annuler.")
+			return
 		}
+		for _, migration := range reverted {
+			fmt.Printf("%s %04d_%s\n", migrateVerb("Annulée", "Serait annulée"), migration.Version, migration.Name)
+		}
+		printSchemaVersion(db)
+	},
+}
+
+// MigrateStatusCmd représente la commande 'migrate status'
+var MigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Affiche la version du schéma et l'état de chaque migration.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, closeDB := openMigrationDB()
+		defer closeDB()
 
-		sqlDB, err := db.DB()
+		statuses, err := migrations.Status(db)
 		if err != nil {
-			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+			log.Fatalf("FATAL: Impossible de lire l'état des migrations: %v", err)
 		}
-		// Assurez-vous que la connexion est fermée après la migration grâce à defer
-		defer func() {
-			if err := sqlDB.Close(); err != nil {
-				log.Printf("Erreur lors de la fermeture de la base de données: %v", err)
+		for _, status := range statuses {
+			state := "en attente"
+			if status.Applied {
+				state = "appliquée le " + status.AppliedAt.Format("2006-01-02 15:04:05")
 			}
-		}()
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+		printSchemaVersion(db)
+	},
+}
+
+// runMigrateUp applique les migrations en attente puis complète les données dérivées.
+func runMigrateUp() {
+	db, closeDB := openMigrationDB()
+	defer closeDB()
+
+	applied, err := migrations.Up(db, migrateDryRunFlag)
+	if err != nil {
+		log.Fatalf("FATAL: Échec de la migration: %v", err)
+	}
+	for _, migration := range applied {
+		fmt.Printf("%s %04d_%s\n", migrateVerb("Appliquée", "Serait appliquée"), migration.Version, migration.Name)
+	}
+	if migrateDryRunFlag {
+		fmt.Println("Essai à blanc: aucune modification n'a été enregistrée.")
+		return
+	}
+
+	if err := backfillCanonicalURLs(db); err != nil {
+		log.Fatalf("FATAL: Échec du calcul des URLs canoniques: %v", err)
+	}
+
+	// Pas touche au log
+	fmt.Println("Migrations de la base de données exécutées avec succès.")
+	printSchemaVersion(db)
+}
 
-		// Exécuter les migrations automatiques de GORM.
-		// Utilisez db.AutoMigrate() et passez-lui les pointeurs vers tous vos modèles.
-		if err := db.AutoMigrate(&models.Link{}, &models.Click{}); err != nil {
-			log.Fatalf("FATAL: Échec de la migration: %v", err)
+// backfillCanonicalURLs calcule la forme canonique des liens créés avant la déduplication.
+// Elle dépend de la configuration (dedupe.strip_params) et n'est donc pas une migration versionnée.
+func backfillCanonicalURLs(db *gorm.DB) error {
+	canonicalizer := canonical.New(cmd2.Cfg.Dedupe.StripParams)
+	var links []models.Link
+	return db.Select("id", "long_url").Where("canonical_url = ''").FindInBatches(&links, 500, func(tx *gorm.DB, batch int) error {
+		for _, link := range links {
+			canonicalURL, err := canonicalizer.Canonicalize(link.LongURL)
+			if err != nil {
+				log.Printf("Attention: URL du lien %d non canonicalisable, ignorée pour la déduplication: %v", link.ID, err)
+				continue
+			}
+			if err := db.Model(&models.Link{}).Where("id = ?", link.ID).UpdateColumn("canonical_url", canonicalURL).Error; err != nil {
+				return err
+			}
 		}
+		return nil
+	}).Error
+}
 
-		// Pas touche au log
-		fmt.Println("Migrations de la base de données exécutées avec succès.")
-	},
+// openMigrationDB ouvre la base de données pour les commandes de migration et renvoie sa fonction de fermeture.
+// En essai à blanc, toutes les requêtes SQL sont affichées.
+func openMigrationDB() (*gorm.DB, func()) {
+	// Charger la configuration chargée globalement via cmd.cfg
+	if cmd2.Cfg == nil {
+		log.Fatalf("FATAL: Configuration not loaded")
+	}
+
+	// Initialiser la connexion à la BDD
+	db, err := database.Open(cmd2.Cfg)
+	if err != nil {
+		log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+	}
+	if migrateDryRunFlag {
+		db = db.Session(&gorm.Session{Logger: logger.New(log.New(os.Stdout, "", 0), logger.Config{LogLevel: logger.Info})})
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+	// Assurez-vous que la connexion est fermée après la migration grâce à defer
+	return db, func() {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Erreur lors de la fermeture de la base de données: %v", err)
+		}
+	}
+}
+
+// printSchemaVersion affiche la version actuelle du schéma et celle attendue par le binaire.
+func printSchemaVersion(db *gorm.DB) {
+	current, err := migrations.CurrentVersion(db)
+	if err != nil {
+		log.Printf("Attention: Impossible de lire la version du schéma: %v", err)
+		return
+	}
+	fmt.Printf("Version du schéma: %d (attendue: %d)\n", current, migrations.Latest())
+}
+
+// migrateVerb choisit le libellé d'une migration selon le mode (réel ou essai à blanc).
+func migrateVerb(done, dryRun string) string {
+	if migrateDryRunFlag {
+		return dryRun
+	}
+	return done
 }
 
 func init() {
+	MigrateCmd.PersistentFlags().BoolVar(&migrateDryRunFlag, "dry-run", false, "Exécute les migrations dans une transaction annulée et affiche le SQL")
+
+	MigrateCmd.AddCommand(MigrateUpCmd, MigrateDownCmd, MigrateStatusCmd)
+
 	// Ajouter la commande à RootCmd
 	cmd2.RootCmd.AddCommand(MigrateCmd)
 }
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande purge
+var (
+	purgeOlderThanFlag string
+	purgeDryRunFlag    bool
+)
+
+// PurgeCmd représente la commande 'purge'
+var PurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Supprime les clics bruts plus anciens qu'une durée donnée.",
+	Long: `Cette commande agrège puis supprime les clics bruts plus anciens que --older-than
+(par défaut, la durée de rétention retention.click_days de la configuration).
+Les statistiques restent exactes : elles sont lues dans les agrégats horaires et journaliers.
+Les clics de l'heure en cours, pas encore agrégés, ne sont jamais supprimés.
+
+Avec --dry-run, la commande affiche seulement le nombre de clics qui seraient supprimés.
+
+Exemple:
+  url-shortener purge --older-than=90d --dry-run
+  url-shortener purge --older-than=720h`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: Configuration not loaded")
+		}
+
+		age := time.Duration(cmd2.Cfg.Retention.ClickDays) * 24 * time.Hour
+		if purgeOlderThanFlag != "" {
+			var err error
+			age, err = parseAge(purgeOlderThanFlag)
+			if err != nil {
+				log.Printf("ERREUR: --older-than invalide: %v", err)
+				os.Exit(1)
+			}
+		}
+		if age <= 0 {
+			log.Printf("ERREUR: Indiquez --older-than ou configurez retention.click_days")
+			os.Exit(1)
+		}
+
+		db, closeDB := openDB()
+		defer closeDB()
+
+		rollupService := services.NewRollupService(repository.NewRollupRepository(db), time.Duration(cmd2.Cfg.Analytics.RollupDelayMinutes)*time.Minute)
+		now := time.Now()
+		count, cutoff, err := rollupService.PurgeClicks(now, now.Add(-age), purgeDryRunFlag)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la purge des clics: %v", err)
+		}
+
+		if purgeDryRunFlag {
+			fmt.Printf("Essai à blanc: %d clic(s) antérieur(s) au %s seraient supprimés.\n", count, cutoff.Local().Format("2006-01-02 15:04:05"))
+			return
+		}
+		fmt.Printf("%d clic(s) antérieur(s) au %s supprimé(s).\n", count, cutoff.Local().Format("2006-01-02 15:04:05"))
+	},
+}
+
+// parseAge lit une durée Go (ex: 720h) ou un nombre de jours suffixé par "d" (ex: 90d).
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("nombre de jours invalide '%s'", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func init() {
+	PurgeCmd.Flags().StringVar(&purgeOlderThanFlag, "older-than", "", "Âge des clics à supprimer, en jours (ex: 90d) ou en durée Go (ex: 720h)")
+	PurgeCmd.Flags().BoolVar(&purgeDryRunFlag, "dry-run", false, "Affiche le nombre de clics qui seraient supprimés, sans rien modifier")
+
+	cmd2.RootCmd.AddCommand(PurgeCmd)
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"720h", 720 * time.Hour},
+		{"90m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAge(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "d", "0d", "-3d", "1.5d", "ninety", "90"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("parseAge(%q) succeeded, want an error", value)
+		}
+	}
+}
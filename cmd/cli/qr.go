@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/qr"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande qr
+var (
+	qrOutFlag    string
+	qrFormatFlag string
+	qrSizeFlag   int
+	qrMarginFlag int
+	qrECCFlag    string
+	qrFgFlag     string
+	qrBgFlag     string
+)
+
+// QRCmd représente la commande 'qr'
+var QRCmd = &cobra.Command{
+	Use:   "qr",
+	Short: "Génère le QR code de l'URL courte d'un lien en PNG ou en SVG.",
+	Long: `Cette commande dessine le QR code de l'URL courte complète d'un lien, pour l'imprimer.
+Le format est déduit de l'extension de --out (.svg ou .png) si --format n'est pas précisé.
+
+Exemple:
+  url-shortener qr --code="xyz123" --out=flyer.png --size=1024
+  url-shortener qr --code="xyz123" --out=flyer.svg --ecc=H --fg="#1a237e"
+  url-shortener qr --code="xyz123" --domain="go.marque.fr" --format=svg > flyer.svg`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if shortCodeFlag == "" {
+			log.Printf("ERREUR: Le flag --code est requis")
+			os.Exit(1)
+		}
+
+		opts := qr.DefaultOptions()
+		opts.Format, opts.Size, opts.Margin, opts.ECC = qrFormatFlag, qrSizeFlag, qrMarginFlag, qrECCFlag
+		if opts.Format == "" {
+			opts.Format = qr.FormatPNG
+			if strings.EqualFold(filepath.Ext(qrOutFlag), ".svg") {
+				opts.Format = qr.FormatSVG
+			}
+		}
+		for name, value := range map[string]string{"--fg": qrFgFlag, "--bg": qrBgFlag} {
+			if value == "" {
+				continue
+			}
+			parsed, err := qr.ParseColor(value)
+			if err != nil {
+				log.Printf("ERREUR: %s invalide: %v", name, err)
+				os.Exit(1)
+			}
+			if name == "--fg" {
+				opts.Foreground = parsed
+			} else {
+				opts.Background = parsed
+			}
+		}
+		if err := opts.Validate(); err != nil {
+			log.Printf("ERREUR: Options invalides: %v", err)
+			os.Exit(1)
+		}
+
+		db, closeDB := openDB()
+		defer closeDB()
+
+		repos := repository.NewGormRepositories(db)
+		clickService := services.NewClickService(repos.Clicks)
+		linkService := services.NewLinkService(repos.Links, clickService, nil, shortcode.NewPolicy(cmd2.Cfg.ShortCode), nil)
+		domainService := services.NewDomainService(repos.Domains, cmd2.Cfg.Server.BaseURL)
+		domainID := resolveDomainFlag(domainService)
+
+		// Une seule image par exécution : pas de cache
+		qrService := services.NewQRService(linkService, domainService, 0)
+		link, image, err := qrService.LinkQRCode(domainID, shortCodeFlag, opts)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("ERREUR: Aucun lien avec le code '%s'", shortCodeFlag)
+			} else {
+				log.Printf("ERREUR: Impossible de générer le QR code: %v", err)
+			}
+			os.Exit(1)
+		}
+
+		if qrOutFlag == "" || qrOutFlag == "-" {
+			if _, err := os.Stdout.Write(image); err != nil {
+				log.Fatalf("FATAL: Échec de l'écriture du QR code: %v", err)
+			}
+			return
+		}
+		if err := os.WriteFile(qrOutFlag, image, 0o644); err != nil {
+			log.Fatalf("FATAL: Impossible d'écrire %s: %v", qrOutFlag, err)
+		}
+		fullShortURL, err := domainService.FullShortURL(link)
+		if err != nil {
+			log.Fatalf("FATAL: Impossible de construire l'URL courte: %v", err)
+		}
+		fmt.Printf("QR code de %s enregistré dans %s.\n", fullShortURL, qrOutFlag)
+	},
+}
+
+func init() {
+	QRCmd.Flags().StringVarP(&shortCodeFlag, "code", "c", "", "Code court du lien (requis)")
+	QRCmd.Flags().StringVar(&domainFlag, "domain", "", "Domaine personnalisé du lien (domaine par défaut si vide)")
+	QRCmd.Flags().StringVarP(&qrOutFlag, "out", "o", "", "Fichier de destination (sortie standard si vide ou \"-\")")
+	QRCmd.Flags().StringVar(&qrFormatFlag, "format", "", "Format: png ou svg (déduit de l'extension de --out si vide)")
+	QRCmd.Flags().IntVar(&qrSizeFlag, "size", qr.DefaultSize, "Côté de l'image en pixels")
+	QRCmd.Flags().IntVar(&qrMarginFlag, "margin", qr.DefaultMargin, "Zone de silence autour du symbole, en modules")
+	QRCmd.Flags().StringVar(&qrECCFlag, "ecc", qr.DefaultECC, "Niveau de correction d'erreurs: L, M, Q ou H")
+	QRCmd.Flags().StringVar(&qrFgFlag, "fg", "", "Couleur des modules, #rgb ou #rrggbb (noir par défaut)")
+	QRCmd.Flags().StringVar(&qrBgFlag, "bg", "", "Couleur du fond, #rgb ou #rrggbb (blanc par défaut)")
+
+	cmd2.RootCmd.AddCommand(QRCmd)
+}
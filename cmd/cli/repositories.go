@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"log"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/cache"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+// cachedRepositories crée les repositories de la base, décorés par le cache configuré.
+// Les commandes qui modifient un lien passent par le cache pour invalider son entrée sur les serveurs.
+func cachedRepositories(db *gorm.DB) (repository.Repositories, func()) {
+	repos, closeCache, err := cache.Decorate(cmd2.Cfg, repository.NewGormRepositories(db))
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'initialisation du cache: %v", err)
+	}
+	return repos, func() {
+		if err := closeCache(); err != nil {
+			log.Printf("Erreur lors de la fermeture du cache: %v", err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// RollupCmd représente la commande 'rollup'
+var RollupCmd = &cobra.Command{
+	Use:   "rollup",
+	Short: "Agrège les clics en tables horaires et journalières.",
+	Long: `Cette commande exécute immédiatement l'agrégation des clics que le serveur lance
+périodiquement (analytics.rollup_interval_minutes) : les heures terminées sont agrégées
+depuis la table des clics, puis les jours terminés depuis les agrégats horaires.
+Les statistiques lisent ces agrégats et ne parcourent les clics bruts que pour l'heure en cours.
+
+Exemple:
+  url-shortener rollup`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, closeDB := openDB()
+		defer closeDB()
+
+		rollupService := services.NewRollupService(repository.NewRollupRepository(db), time.Duration(cmd2.Cfg.Analytics.RollupDelayMinutes)*time.Minute)
+		hours, days, err := rollupService.Compact(time.Now())
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'agrégation des clics: %v", err)
+		}
+		fmt.Printf("Agrégation terminée: %d heure(s) et %d jour(s) agrégés.\n", hours, days)
+	},
+}
+
+func init() {
+	cmd2.RootCmd.AddCommand(RollupCmd)
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande rules
+var (
+	rulesCodeFlag  string
+	rulesSetFlags  []string
+	rulesClearFlag bool
+)
+
+// RulesCmd représente la commande 'rules'
+var RulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Affiche ou remplace les règles de redirection ciblée d'un lien court.",
+	Long: `Sans option, cette commande affiche les règles de ciblage d'un lien (OS, appareil,
+langue, pays). Avec --set, elle remplace toutes les règles par celles fournies, évaluées
+dans l'ordre ; l'URL longue du lien reste la cible par défaut si aucune règle ne correspond.
+Les règles par pays nécessitent une base GeoIP configurée dans la section 'geoip'.
+
+Exemple:
+  url-shortener rules --code="xyz123"
+  url-shortener rules --code="xyz123" \
+    --set="country=FR|BE,url=https://store.example.fr" \
+    --set="country=DE|AT|CH,url=https://store.example.de"
+  url-shortener rules --code="xyz123" --clear`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if rulesCodeFlag == "" {
+			log.Printf("ERREUR: Le flag --code est requis")
+			os.Exit(1)
+		}
+		if rulesClearFlag && len(rulesSetFlags) > 0 {
+			log.Printf("ERREUR: Les flags --set et --clear sont incompatibles")
+			os.Exit(1)
+		}
+
+		// Analyser les nouvelles règles avant d'ouvrir la base de données
+		rules := make([]models.TargetingRule, 0, len(rulesSetFlags))
+		for _, spec := range rulesSetFlags {
+			rule, err := targeting.ParseRuleSpec(spec)
+			if err != nil {
+				log.Printf("ERREUR: Règle de ciblage invalide '%s': %v", spec, err)
+				os.Exit(1)
+			}
+			rules = append(rules, rule)
+		}
+
+		// Charger la configuration chargée globalement via cmd.cfg
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: Configuration not loaded")
+		}
+
+		db, err := database.Open(cmd2.Cfg)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+		}
+
+		defer func() {
+			if err := sqlDB.Close(); err != nil {
+				log.Printf("Erreur lors de la fermeture de la base de données: %v", err)
+			}
+		}()
+
+		repos, closeCache := cachedRepositories(db)
+		defer closeCache()
+
+		clickService := services.NewClickService(repos.Clicks)
+		linkService := services.NewLinkService(repos.Links, clickService, nil, shortcode.NewPolicy(cmd2.Cfg.ShortCode), nil)
+		domainService := services.NewDomainService(repos.Domains, cmd2.Cfg.Server.BaseURL)
+		domainID := resolveDomainFlag(domainService)
+
+		var link *models.Link
+		if len(rulesSetFlags) > 0 || rulesClearFlag {
+			link, err = linkService.SetTargetingRules(domainID, rulesCodeFlag, rules)
+		} else {
+			link, err = linkService.GetLinkByShortCodeWithMessage(domainID, rulesCodeFlag)
+		}
+		if err != nil {
+			log.Printf("ERREUR: Impossible de traiter les règles du lien '%s': %v", rulesCodeFlag, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Règles de ciblage du lien %s:\n", link.ShortCode)
+		for _, rule := range link.TargetingRules {
+			fmt.Printf("  %d. %s\n", rule.Position+1, formatRule(rule))
+		}
+		fmt.Printf("  Par défaut -> %s\n", link.LongURL)
+	},
+}
+
+// formatRule rend une règle de ciblage lisible, en n'affichant que les conditions renseignées.
+func formatRule(rule models.TargetingRule) string {
+	var conditions []string
+	if rule.OS != "" {
+		conditions = append(conditions, "os="+rule.OS)
+	}
+	if rule.Device != "" {
+		conditions = append(conditions, "device="+rule.Device)
+	}
+	if rule.Language != "" {
+		conditions = append(conditions, "lang="+rule.Language)
+	}
+	if rule.Countries != "" {
+		conditions = append(conditions, "country="+strings.ReplaceAll(rule.Countries, ",", "|"))
+	}
+	return fmt.Sprintf("%s -> %s", strings.Join(conditions, ","), rule.TargetURL)
+}
+
+func init() {
+	RulesCmd.Flags().StringVarP(&rulesCodeFlag, "code", "c", "", "Code court du lien (requis)")
+	RulesCmd.Flags().StringVar(&domainFlag, "domain", "", "Domaine personnalisé du lien (domaine par défaut si vide)")
+	RulesCmd.Flags().StringArrayVar(&rulesSetFlags, "set", nil, "Règle \"os=...,device=...,lang=...,country=FR|BE,url=...\" (répétable, remplace toutes les règles)")
+	RulesCmd.Flags().BoolVar(&rulesClearFlag, "clear", false, "Supprime toutes les règles de ciblage du lien")
+
+	if err := RulesCmd.MarkFlagRequired("code"); err != nil {
+		log.Fatalf("FATAL: Impossible de marquer le flag code comme requis: %v", err)
+	}
+
+	cmd2.RootCmd.AddCommand(RulesCmd)
+}
@@ -6,12 +6,11 @@ import (
 	"os"
 
 	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/database"
 	"github.com/armanceau/go-url-shortener/internal/repository"
 	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
 	"github.com/spf13/cobra"
-
-	"github.com/glebarez/sqlite" // Pure go SQLite driver
-	"gorm.io/gorm"
 )
 
 // shortCodeFlag stockera la valeur du flag --code
@@ -25,7 +24,8 @@ var StatsCmd = &cobra.Command{
 pour une URL courte spécifique en utilisant son code.
 
 Exemple:
-  url-shortener stats --code="xyz123"`,
+  url-shortener stats --code="xyz123"
+  url-shortener stats --code="xyz123" --domain="go.marque.fr"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Valider que le flag --code a été fourni
 		if shortCodeFlag == "" {
@@ -39,7 +39,7 @@ Exemple:
 		}
 
 		// Initialiser la connexion à la BDD
-		db, err := gorm.Open(sqlite.Open(cmd2.Cfg.Database.Name), &gorm.Config{})
+		db, err := database.Open(cmd2.Cfg)
 		if err != nil {
 			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
 		}
@@ -60,11 +60,13 @@ Exemple:
 		linkRepo := repository.NewLinkRepository(db)
 		clickRepo := repository.NewClickRepository(db)
 		clickService := services.NewClickService(clickRepo)
-		linkService := services.NewLinkService(linkRepo, clickService)
+		linkService := services.NewLinkService(linkRepo, clickService, nil, shortcode.NewPolicy(cmd2.Cfg.ShortCode), nil)
+		domainService := services.NewDomainService(repository.NewDomainRepository(db), cmd2.Cfg.Server.BaseURL)
+		domainID := resolveDomainFlag(domainService)
 
 		// Appeler GetLinkStats pour récupérer le lien et ses statistiques
 		// Attention, la fonction retourne 3 valeurs
-		link, totalClicks, err := linkService.GetLinkStats(shortCodeFlag)
+		link, totalClicks, err := linkService.GetLinkStats(domainID, shortCodeFlag)
 		if err != nil {
 			log.Printf("ERREUR: Impossible de récupérer les statistiques pour le code '%s': %v", shortCodeFlag, err)
 			os.Exit(1)
@@ -73,6 +75,37 @@ Exemple:
 		fmt.Printf("Statistiques pour le code court: %s\n", link.ShortCode)
 		fmt.Printf("URL longue: %s\n", link.LongURL)
 		fmt.Printf("Total de clics: %d\n", totalClicks)
+		if link.IsLimited() {
+			fmt.Printf("Utilisations: %d/%d\n", link.UseCount, link.MaxUses)
+		}
+
+		variantStats, err := linkService.GetVariantStats(link)
+		if err != nil {
+			log.Printf("ERREUR: Impossible de récupérer les clics par variante: %v", err)
+			os.Exit(1)
+		}
+		if len(variantStats) > 0 {
+			fmt.Println("Clics par variante:")
+			for _, stat := range variantStats {
+				fmt.Printf("  %s (poids %d): %d\n", stat.Variant.Name, stat.Variant.Weight, stat.Clicks)
+			}
+		}
+
+		geo, err := linkService.GetGeoBreakdown(link.ID)
+		if err != nil {
+			log.Printf("ERREUR: Impossible de récupérer la répartition géographique: %v", err)
+			os.Exit(1)
+		}
+		if len(geo.Countries) > 0 {
+			fmt.Println("Clics par pays:")
+			for _, country := range geo.Countries {
+				name := country.Country
+				if name == "" {
+					name = "inconnu"
+				}
+				fmt.Printf("  %s: %d\n", name, country.Clicks)
+			}
+		}
 	},
 }
 
@@ -82,6 +115,8 @@ func init() {
 	// Définir le flag --code pour la commande stats
 	StatsCmd.Flags().StringVarP(&shortCodeFlag, "code", "c", "", "Code court pour lequel récupérer les statistiques (requis)")
 
+	StatsCmd.Flags().StringVar(&domainFlag, "domain", "", "Domaine personnalisé du lien (domaine par défaut si vide)")
+
 	// Marquer le flag comme requis
 	if err := StatsCmd.MarkFlagRequired("code"); err != nil {
 		log.Fatalf("FATAL: Impossible de marquer le flag code comme requis: %v", err)
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/armanceau/go-url-shortener/cmd"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/spf13/cobra"
+)
+
+// Flags de la commande update
+var (
+	updateCodeFlag         string
+	updateStatusFlag       int
+	updateInterstitialFlag bool
+
+	updatePassQueryFlag     bool
+	updateQueryConflictFlag string
+	updatePassPathFlag      bool
+	updateStickyVariantFlag bool
+)
+
+// UpdateCmd représente la commande 'update'
+var UpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Modifie les réglages de redirection d'un lien court existant.",
+	Long: `Cette commande modifie le code de redirection (301/302/307/308), le mode
+page intermédiaire et la transmission du chemin et des paramètres de requête
+d'un lien court. Seuls les flags fournis sont modifiés.
+
+Exemple:
+  url-shortener update --code="xyz123" --status=308
+  url-shortener update --code="xyz123" --interstitial=false
+  url-shortener update --code="xyz123" --pass-query --query-conflict=append`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if updateCodeFlag == "" {
+			log.Printf("ERREUR: Le flag --code est requis")
+			os.Exit(1)
+		}
+
+		// Ne modifier que les réglages explicitement fournis
+		var opts services.UpdateLinkOptions
+		if cmd.Flags().Changed("status") {
+			opts.RedirectStatus = &updateStatusFlag
+		}
+		if cmd.Flags().Changed("interstitial") {
+			opts.Interstitial = &updateInterstitialFlag
+		}
+		if cmd.Flags().Changed("pass-query") {
+			opts.PassQuery = &updatePassQueryFlag
+		}
+		if cmd.Flags().Changed("query-conflict") {
+			opts.QueryConflict = &updateQueryConflictFlag
+		}
+		if cmd.Flags().Changed("pass-path") {
+			opts.PassPath = &updatePassPathFlag
+		}
+		if cmd.Flags().Changed("sticky-variant") {
+			opts.StickyVariant = &updateStickyVariantFlag
+		}
+		if opts == (services.UpdateLinkOptions{}) {
+			log.Printf("ERREUR: Aucun réglage à modifier (voir 'url-shortener update --help')")
+			os.Exit(1)
+		}
+
+		// Charger la configuration chargée globalement via cmd.cfg
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: Configuration not loaded")
+		}
+
+		db, err := database.Open(cmd2.Cfg)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+		}
+
+		defer func() {
+			if err := sqlDB.Close(); err != nil {
+				log.Printf("Erreur lors de la fermeture de la base de données: %v", err)
+			}
+		}()
+
+		repos, closeCache := cachedRepositories(db)
+		defer closeCache()
+
+		clickService := services.NewClickService(repos.Clicks)
+		linkService := services.NewLinkService(repos.Links, clickService, nil, shortcode.NewPolicy(cmd2.Cfg.ShortCode), nil)
+		domainService := services.NewDomainService(repos.Domains, cmd2.Cfg.Server.BaseURL)
+		domainID := resolveDomainFlag(domainService)
+
+		link, err := linkService.UpdateLink(domainID, updateCodeFlag, opts)
+		if err != nil {
+			log.Printf("ERREUR: Impossible de modifier le lien '%s': %v", updateCodeFlag, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Lien %s mis à jour:\n", link.ShortCode)
+		fmt.Printf("Redirection: %d (page intermédiaire: %t)\n", link.EffectiveRedirectStatus(), link.Interstitial)
+		fmt.Printf("Transmission: paramètres=%t (conflit: %s), chemin=%t\n", link.PassQuery, link.QueryConflict, link.PassPath)
+	},
+}
+
+func init() {
+	UpdateCmd.Flags().StringVarP(&updateCodeFlag, "code", "c", "", "Code court du lien à modifier (requis)")
+	UpdateCmd.Flags().StringVar(&domainFlag, "domain", "", "Domaine personnalisé du lien (domaine par défaut si vide)")
+	UpdateCmd.Flags().IntVar(&updateStatusFlag, "status", 0, "Nouveau code HTTP de redirection: 301, 302, 307 ou 308")
+	UpdateCmd.Flags().BoolVar(&updateInterstitialFlag, "interstitial", false, "Active ou désactive la page intermédiaire")
+	UpdateCmd.Flags().BoolVar(&updatePassQueryFlag, "pass-query", false, "Active ou désactive la transmission des paramètres de requête")
+	UpdateCmd.Flags().StringVar(&updateQueryConflictFlag, "query-conflict", "", "Politique de conflit des paramètres: link, request ou append")
+	UpdateCmd.Flags().BoolVar(&updatePassPathFlag, "pass-path", false, "Active ou désactive la transmission du chemin")
+	UpdateCmd.Flags().BoolVar(&updateStickyVariantFlag, "sticky-variant", false, "Active ou désactive la persistance de la variante A/B par visiteur")
+
+	if err := UpdateCmd.MarkFlagRequired("code"); err != nil {
+		log.Fatalf("FATAL: Impossible de marquer le flag code comme requis: %v", err)
+	}
+
+	cmd2.RootCmd.AddCommand(UpdateCmd)
+}
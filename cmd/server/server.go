@@ -12,15 +12,20 @@ import (
 
 	cmd2 "github.com/armanceau/go-url-shortener/cmd"
 	"github.com/armanceau/go-url-shortener/internal/api"
+	"github.com/armanceau/go-url-shortener/internal/cache"
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/database"
+	"github.com/armanceau/go-url-shortener/internal/geoip"
+	"github.com/armanceau/go-url-shortener/internal/migrations"
 	"github.com/armanceau/go-url-shortener/internal/models"
 	"github.com/armanceau/go-url-shortener/internal/monitor"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
 	"github.com/armanceau/go-url-shortener/internal/repository"
 	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
 	"github.com/armanceau/go-url-shortener/internal/workers"
 	"github.com/gin-gonic/gin"
-	"github.com/glebarez/sqlite" // Pure go SQLite driver, checkout https://github.com/glebarez/sqlite for details
 	"github.com/spf13/cobra"
-	"gorm.io/gorm"
 )
 
 // RunServerCmd représente la commande 'run-server' de Cobra.
@@ -39,16 +44,38 @@ puis lance le serveur HTTP.`,
 		}
 		cfg := cmd2.Cfg
 
-		// Initialiser la connexion à la BDD
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
-		if err != nil {
-			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+		// Initialiser les repositories
+		// Avec le pilote memory, aucune base n'est ouverte et les données sont perdues à l'arrêt
+		var repos repository.Repositories
+		if cfg.Database.Driver == database.DriverMemory {
+			log.Println("Attention: stockage en mémoire, les données seront perdues à l'arrêt du serveur.")
+			repos = repository.NewMemoryRepositories()
+		} else {
+			// Initialiser la connexion à la BDD
+			db, err := database.Open(cfg)
+			if err != nil {
+				log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+			}
+
+			// Le serveur n'applique pas les migrations : il refuse de démarrer sur un schéma d'une autre version
+			if err := migrations.CheckVersion(db); err != nil {
+				log.Fatalf("FATAL: %v", err)
+			}
+			repos = repository.NewGormRepositories(db)
 		}
 
-		// Initialiser les repositories
-		// Créez des instances de GormLinkRepository et GormClickRepository
-		linkRepo := repository.NewLinkRepository(db)
-		clickRepo := repository.NewClickRepository(db)
+		// Cache Redis optionnel, partagé entre les instances (section cache)
+		repos, closeCache, err := cache.Decorate(cfg, repos)
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'initialisation du cache: %v", err)
+		}
+		defer closeCache()
+		if cfg.Cache.Driver != "" {
+			log.Printf("Cache %s activé sur %s.", cfg.Cache.Driver, cfg.Cache.Redis.Addr)
+		}
+		linkRepo := repos.Links
+		clickRepo := repos.Clicks
+		domainRepo := repos.Domains
 
 		// Laissez le log
 		log.Println("Repositories initialisés.")
@@ -56,7 +83,15 @@ puis lance le serveur HTTP.`,
 		// Initialiser les services métiers
 		// Créez des instances de LinkService et ClickService, en leur passant les repositories nécessaires
 		clickService := services.NewClickService(clickRepo)
-		linkService := services.NewLinkService(linkRepo, clickService)
+		codeGenerator, err := shortcode.New(cfg.ShortCode, repos.Counters)
+		if err != nil {
+			log.Fatalf("FATAL: Configuration shortcode invalide: %v", err)
+		}
+		linkService := services.NewLinkService(linkRepo, clickService, codeGenerator, shortcode.NewPolicy(cfg.ShortCode), canonical.New(cfg.Dedupe.StripParams))
+		domainService := services.NewDomainService(domainRepo, cfg.Server.BaseURL)
+		exportService := services.NewExportService(linkRepo, clickRepo, domainService)
+		importService := services.NewImportService(linkService, domainService)
+		qrService := services.NewQRService(linkService, domainService, cfg.QR.CacheSize)
 
 		// Laissez le log
 		log.Println("Services métiers initialisés.")
@@ -64,13 +99,60 @@ puis lance le serveur HTTP.`,
 		// Initialiser le channel ClickEventsChannel (api/handlers) des événements de clic et lancer les workers (StartClickWorkers)
 		// Le channel est bufferisé avec la taille configurée
 		// Passez le channel et le clickRepo aux workers
+		// Ouvrir les bases GeoIP optionnelles : en cas d'échec, les clics sont enregistrés sans localisation
+		geoResolver, err := geoip.Open(cfg.GeoIP.CityDatabase, cfg.GeoIP.ASNDatabase)
+		if err != nil {
+			log.Printf("Attention: GeoIP indisponible, enrichissement des clics désactivé: %v", err)
+			geoResolver = nil
+		} else if geoResolver.Enabled() {
+			log.Println("Bases GeoIP chargées, enrichissement des clics activé.")
+		}
+		defer geoResolver.Close()
+
+		// Les adresses IP sont enregistrées selon le mode de confidentialité, après l'enrichissement GeoIP
+		anonymizer, err := privacy.New(cfg.Privacy)
+		if err != nil {
+			log.Fatalf("FATAL: Configuration privacy invalide: %v", err)
+		}
+		if anonymizer.Mode() == privacy.ModeHash && cfg.Privacy.HashSecret == "" {
+			log.Println("Attention: privacy.hash_secret vide, les empreintes d'IP changeront à chaque démarrage.")
+		}
+
 		cfg.ClickEventsChannel = make(chan models.ClickEvent, cfg.Analytics.BufferSize)
-		workers.StartClickWorkers(cfg.Analytics.WorkerCount, cfg.ClickEventsChannel, clickRepo)
+		workers.StartClickWorkers(cfg.Analytics.WorkerCount, cfg.ClickEventsChannel, clickRepo, geoResolver, anonymizer)
 
 		// Remplacer les XXX par les bonnes variables
 		log.Printf("Channel d'événements de clic initialisé avec un buffer de %d. %d worker(s) de clics démarré(s).",
 			cfg.Analytics.BufferSize, cfg.Analytics.WorkerCount)
 
+		// Agréger périodiquement les clics pour que les statistiques ne parcourent pas toute la table des clics
+		// Puis purger les clics bruts au-delà de la durée de rétention, une fois agrégés
+		if repos.Rollups != nil {
+			rollupService := services.NewRollupService(repos.Rollups, time.Duration(cfg.Analytics.RollupDelayMinutes)*time.Minute)
+			if cfg.Analytics.RollupIntervalMinutes > 0 {
+				rollupInterval := time.Duration(cfg.Analytics.RollupIntervalMinutes) * time.Minute
+				workers.StartRollupWorker(rollupService, rollupInterval)
+				log.Printf("Agrégation des clics démarrée avec un intervalle de %v.", rollupInterval)
+			}
+			if cfg.Retention.ClickDays > 0 {
+				retention := time.Duration(cfg.Retention.ClickDays) * 24 * time.Hour
+				purgeInterval := time.Duration(cfg.Retention.IntervalMinutes) * time.Minute
+				if purgeInterval <= 0 {
+					purgeInterval = time.Hour
+				}
+				workers.StartRetentionWorker(rollupService, retention, purgeInterval)
+				log.Printf("Purge des clics de plus de %d jour(s) démarrée.", cfg.Retention.ClickDays)
+			}
+		}
+
+		// Conserver les réponses des créations portant un en-tête Idempotency-Key, et purger celles dont la fenêtre est écoulée
+		var idempotencyService *services.IdempotencyService
+		if cfg.Idempotency.WindowHours > 0 {
+			idempotencyService = services.NewIdempotencyService(repos.Idempotency, time.Duration(cfg.Idempotency.WindowHours)*time.Hour)
+			workers.StartIdempotencyPurgeWorker(idempotencyService, time.Hour)
+			log.Printf("Clés d'idempotence conservées pendant %d heure(s).", cfg.Idempotency.WindowHours)
+		}
+
 		// Initialiser et lancer le moniteur d'URLs
 		// Utilisez l'intervalle configuré
 		monitorInterval := time.Duration(cfg.Monitor.IntervalMinutes) * time.Minute
@@ -83,8 +165,14 @@ puis lance le serveur HTTP.`,
 
 		// Configurer le routeur Gin et les handlers API
 		// Passez les services nécessaires aux fonctions de configuration des routes
-		router := gin.Default()
-		api.SetupRoutes(router, linkService, cfg)
+		// Le journal des requêtes applique aux adresses IP le même traitement que les clics enregistrés
+		router := gin.New()
+		router.Use(api.AccessLogger(gin.DefaultWriter, anonymizer, cfg.Privacy.HonorDNT), gin.Recovery())
+		// Sans proxy de confiance, les en-têtes X-Forwarded-For et consorts sont ignorés : ils sont falsifiables
+		if err := api.ConfigureClientIP(router, cfg.Server.TrustedProxies, cfg.Server.ClientIPHeaders); err != nil {
+			log.Fatalf("FATAL: server.trusted_proxies invalide: %v", err)
+		}
+		api.SetupRoutes(router, linkService, domainService, exportService, importService, qrService, idempotencyService, geoResolver, cfg)
 
 		// Pas toucher au log
 		log.Println("Routes API configurées.")
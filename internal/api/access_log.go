@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/privacy"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogger journalise chaque requête dans out, au format du logger par défaut de Gin, mais avec l'adresse
+// du client transformée par l'anonymizer (privacy.ip_mode), comme celle des clics enregistrés :
+// les journaux ne conservent pas d'adresse que la base n'aurait pas gardée. Si honorDNT est vrai,
+// l'adresse des visiteurs qui refusent le suivi (DNT, Sec-GPC) n'est pas journalisée.
+func AccessLogger(out io.Writer, anonymizer *privacy.Anonymizer, honorDNT bool) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			clientIP := anonymizer.Anonymize(param.ClientIP, param.TimeStamp)
+			if honorDNT && privacy.OptedOut(param.Request.Header) {
+				clientIP = ""
+			}
+			if clientIP == "" {
+				clientIP = "-"
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency.Truncate(time.Microsecond),
+				clientIP,
+				param.Method,
+				param.Path,
+				param.ErrorMessage,
+			)
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/privacy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAccessLoggerAnonymizesClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		mode    string
+		header  http.Header
+		want    string
+		notWant string
+	}{
+		{privacy.ModeFull, http.Header{}, "203.0.113.7", ""},
+		{privacy.ModeTruncate, http.Header{}, "203.0.113.0", "203.0.113.7"},
+		{privacy.ModeDrop, http.Header{}, " - ", "203.0.113"},
+		{privacy.ModeHash, http.Header{}, "", "203.0.113"},
+		{privacy.ModeFull, http.Header{"Dnt": {"1"}}, " - ", "203.0.113"},
+	}
+	for _, tt := range tests {
+		anonymizer, err := privacy.New(privacy.Options{IPMode: tt.mode, IPv4Prefix: 24, IPv6Prefix: 48, HashSecret: "s"})
+		if err != nil {
+			t.Fatalf("privacy.New: %v", err)
+		}
+		var out bytes.Buffer
+		router := gin.New()
+		router.Use(AccessLogger(&out, anonymizer, true))
+		router.GET("/abc", func(c *gin.Context) { c.Status(http.StatusFound) })
+
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header = tt.header
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		line := out.String()
+		if !strings.Contains(line, "302") || !strings.Contains(line, `"/abc"`) || !strings.Contains(line, tt.want) {
+			t.Errorf("%s mode, header %v: log line %q does not contain %q", tt.mode, tt.header, line, tt.want)
+		}
+		if tt.notWant != "" && strings.Contains(line, tt.notWant) {
+			t.Errorf("%s mode, header %v: log line %q contains %q", tt.mode, tt.header, line, tt.notWant)
+		}
+	}
+}
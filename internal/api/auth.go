@@ -0,0 +1,34 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAPIToken protège une route par un jeton d'API, transmis dans l'en-tête "Authorization: Bearer <jeton>".
+// Sans jeton configuré (auth.api_tokens), toutes les requêtes sont refusées.
+func RequireAPIToken(tokens []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !validToken(strings.TrimSpace(provided), tokens) {
+			c.Header("WWW-Authenticate", `Bearer realm="url-shortener"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid API token is required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// validToken compare le jeton fourni à chaque jeton configuré en temps constant.
+func validToken(provided string, tokens []string) bool {
+	valid := false
+	for _, token := range tokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBatchSize est le nombre maximal de liens créés par un appel à POST /api/v1/links/batch.
+const maxBatchSize = 1000
+
+// CreateLinksBatchHandler gère POST /api/v1/links/batch : le corps est un tableau de CreateLinkRequest.
+// Chaque élément est traité comme par POST /api/v1/links et reçoit son propre résultat, dans l'ordre,
+// avec le statut qu'aurait renvoyé la création seule (201 créé, 200 dédupliqué, 400 invalide, 500 erreur).
+// Un élément invalide n'empêche pas la création des autres.
+func CreateLinksBatchHandler(linkService *services.LinkService, domainService *services.DomainService, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var items []json.RawMessage
+		if err := json.NewDecoder(c.Request.Body).Decode(&items); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: expected a JSON array of links"})
+			return
+		}
+		if len(items) == 0 || len(items) > maxBatchSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch must contain between 1 and %d links", maxBatchSize)})
+			return
+		}
+
+		results := make([]gin.H, len(items))
+		requests := make([]services.BatchLinkRequest, 0, len(items))
+		positions := make([]int, 0, len(items)) // requête transmise au service -> élément du lot
+		domainNames := make([]string, len(items))
+		domains := make(map[string]uint)
+
+		for i, item := range items {
+			var req CreateLinkRequest
+			if err := binding.JSON.BindBody(item, &req); err != nil {
+				results[i] = batchError(i, http.StatusBadRequest, "Invalid request format: "+err.Error())
+				continue
+			}
+
+			domainID, ok := domains[req.Domain]
+			if !ok {
+				var err error
+				domainID, err = domainService.ResolveName(req.Domain)
+				if err != nil {
+					if errors.Is(err, services.ErrUnknownDomain) {
+						results[i] = batchError(i, http.StatusBadRequest, err.Error())
+						continue
+					}
+					log.Printf("Error resolving domain %q: %v", req.Domain, err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+					return
+				}
+				domains[req.Domain] = domainID
+			}
+
+			dedupe := cfg.Dedupe.Default
+			if req.Dedupe != nil {
+				dedupe = *req.Dedupe
+			}
+			requests = append(requests, services.BatchLinkRequest{LongURL: req.LongURL, Options: req.options(domainID), Dedupe: dedupe})
+			positions = append(positions, i)
+			domainNames[i] = req.Domain
+		}
+
+		created, err := linkService.CreateLinks(requests)
+		if err != nil {
+			log.Printf("Error creating batch of %d link(s): %v", len(requests), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create short links"})
+			return
+		}
+
+		for k, result := range created {
+			i := positions[k]
+			switch {
+			case result.Err != nil && isInvalidLinkError(result.Err):
+				results[i] = batchError(i, http.StatusBadRequest, result.Err.Error())
+			case result.Err != nil:
+				log.Printf("Error creating short link %d of batch: %v", i, result.Err)
+				results[i] = batchError(i, http.StatusInternalServerError, "Failed to create short link")
+			default:
+				status := http.StatusCreated
+				if result.Deduplicated {
+					status = http.StatusOK
+				}
+				body, err := createdLinkJSON(domainService, result.Link, domainNames[i], result.Deduplicated)
+				if err != nil {
+					log.Printf("Error building short URL for %s: %v", result.Link.ShortCode, err)
+					results[i] = batchError(i, http.StatusInternalServerError, "Internal server error")
+					break
+				}
+				body["index"], body["status"] = i, status
+				results[i] = body
+			}
+		}
+		counts := make(map[int]int)
+		for _, result := range results {
+			counts[result["status"].(int)]++
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"results":      results,
+			"created":      counts[http.StatusCreated],
+			"deduplicated": counts[http.StatusOK],
+			"failed":       len(results) - counts[http.StatusCreated] - counts[http.StatusOK],
+		})
+	}
+}
+
+// batchError prépare le résultat en échec d'un élément du lot.
+func batchError(index, status int, message string) gin.H {
+	return gin.H{"index": index, "status": status, "error": message}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateLinksBatch(t *testing.T) {
+	s := newTestServer(t)
+	if rec := s.do(http.MethodPost, "/api/v1/domains", `{"host":"brand.test"}`, authHeader...); rec.Code != http.StatusCreated {
+		t.Fatalf("POST domain: status = %d", rec.Code)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/links/batch", `[
+		{"long_url":"https://example.com/a","owner":"alice"},
+		{"long_url":"https://example.com/b","domain":"brand.test"},
+		{"long_url":"not a url"},
+		{"long_url":"https://example.com","domain":"unknown.test"},
+		{"long_url":"https://example.com/a/","owner":"alice","dedupe":true},
+		{"long_url":"javascript://example.com/%0Aalert(1)"}]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var batch struct {
+		Results []struct {
+			Index        int    `json:"index"`
+			Status       int    `json:"status"`
+			ShortCode    string `json:"short_code"`
+			FullShortURL string `json:"full_short_url"`
+			Error        string `json:"error"`
+		} `json:"results"`
+		Created      int `json:"created"`
+		Deduplicated int `json:"deduplicated"`
+		Failed       int `json:"failed"`
+	}
+	decodeJSON(t, rec, &batch)
+	if batch.Created != 2 || batch.Deduplicated != 1 || batch.Failed != 3 || len(batch.Results) != 6 {
+		t.Fatalf("batch = %+v, want 2 created, 1 deduplicated and 3 failed", batch)
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusCreated, http.StatusBadRequest, http.StatusBadRequest, http.StatusOK, http.StatusBadRequest} {
+		if result := batch.Results[i]; result.Index != i || result.Status != want {
+			t.Errorf("result %d = %+v, want status %d", i, result, want)
+		}
+	}
+	if !strings.HasPrefix(batch.Results[1].FullShortURL, "https://brand.test/") {
+		t.Errorf("full_short_url = %q, want it on brand.test", batch.Results[1].FullShortURL)
+	}
+	if batch.Results[4].ShortCode != batch.Results[0].ShortCode {
+		t.Errorf("deduplicated code = %q, want %q", batch.Results[4].ShortCode, batch.Results[0].ShortCode)
+	}
+
+	if rec := s.do(http.MethodGet, "/"+batch.Results[0].ShortCode, ""); rec.Code != http.StatusFound {
+		t.Errorf("GET created link: status = %d, want 302", rec.Code)
+	}
+}
+
+func TestCreateLinksBatchRejectsInvalidBodies(t *testing.T) {
+	s := newTestServer(t)
+	tooMany := "[" + strings.Repeat(`{"long_url":"https://example.com"},`, maxBatchSize) + `{"long_url":"https://example.com"}]`
+	for _, body := range []string{`{"long_url":"https://example.com"}`, `[]`, `not json`, tooMany} {
+		if rec := s.do(http.MethodPost, "/api/v1/links/batch", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %.40s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
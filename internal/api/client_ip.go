@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headerForwarded est l'en-tête standard des proxys (RFC 7239).
+const headerForwarded = "Forwarded"
+
+// ConfigureClientIP définit comment le moteur Gin détermine l'adresse du client (c.ClientIP()).
+// Les en-têtes de proxy ne sont lus que si la requête provient d'un des proxys de confiance (CIDR ou adresses) :
+// sans proxy de confiance, l'adresse de la connexion est utilisée et les en-têtes, falsifiables, sont ignorés.
+// headers liste les en-têtes lus, par ordre de priorité, parmi Forwarded, X-Forwarded-For, X-Real-IP...
+func ConfigureClientIP(router *gin.Engine, trustedProxies, headers []string) error {
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return err
+	}
+
+	// Un en-tête listé plusieurs fois (x-forwarded-for et X-Forwarded-For) n'est lu qu'une fois, à son premier rang
+	router.RemoteIPHeaders = make([]string, 0, len(headers))
+	seen := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header == "" || seen[header] {
+			continue
+		}
+		seen[header] = true
+		router.RemoteIPHeaders = append(router.RemoteIPHeaders, header)
+	}
+	if seen[headerForwarded] {
+		router.Use(normalizeForwardedHeader)
+	}
+	return nil
+}
+
+// normalizeForwardedHeader réécrit l'en-tête Forwarded sous la forme d'une liste d'adresses (comme X-Forwarded-For),
+// la seule que Gin sait parcourir en ignorant les proxys de confiance.
+func normalizeForwardedHeader(c *gin.Context) {
+	if values := c.Request.Header.Values(headerForwarded); len(values) > 0 {
+		c.Request.Header.Set(headerForwarded, strings.Join(forwardedFor(strings.Join(values, ",")), ", "))
+	}
+	c.Next()
+}
+
+// forwardedFor extrait les paramètres "for" des éléments d'un en-tête Forwarded (RFC 7239), du client au dernier proxy.
+// Un nœud inconnu ou masqué ("unknown", "_secret") est conservé tel quel : ce n'est pas une adresse,
+// et Gin s'arrête donc à ce nœud plutôt que de croire l'adresse qui le précède.
+func forwardedFor(header string) []string {
+	var addresses []string
+	for _, element := range splitQuoted(header, ',') {
+		for _, pair := range splitQuoted(element, ';') {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+				continue
+			}
+			addresses = append(addresses, forwardedNode(strings.Trim(strings.TrimSpace(value), `"`)))
+		}
+	}
+	return addresses
+}
+
+// forwardedNode renvoie l'adresse d'un nœud Forwarded, sans crochets IPv6 ni port.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// splitQuoted découpe s selon sep, en ignorant les séparateurs placés entre guillemets.
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientIPRouter renvoie un routeur qui répond l'adresse client déterminée par Gin.
+func clientIPRouter(t *testing.T, trustedProxies, headers []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := ConfigureClientIP(router, trustedProxies, headers); err != nil {
+		t.Fatalf("ConfigureClientIP: %v", err)
+	}
+	router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+	return router
+}
+
+func TestClientIP(t *testing.T) {
+	defaultHeaders := []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+	tests := []struct {
+		name    string
+		trusted []string
+		remote  string
+		header  http.Header
+		want    string
+	}{
+		{
+			name:   "no trusted proxy ignores spoofed X-Forwarded-For",
+			remote: "192.0.2.50:1234",
+			header: http.Header{"X-Forwarded-For": {"203.0.113.9"}},
+			want:   "192.0.2.50",
+		},
+		{
+			name:    "untrusted peer ignores spoofed X-Forwarded-For",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "192.0.2.50:1234",
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.9"}, "X-Real-Ip": {"203.0.113.10"}},
+			want:    "192.0.2.50",
+		},
+		{
+			name:    "untrusted peer ignores spoofed Forwarded",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "192.0.2.50:1234",
+			header:  http.Header{"Forwarded": {"for=203.0.113.9"}},
+			want:    "192.0.2.50",
+		},
+		{
+			name:    "trusted proxy",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.9"}},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "trusted proxy chain keeps the first untrusted address",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.9, 10.0.0.7"}},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "trusted proxy ignores addresses prepended by the client",
+			trusted: []string{"10.0.0.5"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"X-Forwarded-For": {"127.0.0.1, 203.0.113.9"}},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "Forwarded takes priority",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"Forwarded": {`for=198.51.100.1;proto=https, for="[2001:db8::1]:443"`}, "X-Forwarded-For": {"203.0.113.9"}},
+			want:    "2001:db8::1",
+		},
+		{
+			name:    "Forwarded over several header lines",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"Forwarded": {"for=198.51.100.1", "for=10.0.0.9:8080"}},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "obfuscated Forwarded node is not trusted through",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"Forwarded": {"for=198.51.100.1, for=_hidden"}},
+			want:    "10.0.0.5",
+		},
+		{
+			name:    "X-Real-IP as last resort",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.5:1234",
+			header:  http.Header{"X-Real-Ip": {"203.0.113.10"}},
+			want:    "203.0.113.10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := clientIPRouter(t, tt.trusted, defaultHeaders)
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remote
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureClientIPDeduplicatesHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	headers := []string{"forwarded", " X-Forwarded-For", "Forwarded", "x-forwarded-for", "", "X-Real-IP"}
+	if err := ConfigureClientIP(router, []string{"10.0.0.0/8"}, headers); err != nil {
+		t.Fatalf("ConfigureClientIP: %v", err)
+	}
+	want := []string{"Forwarded", "X-Forwarded-For", "X-Real-Ip"}
+	if !reflect.DeepEqual(router.RemoteIPHeaders, want) {
+		t.Errorf("RemoteIPHeaders = %v, want %v", router.RemoteIPHeaders, want)
+	}
+	if len(router.Handlers) != 1 {
+		t.Errorf("%d middlewares registered, want the Forwarded normalization once", len(router.Handlers))
+	}
+
+	router = gin.New()
+	if err := ConfigureClientIP(router, nil, []string{"X-Forwarded-For"}); err != nil {
+		t.Fatalf("ConfigureClientIP: %v", err)
+	}
+	if len(router.Handlers) != 0 {
+		t.Errorf("%d middlewares registered without the Forwarded header, want none", len(router.Handlers))
+	}
+}
+
+func TestConfigureClientIPRejectsInvalidProxies(t *testing.T) {
+	if err := ConfigureClientIP(gin.New(), []string{"not-a-cidr"}, nil); err == nil {
+		t.Error("ConfigureClientIP accepted an invalid trusted proxy")
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"for=192.0.2.60;proto=http;by=203.0.113.43", []string{"192.0.2.60"}},
+		{`For="[2001:db8:cafe::17]:4711"`, []string{"2001:db8:cafe::17"}},
+		{"for=192.0.2.43, for=198.51.100.17:8080", []string{"192.0.2.43", "198.51.100.17"}},
+		{`for=unknown, for="_gazonk"`, []string{"unknown", "_gazonk"}},
+		{`proto=https;host="a,b";for=192.0.2.1`, []string{"192.0.2.1"}},
+		{"by=203.0.113.43", nil},
+	}
+	for _, tt := range tests {
+		if got := forwardedFor(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("forwardedFor(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
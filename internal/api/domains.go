@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateDomainRequest représente le corps de la requête JSON pour l'ajout d'un domaine personnalisé.
+type CreateDomainRequest struct {
+	Host    string `json:"host" binding:"required"`
+	BaseURL string `json:"base_url" binding:"omitempty,url"` // "https://<host>" par défaut
+}
+
+// domainJSON prépare un domaine pour une réponse JSON.
+func domainJSON(domain *models.Domain) gin.H {
+	return gin.H{
+		"id":         domain.ID,
+		"host":       domain.Host,
+		"base_url":   domain.BaseURL,
+		"created_at": domain.CreatedAt,
+	}
+}
+
+// requestDomainID résout le domaine choisi par le paramètre de requête "domain" (domaine par défaut si absent).
+// En cas d'erreur, la réponse est déjà écrite et false est renvoyé.
+func requestDomainID(c *gin.Context, domainService *services.DomainService) (uint, bool) {
+	domainID, err := domainService.ResolveName(c.Query("domain"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownDomain) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return 0, false
+		}
+		log.Printf("Error resolving domain %q: %v", c.Query("domain"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return 0, false
+	}
+	return domainID, true
+}
+
+// ListDomainsHandler gère la liste des domaines personnalisés.
+func ListDomainsHandler(domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domains, err := domainService.ListDomains()
+		if err != nil {
+			log.Printf("Error listing domains: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		result := make([]gin.H, len(domains))
+		for i := range domains {
+			result[i] = domainJSON(&domains[i])
+		}
+		c.JSON(http.StatusOK, gin.H{"domains": result})
+	}
+}
+
+// CreateDomainHandler gère l'ajout d'un domaine personnalisé.
+func CreateDomainHandler(domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateDomainRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		domain, err := domainService.CreateDomain(req.Host, req.BaseURL)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidDomain) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrDomainExists) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error creating domain %s: %v", req.Host, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, domainJSON(domain))
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCustomDomains(t *testing.T) {
+	s := newTestServer(t)
+
+	if rec := s.do(http.MethodPost, "/api/v1/domains", `{"host":"brand.test"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST domain without token: status = %d, want 401", rec.Code)
+	}
+
+	rec := s.do(http.MethodPost, "/api/v1/domains", `{"host":"Brand.test"}`, authHeader...)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST domain: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/domains", `{"host":"brand.test"}`, authHeader...); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate domain: status = %d, want 409", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/domains", `{"host":"bad/host"}`, authHeader...); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid domain: status = %d, want 400", rec.Code)
+	}
+
+	rec = s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/brand","domain":"brand.test"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST link: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode    string `json:"short_code"`
+		FullShortURL string `json:"full_short_url"`
+	}
+	decodeJSON(t, rec, &created)
+	if created.FullShortURL != "https://brand.test/"+created.ShortCode {
+		t.Errorf("full_short_url = %q, want it on https://brand.test", created.FullShortURL)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","domain":"unknown.test"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown domain: status = %d, want 400", rec.Code)
+	}
+
+	// Le domaine d'une redirection est celui de l'en-tête Host
+	req := func(host string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/"+created.ShortCode, nil)
+		r.Host = host
+		return r
+	}
+	if rec := s.serve(req("brand.test")); rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/brand" {
+		t.Errorf("GET on brand.test: status = %d, Location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := s.serve(req("sho.rt")); rec.Code != http.StatusNotFound {
+		t.Errorf("GET on the default domain: status = %d, want 404", rec.Code)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/links/"+created.ShortCode+"/stats?domain=brand.test", ""); rec.Code != http.StatusOK {
+		t.Errorf("stats on brand.test: status = %d, want 200", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/links/"+created.ShortCode+"/stats", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("stats on the default domain: status = %d, want 404", rec.Code)
+	}
+
+	var list struct {
+		Domains []struct {
+			Host string `json:"host"`
+		} `json:"domains"`
+	}
+	decodeJSON(t, s.do(http.MethodGet, "/api/v1/domains", ""), &list)
+	if len(list.Domains) != 1 || list.Domains[0].Host != "brand.test" {
+		t.Errorf("domains = %+v, want brand.test", list.Domains)
+	}
+}
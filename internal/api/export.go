@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/export"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFunc est la méthode d'export d'une ressource (liens ou clics).
+type exportFunc func(s *services.ExportService, format string, since, until time.Time, w io.Writer) (int, error)
+
+// ExportLinksHandler gère GET /api/v1/export/links?format=&since=&until=.
+func ExportLinksHandler(exportService *services.ExportService) gin.HandlerFunc {
+	return exportHandler(exportService, "links", (*services.ExportService).ExportLinks)
+}
+
+// ExportClicksHandler gère GET /api/v1/export/clicks?format=&since=&until=.
+func ExportClicksHandler(exportService *services.ExportService) gin.HandlerFunc {
+	return exportHandler(exportService, "clicks", (*services.ExportService).ExportClicks)
+}
+
+// exportHandler diffuse l'export en pièce jointe au fil de sa lecture en base.
+// Les paramètres sont vérifiés avant la première écriture ; une erreur pendant la diffusion
+// ne peut plus changer le statut HTTP : la connexion est interrompue et le fichier reçu est incomplet.
+func exportHandler(exportService *services.ExportService, name string, run exportFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", export.FormatCSV)
+		if !export.IsValidFormat(format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": export.ErrInvalidFormat.Error()})
+			return
+		}
+		since, err := export.ParseTime(c.Query("since"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since: " + err.Error()})
+			return
+		}
+		until, err := export.ParseTime(c.Query("until"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until: " + err.Error()})
+			return
+		}
+
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+		c.Status(http.StatusOK)
+
+		buffered := bufio.NewWriter(c.Writer)
+		count, err := run(exportService, format, since, until, buffered)
+		if err == nil {
+			err = buffered.Flush()
+		}
+		if err != nil {
+			log.Printf("Error exporting %s after %d record(s): %v", name, count, err)
+			abortStream(c)
+		}
+	}
+}
+
+// abortStream ferme la connexion d'une réponse déjà commencée, sans la terminer proprement,
+// pour que le client ne prenne pas un fichier tronqué pour complet.
+func abortStream(c *gin.Context) {
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		log.Printf("Unable to abort streamed response: %v", err)
+		return
+	}
+	conn.Close()
+}
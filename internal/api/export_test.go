@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportRequiresToken(t *testing.T) {
+	// Sans jeton configuré, l'export est fermé
+	if rec := newTestServer(t, withAPIToken()).do(http.MethodGet, "/api/v1/export/links", "", "Authorization", "Bearer "); rec.Code != http.StatusUnauthorized {
+		t.Errorf("export without configured tokens: status = %d, want 401", rec.Code)
+	}
+
+	s := newTestServer(t, withAPIToken("", "secret"))
+	for _, header := range []string{"", "secret", "Bearer wrong", "Bearer ", "Basic secret"} {
+		rec := s.do(http.MethodGet, "/api/v1/export/links", "", "Authorization", header)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: status = %d, want 401 with WWW-Authenticate", header, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/export/links", "", "Authorization", "Bearer secret"); rec.Code != http.StatusOK {
+		t.Errorf("valid token: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestExportLinks(t *testing.T) {
+	s := newTestServer(t, withAPIToken("secret"))
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/exported"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d", rec.Code)
+	}
+
+	rec := s.do(http.MethodGet, "/api/v1/export/links?format=csv", "", "Authorization", "Bearer secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="links.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") || !strings.Contains(rec.Body.String(), "https://example.com/exported") {
+		t.Errorf("export = %s %q", rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	rec = s.do(http.MethodGet, "/api/v1/export/clicks?format=jsonl&since=2024-01-01", "", "Authorization", "Bearer secret")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("clicks export: status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, query := range []string{"format=xlsx", "since=yesterday", "until=2024-13-01"} {
+		if rec := s.do(http.MethodGet, "/api/v1/export/clicks?"+query, "", "Authorization", "Bearer secret"); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/armanceau/go-url-shortener/internal/canonical"
 	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/armanceau/go-url-shortener/internal/geoip"
 	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
 	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm" // Pour gérer gorm.ErrRecordNotFound
 )
@@ -17,8 +21,11 @@ import (
 // aux workers asynchrones. Il est bufferisé pour ne pas bloquer les requêtes de redirection.
 var ClickEventsChannel chan models.ClickEvent
 
-// SetupRoutes configure toutes les routes de l'API Gin et injecte les dépendances nécessaires
-func SetupRoutes(router *gin.Engine, linkService *services.LinkService, cfg *config.Config) {
+// SetupRoutes configure toutes les routes de l'API Gin et injecte les dépendances nécessaires.
+// Le domainService résout le domaine (Host) des redirections et construit les URLs courtes complètes.
+// Le geoResolver (optionnel, peut être nil) sert à évaluer les règles de ciblage par pays.
+// L'idempotencyService (optionnel, nil si idempotency.window_hours vaut 0) enregistre les réponses des créations.
+func SetupRoutes(router *gin.Engine, linkService *services.LinkService, domainService *services.DomainService, exportService *services.ExportService, importService *services.ImportService, qrService *services.QRService, idempotencyService *services.IdempotencyService, geoResolver *geoip.Resolver, cfg *config.Config) {
 	// Utiliser le channel de la configuration au lieu de créer un nouveau
 	ClickEventsChannel = cfg.ClickEventsChannel
 
@@ -29,12 +36,37 @@ func SetupRoutes(router *gin.Engine, linkService *services.LinkService, cfg *con
 	// Doivent être au format /api/v1/
 	api := router.Group("/api/v1")
 	{
-		api.POST("/links", CreateShortLinkHandler(linkService, cfg))
-		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(linkService))
+		// Les routes /links/:shortCode acceptent ?domain=<host> pour cibler un lien d'un domaine personnalisé
+		// Les créations acceptent un en-tête Idempotency-Key pour rejouer sans risque une requête après un délai d'attente
+		api.POST("/links", Idempotent(idempotencyService), CreateShortLinkHandler(linkService, domainService, cfg))
+		api.POST("/links/batch", Idempotent(idempotencyService), CreateLinksBatchHandler(linkService, domainService, cfg))
+		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(linkService, domainService))
+		api.GET("/links/:shortCode/qr", GetLinkQRCodeHandler(qrService, domainService))
+		api.GET("/links/:shortCode/rules", GetTargetingRulesHandler(linkService, domainService))
+		api.GET("/stats", GetUTMStatsHandler(linkService))
+		api.GET("/domains", ListDomainsHandler(domainService))
+
+		// Modifications des liens existants et des domaines, réservées aux détenteurs d'un jeton d'API (auth.api_tokens) :
+		// elles peuvent détourner le trafic d'un lien
+		admin := api.Group("", RequireAPIToken(cfg.Auth.APITokens))
+		admin.PATCH("/links/:shortCode", UpdateLinkHandler(linkService, domainService))
+		admin.PUT("/links/:shortCode/rules", SetTargetingRulesHandler(linkService, domainService))
+		admin.PUT("/links/:shortCode/variants", SetVariantsHandler(linkService, domainService))
+		admin.POST("/domains", CreateDomainHandler(domainService))
+
+		// Import en masse (migration depuis un autre service), réservé aux détenteurs d'un jeton d'API
+		api.POST("/links/import", RequireAPIToken(cfg.Auth.APITokens), ImportLinksHandler(importService, domainService))
+
+		// Exports en flux, réservés aux détenteurs d'un jeton d'API (auth.api_tokens)
+		exports := api.Group("/export", RequireAPIToken(cfg.Auth.APITokens))
+		exports.GET("/links", ExportLinksHandler(exportService))
+		exports.GET("/clicks", ExportClicksHandler(exportService))
 	}
 
 	// Route de Redirection (au niveau racine pour les short codes)
-	router.GET("/:shortCode", RedirectHandler(linkService))
+	// La seconde route capture les chemins supplémentaires (/abc123/docs/page) pour la transmission du chemin
+	router.GET("/:shortCode", RedirectHandler(linkService, domainService, geoResolver, cfg.Privacy.HonorDNT))
+	router.GET("/:shortCode/*path", RedirectHandler(linkService, domainService, geoResolver, cfg.Privacy.HonorDNT))
 }
 
 // HealthCheckHandler gère la route /health pour vérifier l'état du service.
@@ -46,10 +78,100 @@ func HealthCheckHandler(c *gin.Context) {
 // CreateLinkRequest représente le corps de la requête JSON pour la création d'un lien.
 type CreateLinkRequest struct {
 	LongURL string `json:"long_url" binding:"required,url"`
+	Domain  string `json:"domain"`                  // Nom d'hôte d'un domaine personnalisé (domaine par défaut si vide)
+	Owner   string `json:"owner" binding:"max=100"` // Propriétaire du lien, périmètre de la déduplication
+	// Dedupe renvoie le lien existant du propriétaire pour une URL identique une fois canonicalisée
+	// (dedupe.default de la configuration si absent)
+	Dedupe  *bool `json:"dedupe"`
+	MaxUses int   `json:"max_uses" binding:"omitempty,min=0"` // 0 = illimité, 1 = lien à usage unique
+	// RedirectStatus est le code de redirection du lien (302 par défaut)
+	RedirectStatus int  `json:"redirect_status" binding:"omitempty,oneof=301 302 307 308"`
+	Interstitial   bool `json:"interstitial"` // Affiche une page intermédiaire avant la redirection
+	// Réglages de transmission de la requête entrante vers l'URL longue
+	PassQuery     bool   `json:"pass_query"`
+	QueryConflict string `json:"query_conflict" binding:"omitempty,oneof=link request append"`
+	PassPath      bool   `json:"pass_path"`
+	// Paramètres de campagne UTM, stockés séparément et appliqués à la redirection
+	UTMSource   string `json:"utm_source" binding:"max=100"`
+	UTMMedium   string `json:"utm_medium" binding:"max=100"`
+	UTMCampaign string `json:"utm_campaign" binding:"max=100"`
+	UTMTerm     string `json:"utm_term" binding:"max=100"`
+	UTMContent  string `json:"utm_content" binding:"max=100"`
+	// Règles de redirection ciblée (OS, appareil, langue), évaluées dans l'ordre
+	TargetingRules []TargetingRuleRequest `json:"targeting_rules" binding:"omitempty,dive"`
+	// Destinations pondérées pour les tests A/B, éventuellement persistantes par visiteur
+	Variants      []VariantRequest `json:"variants" binding:"omitempty,dive"`
+	StickyVariant bool             `json:"sticky_variant"`
+}
+
+// UTM renvoie les paramètres UTM de la requête sous forme de models.UTMParams.
+func (r CreateLinkRequest) UTM() models.UTMParams {
+	return models.UTMParams{
+		Source:   r.UTMSource,
+		Medium:   r.UTMMedium,
+		Campaign: r.UTMCampaign,
+		Term:     r.UTMTerm,
+		Content:  r.UTMContent,
+	}
+}
+
+// options renvoie les options de création du lien demandé sur le domaine résolu.
+func (r CreateLinkRequest) options(domainID uint) services.CreateLinkOptions {
+	return services.CreateLinkOptions{
+		DomainID:       domainID,
+		Owner:          r.Owner,
+		MaxUses:        r.MaxUses,
+		RedirectStatus: r.RedirectStatus,
+		Interstitial:   r.Interstitial,
+		PassQuery:      r.PassQuery,
+		QueryConflict:  r.QueryConflict,
+		PassPath:       r.PassPath,
+		UTM:            r.UTM(),
+		TargetingRules: toTargetingRules(r.TargetingRules),
+		Variants:       toVariants(r.Variants),
+		StickyVariant:  r.StickyVariant,
+	}
+}
+
+// isInvalidLinkError indique si la création d'un lien a échoué à cause de la requête (400) plutôt que du serveur.
+func isInvalidLinkError(err error) bool {
+	return errors.Is(err, services.ErrInvalidLongURL) || errors.Is(err, targeting.ErrInvalidRule) || errors.Is(err, services.ErrInvalidVariant) || errors.Is(err, canonical.ErrInvalidURL)
+}
+
+// UpdateLinkRequest représente le corps de la requête JSON pour la modification d'un lien.
+// Les champs absents ne sont pas modifiés.
+type UpdateLinkRequest struct {
+	RedirectStatus *int  `json:"redirect_status" binding:"omitempty,oneof=301 302 307 308"`
+	Interstitial   *bool `json:"interstitial"`
+
+	PassQuery     *bool   `json:"pass_query"`
+	QueryConflict *string `json:"query_conflict" binding:"omitempty,oneof=link request append"`
+	PassPath      *bool   `json:"pass_path"`
+	StickyVariant *bool   `json:"sticky_variant"`
+}
+
+// withLinkSettings ajoute les réglages de redirection du lien à une réponse JSON.
+func withLinkSettings(h gin.H, link *models.Link) gin.H {
+	h["max_uses"] = link.MaxUses
+	h["redirect_status"] = link.EffectiveRedirectStatus()
+	h["interstitial"] = link.Interstitial
+	h["pass_query"] = link.PassQuery
+	h["query_conflict"] = link.QueryConflict
+	h["pass_path"] = link.PassPath
+	h["utm_source"] = link.UTM.Source
+	h["utm_medium"] = link.UTM.Medium
+	h["utm_campaign"] = link.UTM.Campaign
+	h["utm_term"] = link.UTM.Term
+	h["utm_content"] = link.UTM.Content
+	h["targeting_rules"] = targetingRulesJSON(link.TargetingRules)
+	h["sticky_variant"] = link.StickyVariant
+	h["variants"] = variantsJSON(link.Variants)
+	return h
 }
 
 // CreateShortLinkHandler gère la création d'une URL courte.
-func CreateShortLinkHandler(linkService *services.LinkService, cfg *config.Config) gin.HandlerFunc {
+// Avec la déduplication, un lien existant pour la même URL canonique est renvoyé avec le code 200 au lieu d'en créer un nouveau.
+func CreateShortLinkHandler(linkService *services.LinkService, domainService *services.DomainService, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req CreateLinkRequest
 		// Tente de lier le JSON de la requête à la structure CreateLinkRequest
@@ -59,32 +181,144 @@ func CreateShortLinkHandler(linkService *services.LinkService, cfg *config.Confi
 			return
 		}
 
+		// Le code court est unique dans l'espace de noms du domaine choisi
+		domainID, err := domainService.ResolveName(req.Domain)
+		if err != nil {
+			if errors.Is(err, services.ErrUnknownDomain) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error resolving domain %q: %v", req.Domain, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		dedupe := cfg.Dedupe.Default
+		if req.Dedupe != nil {
+			dedupe = *req.Dedupe
+		}
+		if dedupe {
+			existing, err := linkService.FindDuplicateLink(domainID, req.Owner, req.LongURL)
+			switch {
+			case err == nil:
+				respondWithLink(c, domainService, http.StatusOK, existing, req.Domain, true)
+				return
+			case isInvalidLinkError(err):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				log.Printf("Error looking up duplicate link: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+
 		// Appeler le LinkService (CreateLink) pour créer le nouveau lien
-		link, err := linkService.CreateLink(req.LongURL)
+		link, err := linkService.CreateLink(req.LongURL, req.options(domainID))
 		if err != nil {
+			if isInvalidLinkError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			log.Printf("Error creating short link: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create short link"})
 			return
 		}
 
 		// Retourne le code court et l'URL longue dans la réponse JSON
-		// Choisir le bon code HTTP
-		c.JSON(http.StatusCreated, gin.H{
-			"short_code":     link.ShortCode,
-			"long_url":       link.LongURL,
-			"full_short_url": cfg.Server.BaseURL + "/" + link.ShortCode,
+		respondWithLink(c, domainService, http.StatusCreated, link, req.Domain, false)
+	}
+}
+
+// respondWithLink écrit la réponse de création d'un lien, avec son URL courte complète sur son domaine.
+func respondWithLink(c *gin.Context, domainService *services.DomainService, status int, link *models.Link, domain string, deduplicated bool) {
+	body, err := createdLinkJSON(domainService, link, domain, deduplicated)
+	if err != nil {
+		log.Printf("Error building short URL for %s: %v", link.ShortCode, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(status, body)
+}
+
+// createdLinkJSON prépare la réponse de création d'un lien, avec son URL courte complète sur son domaine.
+func createdLinkJSON(domainService *services.DomainService, link *models.Link, domain string, deduplicated bool) (gin.H, error) {
+	fullShortURL, err := domainService.FullShortURL(link)
+	if err != nil {
+		return nil, err
+	}
+
+	return withLinkSettings(gin.H{
+		"short_code":     link.ShortCode,
+		"long_url":       link.LongURL,
+		"domain":         services.NormalizeHost(domain),
+		"owner":          link.Owner,
+		"full_short_url": fullShortURL,
+		"deduplicated":   deduplicated,
+	}, link), nil
+}
+
+// UpdateLinkHandler gère la modification des réglages de redirection et de transmission d'un lien existant.
+func UpdateLinkHandler(linkService *services.LinkService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
+
+		var req UpdateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		link, err := linkService.UpdateLink(domainID, shortCode, services.UpdateLinkOptions{
+			RedirectStatus: req.RedirectStatus,
+			Interstitial:   req.Interstitial,
+			PassQuery:      req.PassQuery,
+			QueryConflict:  req.QueryConflict,
+			PassPath:       req.PassPath,
+			StickyVariant:  req.StickyVariant,
 		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			if errors.Is(err, services.ErrInvalidRedirectStatus) || errors.Is(err, services.ErrInvalidQueryConflict) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error updating link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, withLinkSettings(gin.H{
+			"short_code": link.ShortCode,
+			"long_url":   link.LongURL,
+		}, link))
 	}
 }
 
 // RedirectHandler gère la redirection d'une URL courte vers l'URL longue et l'enregistrement asynchrone des clics.
-func RedirectHandler(linkService *services.LinkService) gin.HandlerFunc {
+// Avec honorDNT, les visiteurs qui envoient DNT: 1 ou Sec-GPC: 1 sont comptés sans IP, User-Agent ni localisation.
+func RedirectHandler(linkService *services.LinkService, domainService *services.DomainService, geoResolver *geoip.Resolver, honorDNT bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Récupère le shortCode de l'URL avec c.Param
 		shortCode := c.Param("shortCode")
 
+		// Chaque domaine personnalisé a son propre espace de noms : on le déduit de l'en-tête Host
+		domainID, err := domainService.ResolveHost(c.Request.Host)
+		if err != nil {
+			log.Printf("Error resolving domain for %s: %v", c.Request.Host, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
 		// Récupérer l'URL longue associée au shortCode depuis le linkService (GetLinkByShortCode)
-		link, err := linkService.GetLinkByShortCodeWithMessage(shortCode)
+		link, err := linkService.GetLinkByShortCodeWithMessage(domainID, shortCode)
 		if err != nil {
 			// Si le lien n'est pas trouvé, retourner HTTP 404 Not Found.
 			// Utiliser errors.Is et l'erreur Gorm
@@ -97,6 +331,46 @@ func RedirectHandler(linkService *services.LinkService) gin.HandlerFunc {
 			return
 		}
 
+		// Choisir la cible selon les règles de ciblage (OS, appareil, langue, pays), puis construire l'URL de destination
+		// en transmettant le chemin et les paramètres selon les réglages du lien.
+		// Calculée avant la réservation pour ne pas consommer d'utilisation sur une requête refusée.
+		visitor := targeting.NewVisitor(c.GetHeader("User-Agent"), c.GetHeader("Accept-Language"))
+		if targeting.NeedsCountry(link.TargetingRules) {
+			// Sans base GeoIP, le pays reste inconnu et seule l'URL longue (cible par défaut) s'applique
+			visitor.Country = geoResolver.Country(c.ClientIP())
+		}
+		resolution := services.ResolveTarget(link, visitor, stickyVariantID(c, link))
+		destination, err := services.BuildDestination(link, resolution.Target, c.Param("path"), c.Request.URL.Query())
+		if err != nil {
+			if errors.Is(err, services.ErrPathPassthroughDisabled) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			log.Printf("Error building destination for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		// Un lien enregistré avant la validation du schéma peut pointer vers javascript: ou data: : il n'est jamais suivi
+		if !services.IsWebURL(destination) {
+			log.Printf("Refusing to redirect %s to a non-http(s) destination", shortCode)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+			return
+		}
+
+		// Consommer une utilisation pour les liens limités (usage unique ou N usages).
+		// Cette étape est synchrone et atomique : elle doit réussir avant toute redirection.
+		// Un lien épuisé disparaît pour les visiteurs (410) mais reste enregistré, pour ses statistiques (exhausted)
+		// et pour que son code ne soit jamais réattribué à un autre lien qui recevrait ses visites.
+		if err := linkService.ClaimLink(link); err != nil {
+			if errors.Is(err, services.ErrLinkExhausted) {
+				c.JSON(http.StatusGone, gin.H{"error": "Short URL is no longer available"})
+				return
+			}
+			log.Printf("Error claiming link %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
 		// Créer un ClickEvent avec les informations pertinentes
 		clickEvent := models.ClickEvent{
 			LinkID:    link.ID,
@@ -104,6 +378,16 @@ func RedirectHandler(linkService *services.LinkService) gin.HandlerFunc {
 			UserAgent: c.GetHeader("User-Agent"),
 			IPAddress: c.ClientIP(),
 		}
+		if resolution.Rule != nil {
+			clickEvent.RuleID = &resolution.Rule.ID
+		}
+		if resolution.Variant != nil {
+			clickEvent.VariantID = &resolution.Variant.ID
+		}
+		if honorDNT && privacy.OptedOut(c.Request.Header) {
+			clickEvent.UserAgent, clickEvent.IPAddress = "", ""
+			clickEvent.DoNotTrack = true
+		}
 
 		// Envoyer le ClickEvent dans le ClickEventsChannel avec le Multiplexage
 		// Utilise un `select` avec un `default` pour éviter de bloquer si le channel est plein
@@ -114,19 +398,32 @@ func RedirectHandler(linkService *services.LinkService) gin.HandlerFunc {
 			log.Printf("Warning: ClickEventsChannel is full, dropping click event for %s.", shortCode)
 		}
 
-		// Effectuer la redirection HTTP 302 (StatusFound) vers l'URL longue
-		c.Redirect(http.StatusFound, link.LongURL)
+		// Mémoriser la variante A/B du visiteur si le lien est "sticky"
+		rememberVariant(c, link, resolution.Variant)
+
+		// Mode page intermédiaire : on affiche un avertissement avant de quitter le site
+		if link.Interstitial {
+			renderInterstitial(c, destination)
+			return
+		}
+
+		// Effectuer la redirection HTTP avec le code configuré sur le lien (302 par défaut)
+		c.Redirect(link.EffectiveRedirectStatus(), destination)
 	}
 }
 
 // GetLinkStatsHandler gère la récupération des statistiques pour un lien spécifique.
-func GetLinkStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
+func GetLinkStatsHandler(linkService *services.LinkService, domainService *services.DomainService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Récupère le shortCode de l'URL avec c.Param
 		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
 
 		// Appeler le LinkService pour obtenir le lien et le nombre total de clics
-		link, totalClicks, err := linkService.GetLinkStats(shortCode)
+		link, totalClicks, err := linkService.GetLinkStats(domainID, shortCode)
 		if err != nil {
 			// Gérer le cas où le lien n'est pas trouvé
 			if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -139,10 +436,90 @@ func GetLinkStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		// Répartition géographique (vide si aucune base GeoIP n'est configurée)
+		geo, err := linkService.GetGeoBreakdown(link.ID)
+		if err != nil {
+			log.Printf("Error getting geo stats for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		// Clics par variante A/B (vide si le lien n'a pas de variantes)
+		variantStats, err := linkService.GetVariantStats(link)
+		if err != nil {
+			log.Printf("Error getting variant stats for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		response := withLinkSettings(gin.H{
 			"short_code":   link.ShortCode,
 			"long_url":     link.LongURL,
 			"total_clicks": totalClicks,
+			"use_count":    link.UseCount,
+			"exhausted":    link.IsExhausted(),
+			"geo":          geoBreakdownJSON(geo),
+		}, link)
+		response["variants"] = variantStatsJSON(variantStats)
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// geoBreakdownJSON prépare la répartition géographique des clics pour une réponse JSON.
+func geoBreakdownJSON(geo *services.GeoBreakdown) gin.H {
+	countries := make([]gin.H, len(geo.Countries))
+	for i, country := range geo.Countries {
+		countries[i] = gin.H{"country": country.Country, "clicks": country.Clicks}
+	}
+	locations := make([]gin.H, len(geo.Locations))
+	for i, location := range geo.Locations {
+		locations[i] = gin.H{
+			"country": location.Country,
+			"region":  location.Region,
+			"city":    location.City,
+			"clicks":  location.Count,
+		}
+	}
+	return gin.H{"countries": countries, "locations": locations}
+}
+
+// GetUTMStatsHandler gère les statistiques agrégées des liens filtrés par paramètres UTM
+// (ex: /api/v1/stats?utm_campaign=soldes_ete&utm_medium=email).
+func GetUTMStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := models.UTMParams{
+			Source:   c.Query("utm_source"),
+			Medium:   c.Query("utm_medium"),
+			Campaign: c.Query("utm_campaign"),
+			Term:     c.Query("utm_term"),
+			Content:  c.Query("utm_content"),
+		}
+		if filter.IsEmpty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one utm_* filter is required"})
+			return
+		}
+
+		results, totalClicks, err := linkService.GetUTMStats(filter)
+		if err != nil {
+			log.Printf("Error getting UTM stats: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		links := make([]gin.H, len(results))
+		for i, result := range results {
+			links[i] = gin.H{
+				"short_code":   result.Link.ShortCode,
+				"long_url":     result.Link.LongURL,
+				"total_clicks": result.Clicks,
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"filters":      filter.Values(),
+			"link_count":   len(results),
+			"total_clicks": totalClicks,
+			"links":        links,
 		})
 	}
 }
@@ -0,0 +1,409 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/armanceau/go-url-shortener/internal/migrations"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer regroupe un routeur complet sur une base SQLite temporaire.
+type testServer struct {
+	router      *gin.Engine
+	db          *gorm.DB
+	repos       repository.Repositories
+	linkService *services.LinkService
+	cfg         *config.Config
+}
+
+// testAPIToken est le jeton d'API accepté par défaut par le serveur de test.
+const testAPIToken = "test-token"
+
+// authHeader est l'en-tête à passer à testServer.do pour les routes réservées.
+var authHeader = []string{"Authorization", "Bearer " + testAPIToken}
+
+// withAPIToken remplace les jetons d'API acceptés par les routes réservées.
+func withAPIToken(tokens ...string) func(cfg *config.Config) {
+	return func(cfg *config.Config) { cfg.Auth.APITokens = tokens }
+}
+
+// newTestServer construit le serveur de test ; configure (optionnel) ajuste la configuration avant la création des routes.
+func newTestServer(t *testing.T, configure ...func(cfg *config.Config)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Server.BaseURL = "http://sho.rt"
+	cfg.Auth.APITokens = []string{testAPIToken}
+	cfg.ClickEventsChannel = make(chan models.ClickEvent, 100)
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := migrations.Up(db, false); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	repos := repository.NewGormRepositories(db)
+	generator, err := shortcode.New(cfg.ShortCode, repos.Counters)
+	if err != nil {
+		t.Fatalf("shortcode.New: %v", err)
+	}
+	linkService := services.NewLinkService(repos.Links, services.NewClickService(repos.Clicks), generator, shortcode.NewPolicy(cfg.ShortCode), canonical.New(nil))
+	domainService := services.NewDomainService(repos.Domains, cfg.Server.BaseURL)
+	exportService := services.NewExportService(repos.Links, repos.Clicks, domainService)
+	importService := services.NewImportService(linkService, domainService)
+
+	router := gin.New()
+	SetupRoutes(router, linkService, domainService, exportService, importService, services.NewQRService(linkService, domainService, 0), services.NewIdempotencyService(repos.Idempotency, time.Hour), nil, cfg)
+	return &testServer{router: router, db: db, repos: repos, linkService: linkService, cfg: cfg}
+}
+
+func (s *testServer) do(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return s.serve(req)
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeJSON décode le corps JSON d'une réponse.
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %s: %v", rec.Body, err)
+	}
+}
+
+func TestCreateLinkRejectsNonWebSchemes(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, longURL := range []string{
+		"javascript://example.com/%0Aalert(document.domain)",
+		"data://example.com/text/html,<script>alert(1)</script>",
+		"ftp://example.com/file",
+	} {
+		body := `{"long_url":"` + longURL + `","interstitial":true}`
+		if rec := s.do(http.MethodPost, "/api/v1/links", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: status = %d, want 400 (body %s)", longURL, rec.Code, rec.Body)
+		}
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/ok","interstitial":true}`); rec.Code != http.StatusCreated {
+		t.Fatalf("POST https URL: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+}
+
+func TestRedirectRefusesStoredNonWebDestination(t *testing.T) {
+	s := newTestServer(t)
+
+	// Lien enregistré avant la validation du schéma
+	link := &models.Link{
+		ShortCode:      "evil",
+		LongURL:        "javascript://example.com/%0Aalert(document.domain)",
+		RedirectStatus: models.DefaultRedirectStatus,
+		Interstitial:   true,
+	}
+	if err := s.db.Create(link).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	rec := s.do(http.MethodGet, "/evil", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /evil: status = %d, want 404", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "javascript") || strings.Contains(rec.Body.String(), "<script>") {
+		t.Fatalf("GET /evil rendered the destination: %s", rec.Body)
+	}
+}
+
+func TestRedirectInterstitial(t *testing.T) {
+	s := newTestServer(t)
+
+	link, err := s.linkService.CreateLink("https://example.com/page?a=1", services.CreateLinkOptions{Interstitial: true})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	rec := s.do(http.MethodGet, "/"+link.ShortCode, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `href="https://example.com/page?a=1"`) {
+		t.Errorf("interstitial does not link to the destination: %s", rec.Body)
+	}
+}
+
+func TestUpdateLinkRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+	link, err := s.linkService.CreateLink("https://example.com/page", services.CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	target := "/api/v1/links/" + link.ShortCode
+	body := `{"redirect_status":301,"interstitial":true}`
+
+	for _, headers := range [][]string{nil, {"Authorization", "Bearer wrong"}, {"Authorization", testAPIToken}} {
+		if rec := s.do(http.MethodPatch, target, body, headers...); rec.Code != http.StatusUnauthorized {
+			t.Errorf("PATCH with %v: status = %d, want 401", headers, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/"+link.ShortCode, ""); rec.Code != http.StatusFound {
+		t.Fatalf("GET after refused PATCH: status = %d, want 302", rec.Code)
+	}
+
+	rec := s.do(http.MethodPatch, target, `{"redirect_status":301}`, authHeader...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH: status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/"+link.ShortCode, ""); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("GET after PATCH: status = %d, want 301", rec.Code)
+	}
+	if rec := s.do(http.MethodPatch, target, `{"interstitial":true}`, authHeader...); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH interstitial: status = %d, want 200", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/"+link.ShortCode, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET with interstitial: status = %d, want 200", rec.Code)
+	}
+
+	if rec := s.do(http.MethodPatch, target, `{"redirect_status":303}`, authHeader...); rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH redirect_status 303: status = %d, want 400", rec.Code)
+	}
+	if rec := s.do(http.MethodPatch, "/api/v1/links/missing", body, authHeader...); rec.Code != http.StatusNotFound {
+		t.Errorf("PATCH unknown link: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestRedirectLimitedUseLink(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/once","max_uses":1}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, rec, &created)
+
+	if rec := s.do(http.MethodGet, "/"+created.ShortCode, ""); rec.Code != http.StatusFound {
+		t.Fatalf("first visit: status = %d, want 302", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/"+created.ShortCode, ""); rec.Code != http.StatusGone {
+		t.Fatalf("second visit: status = %d, want 410", rec.Code)
+	}
+	// Seule la visite réussie produit un clic
+	if got := len(s.cfg.ClickEventsChannel); got != 1 {
+		t.Errorf("%d click events, want 1", got)
+	}
+
+	rec = s.do(http.MethodGet, "/api/v1/links/"+created.ShortCode+"/stats", "")
+	var stats struct {
+		UseCount  int  `json:"use_count"`
+		MaxUses   int  `json:"max_uses"`
+		Exhausted bool `json:"exhausted"`
+	}
+	decodeJSON(t, rec, &stats)
+	if stats.UseCount != 1 || stats.MaxUses != 1 || !stats.Exhausted {
+		t.Errorf("stats use_count = %d, max_uses = %d, exhausted = %v, want 1, 1 and true", stats.UseCount, stats.MaxUses, stats.Exhausted)
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","max_uses":-1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("negative max_uses: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRedirectPassthrough(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/docs?lang=fr","pass_query":true,"query_conflict":"request","pass_path":true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, rec, &created)
+
+	rec = s.do(http.MethodGet, "/"+created.ShortCode+"/guide/intro?lang=en&utm_source=x", "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("GET: status = %d, want 302", rec.Code)
+	}
+	if got, want := rec.Header().Get("Location"), "https://example.com/docs/guide/intro?lang=en&utm_source=x"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	plain, err := s.linkService.CreateLink("https://example.com/plain", services.CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if rec := s.do(http.MethodGet, "/"+plain.ShortCode+"/extra", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("extra path without passthrough: status = %d, want 404", rec.Code)
+	}
+	if rec := s.do(http.MethodGet, "/"+plain.ShortCode+"?x=1", ""); rec.Header().Get("Location") != "https://example.com/plain" {
+		t.Errorf("query passed without passthrough: Location = %q", rec.Header().Get("Location"))
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","query_conflict":"merge"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown query_conflict: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestUTMParameters(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/?ref=1","utm_source":"newsletter","utm_medium":"email","utm_campaign":"spring"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode   string `json:"short_code"`
+		UTMCampaign string `json:"utm_campaign"`
+	}
+	decodeJSON(t, rec, &created)
+	if created.UTMCampaign != "spring" {
+		t.Errorf("utm_campaign = %q, want spring", created.UTMCampaign)
+	}
+
+	rec = s.do(http.MethodGet, "/"+created.ShortCode, "")
+	if got, want := rec.Header().Get("Location"), "https://example.com/?ref=1&utm_campaign=spring&utm_medium=email&utm_source=newsletter"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	s.recordClicks(t)
+
+	rec = s.do(http.MethodGet, "/api/v1/stats?utm_campaign=spring", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET stats: status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var stats struct {
+		LinkCount   int `json:"link_count"`
+		TotalClicks int `json:"total_clicks"`
+	}
+	decodeJSON(t, rec, &stats)
+	if stats.LinkCount != 1 || stats.TotalClicks != 1 {
+		t.Errorf("stats link_count = %d, total_clicks = %d, want 1 and 1", stats.LinkCount, stats.TotalClicks)
+	}
+
+	if rec := s.do(http.MethodGet, "/api/v1/stats", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("stats without filter: status = %d, want 400", rec.Code)
+	}
+	long := strings.Repeat("x", 101)
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","utm_source":"`+long+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("utm_source over 100 characters: status = %d, want 400", rec.Code)
+	}
+}
+
+// recordClicks enregistre les clics en attente dans le canal, comme le ferait le worker.
+func (s *testServer) recordClicks(t *testing.T) {
+	t.Helper()
+	for len(s.cfg.ClickEventsChannel) > 0 {
+		event := <-s.cfg.ClickEventsChannel
+		click := &models.Click{LinkID: event.LinkID, Timestamp: event.Timestamp, UserAgent: event.UserAgent, IPAddress: event.IPAddress, RuleID: event.RuleID, VariantID: event.VariantID}
+		if err := s.db.Create(click).Error; err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+}
+
+func TestLinkStatsGeoBreakdown(t *testing.T) {
+	s := newTestServer(t)
+
+	link, err := s.linkService.CreateLink("https://example.com", services.CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	for _, country := range []string{"FR", "FR", "BE"} {
+		if err := s.db.Create(&models.Click{LinkID: link.ID, Timestamp: time.Now(), Country: country}).Error; err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+
+	var stats struct {
+		TotalClicks int `json:"total_clicks"`
+		Geo         struct {
+			Countries []struct {
+				Country string `json:"country"`
+				Clicks  int    `json:"clicks"`
+			} `json:"countries"`
+		} `json:"geo"`
+	}
+	decodeJSON(t, s.do(http.MethodGet, "/api/v1/links/"+link.ShortCode+"/stats", ""), &stats)
+	countries := stats.Geo.Countries
+	if stats.TotalClicks != 3 || len(countries) != 2 || countries[0].Country != "FR" || countries[0].Clicks != 2 {
+		t.Errorf("stats = %+v, want 3 clicks with FR first", stats)
+	}
+}
+
+func TestCreateLinkDedupe(t *testing.T) {
+	s := newTestServer(t)
+
+	type createdLink struct {
+		ShortCode    string `json:"short_code"`
+		Deduplicated bool   `json:"deduplicated"`
+	}
+	create := func(body string, wantStatus int) createdLink {
+		t.Helper()
+		rec := s.do(http.MethodPost, "/api/v1/links", body)
+		if rec.Code != wantStatus {
+			t.Fatalf("POST %s: status = %d, want %d (body %s)", body, rec.Code, wantStatus, rec.Body)
+		}
+		var link createdLink
+		decodeJSON(t, rec, &link)
+		return link
+	}
+
+	first := create(`{"long_url":"https://example.com/page/","owner":"alice","dedupe":true}`, http.StatusCreated)
+	again := create(`{"long_url":"HTTPS://example.com:443/page","owner":"alice","dedupe":true}`, http.StatusOK)
+	if again.ShortCode != first.ShortCode || !again.Deduplicated || first.Deduplicated {
+		t.Errorf("dedupe returned %+v for %+v, want the same code flagged as deduplicated", again, first)
+	}
+	if other := create(`{"long_url":"https://example.com/page","owner":"bob","dedupe":true}`, http.StatusCreated); other.ShortCode == first.ShortCode {
+		t.Error("dedupe reused another owner's link")
+	}
+	if plain := create(`{"long_url":"https://example.com/page","owner":"alice"}`, http.StatusCreated); plain.ShortCode == first.ShortCode {
+		t.Error("link deduplicated without the dedupe flag")
+	}
+
+	// La configuration peut activer la déduplication par défaut, la requête gardant le dernier mot
+	s.cfg.Dedupe.Default = true
+	if link := create(`{"long_url":"https://example.com/page","owner":"alice"}`, http.StatusOK); link.ShortCode != first.ShortCode {
+		t.Errorf("default dedupe returned %q, want %q", link.ShortCode, first.ShortCode)
+	}
+	create(`{"long_url":"https://example.com/page","owner":"alice","dedupe":false}`, http.StatusCreated)
+
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"javascript://example.com/%0Aalert(1)","dedupe":true}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("non-web URL with dedupe: status = %d, want 400", rec.Code)
+	}
+}
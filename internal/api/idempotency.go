@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength est la longueur maximale d'une clé d'idempotence (taille de la colonne).
+const maxIdempotencyKeyLength = 255
+
+// Idempotent rend une route de création rejouable sans effet de bord : si la requête porte un en-tête
+// Idempotency-Key déjà vu pour cette route, la réponse d'origine est renvoyée (avec l'en-tête Idempotent-Replayed)
+// au lieu de traiter la requête à nouveau. La même clé avec un corps différent est refusée (422),
+// une clé dont la requête d'origine est en cours aussi (409). Les erreurs du serveur (5xx) ne sont pas enregistrées :
+// la clé est libérée pour que le client puisse réessayer. Sans en-tête, ou si idempotencyService est nil, la route est inchangée.
+func Idempotent(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || idempotencyService == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must not exceed 255 characters"})
+			return
+		}
+
+		// Le corps est lu pour son empreinte puis rendu au handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unable to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.FullPath()
+		stored, err := idempotencyService.Begin(scope, key, body, time.Now())
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			log.Printf("Error checking idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		case stored != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, "application/json; charset=utf-8", stored.Response)
+			c.Abort()
+			return
+		}
+
+		// Si le handler panique, la clé est libérée avant que la panique ne remonte au middleware Recovery
+		defer func() {
+			if r := recover(); r != nil {
+				if err := idempotencyService.Release(scope, key); err != nil {
+					log.Printf("Error releasing idempotency key: %v", err)
+				}
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			err = idempotencyService.Release(scope, key)
+		} else {
+			err = idempotencyService.Complete(scope, key, status, recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Error saving idempotency key: %v", err)
+		}
+	}
+}
+
+// responseRecorder conserve une copie du corps de la réponse écrite par le handler.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestIdempotentLinkCreation(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"long_url":"https://example.com/once"}`
+
+	first := s.do(http.MethodPost, "/api/v1/links", body, "Idempotency-Key", "retry-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first POST: status = %d, want 201 (body %s)", first.Code, first.Body)
+	}
+	replay := s.do(http.MethodPost, "/api/v1/links", body, "Idempotency-Key", "retry-1")
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: status = %d, body %s, replayed %q, want the original response", replay.Code, replay.Body, replay.Header().Get("Idempotent-Replayed"))
+	}
+	if links, _ := s.repos.Links.GetAllLinks(); len(links) != 1 {
+		t.Errorf("%d links stored, want 1", len(links))
+	}
+
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/other"}`, "Idempotency-Key", "retry-1"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, other body: status = %d, want 422", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/links", body, "Idempotency-Key", strings.Repeat("k", 256)); rec.Code != http.StatusBadRequest {
+		t.Errorf("key too long: status = %d, want 400", rec.Code)
+	}
+
+	// Sans en-tête, chaque requête crée un lien
+	s.do(http.MethodPost, "/api/v1/links", body)
+	if links, _ := s.repos.Links.GetAllLinks(); len(links) != 2 {
+		t.Errorf("%d links stored, want 2", len(links))
+	}
+
+	// Les réponses en erreur client sont aussi rejouées
+	invalid := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"ftp://example.com"}`, "Idempotency-Key", "invalid")
+	if replay := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"ftp://example.com"}`, "Idempotency-Key", "invalid"); replay.Code != invalid.Code || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replayed 400: status = %d, replayed %q", replay.Code, replay.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestIdempotentBatch(t *testing.T) {
+	s := newTestServer(t)
+	body := `[{"long_url":"https://example.com/a"},{"long_url":"https://example.com/b"}]`
+
+	first := s.do(http.MethodPost, "/api/v1/links/batch", body, "Idempotency-Key", "batch-1")
+	replay := s.do(http.MethodPost, "/api/v1/links/batch", body, "Idempotency-Key", "batch-1")
+	if first.Code != http.StatusOK || replay.Body.String() != first.Body.String() {
+		t.Errorf("batch replay = %d %s, want %d %s", replay.Code, replay.Body, first.Code, first.Body)
+	}
+	if links, _ := s.repos.Links.GetAllLinks(); len(links) != 2 {
+		t.Errorf("%d links stored, want 2", len(links))
+	}
+	// Une même clé peut servir sur une autre route
+	if rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/c"}`, "Idempotency-Key", "batch-1"); rec.Code != http.StatusCreated {
+		t.Errorf("same key on another route: status = %d, want 201", rec.Code)
+	}
+}
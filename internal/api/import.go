@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/armanceau/go-url-shortener/internal/importer"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize est la taille maximale du fichier envoyé à l'import (32 Mio).
+const maxImportSize = 32 << 20
+
+// ImportLinksHandler gère POST /api/v1/links/import?format=&domain=&owner=&atomic=&dry_run=.
+// Le fichier est le corps de la requête, ou le champ "file" d'un formulaire multipart.
+// Le format est déduit du paramètre format, sinon du type MIME ou du nom du fichier envoyé.
+// La réponse est le rapport ligne par ligne : 200 si l'import a eu lieu (même partiellement),
+// 422 si rien n'a été enregistré parce que le mode atomique a rencontré une ligne invalide.
+func ImportLinksHandler(importService *services.ImportService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+		var body io.Reader = c.Request.Body
+		format := importer.DetectFormat(c.ContentType())
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			header, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'file' form field"})
+				return
+			}
+			file, err := header.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read uploaded file"})
+				return
+			}
+			defer file.Close()
+			body, format = file, importer.DetectFormat(header.Filename)
+		}
+		if c.Query("format") != "" {
+			format = c.Query("format")
+		}
+		if !importer.IsValidFormat(format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": importer.ErrInvalidFormat.Error()})
+			return
+		}
+
+		opts := services.ImportOptions{Domain: c.Query("domain"), Owner: c.Query("owner")}
+		for name, target := range map[string]*bool{"atomic": &opts.Atomic, "dry_run": &opts.DryRun} {
+			if value := c.Query(name); value != "" {
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a boolean"})
+					return
+				}
+				*target = parsed
+			}
+		}
+
+		rows, err := importer.Read(format, body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report, err := importService.Import(rows, opts)
+		if err != nil {
+			if errors.Is(err, services.ErrUnknownDomain) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error importing links: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		results := make([]gin.H, len(report.Results))
+		for i, result := range report.Results {
+			item := gin.H{"line": result.Line, "short_code": result.ShortCode, "long_url": result.LongURL}
+			switch {
+			case result.Err != nil:
+				item["status"], item["error"] = "error", result.Err.Error()
+			case result.Link != nil:
+				item["status"] = "imported"
+				if fullShortURL, err := domainService.FullShortURL(result.Link); err == nil {
+					item["full_short_url"] = fullShortURL
+				}
+			default:
+				item["status"] = "valid"
+			}
+			results[i] = item
+		}
+
+		status := http.StatusOK
+		if opts.Atomic && report.Failed > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"dry_run":  opts.DryRun,
+			"atomic":   opts.Atomic,
+			"total":    len(report.Results),
+			"valid":    report.Valid,
+			"imported": report.Imported,
+			"failed":   report.Failed,
+			"results":  results,
+		})
+	}
+}
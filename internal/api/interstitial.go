@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// interstitialDelaySeconds est le délai (en secondes) avant la redirection automatique depuis la page intermédiaire.
+const interstitialDelaySeconds = 5
+
+// interstitialTemplate est la page "Vous allez quitter..." affichée pour les destinations non fiables.
+// html/template échappe automatiquement l'URL de destination dans le HTML, l'attribut et le script.
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="fr">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex, nofollow">
+<meta http-equiv="refresh" content="{{.Delay}};url={{.URL}}">
+<title>Redirection en cours…</title>
+</head>
+<body>
+<main>
+<h1>Vous allez quitter ce site</h1>
+<p>Vous êtes sur le point d'être redirigé vers :</p>
+<p><a href="{{.URL}}" rel="noopener noreferrer nofollow">{{.URL}}</a></p>
+<p>Redirection automatique dans <span id="countdown">{{.Delay}}</span> seconde(s).</p>
+</main>
+<script>
+(function () {
+  var remaining = {{.Delay}};
+  var target = {{.URL}};
+  var el = document.getElementById("countdown");
+  var timer = setInterval(function () {
+    remaining--;
+    if (remaining <= 0) {
+      clearInterval(timer);
+      window.location.replace(target);
+      return;
+    }
+    el.textContent = remaining;
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+// renderInterstitial écrit la page intermédiaire pointant vers destination.
+func renderInterstitial(c *gin.Context, destination string) {
+	var buf bytes.Buffer
+	data := struct {
+		URL   string
+		Delay int
+	}{URL: destination, Delay: interstitialDelaySeconds}
+
+	if err := interstitialTemplate.Execute(&buf, data); err != nil {
+		log.Printf("Error rendering interstitial page for %s: %v", destination, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// La page ne doit pas être mise en cache : la destination ou le mode peuvent changer.
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image/color"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/armanceau/go-url-shortener/internal/qr"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetLinkQRCodeHandler gère GET /api/v1/links/:shortCode/qr?format=&size=&margin=&ecc=&fg=&bg=.
+// L'image encode l'URL courte complète du lien (full_short_url). Par défaut : PNG de 256 pixels,
+// marge de 4 modules, correction M, noir sur blanc ; fg et bg sont des couleurs #rgb ou #rrggbb (# encodé en %23 ou omis).
+// L'image porte un ETag et peut être mise en cache par le client.
+func GetLinkQRCodeHandler(qrService *services.QRService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
+
+		opts, err := qrOptionsFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		_, image, err := qrService.LinkQRCode(domainID, shortCode, opts)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			if errors.Is(err, qr.ErrSizeTooSmall) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error rendering QR code for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		sum := sha256.Sum256(image)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", "public, max-age=86400")
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Data(http.StatusOK, qr.ContentType(opts.Format), image)
+	}
+}
+
+// qrOptionsFromQuery lit et valide les options de rendu de la requête, les absentes gardant leur valeur par défaut.
+func qrOptionsFromQuery(c *gin.Context) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+	if format := c.Query("format"); format != "" {
+		opts.Format = format
+	}
+	if ecc := c.Query("ecc"); ecc != "" {
+		opts.ECC = ecc
+	}
+	for name, target := range map[string]*int{"size": &opts.Size, "margin": &opts.Margin} {
+		if value := c.Query(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return opts, errors.New(name + " must be an integer")
+			}
+			*target = parsed
+		}
+	}
+	for name, target := range map[string]*color.NRGBA{"fg": &opts.Foreground, "bg": &opts.Background} {
+		if value := c.Query(name); value != "" {
+			parsed, err := qr.ParseColor(value)
+			if err != nil {
+				return opts, errors.New(name + ": " + err.Error())
+			}
+			*target = parsed
+		}
+	}
+	return opts, opts.Validate()
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLinkQRCode(t *testing.T) {
+	s := newTestServer(t)
+	created := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com/qr"}`)
+	var link struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, created, &link)
+	target := "/api/v1/links/" + link.ShortCode + "/qr"
+
+	rec := s.do(http.MethodGet, target, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("GET qr: status = %d, content type %q, want a PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	img, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 256 {
+		t.Errorf("default image is %d pixels wide, want 256", bounds.Dx())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || rec.Header().Get("Cache-Control") == "" {
+		t.Errorf("ETag = %q, Cache-Control = %q, want both set", etag, rec.Header().Get("Cache-Control"))
+	}
+
+	if rec := s.do(http.MethodGet, target, "", "If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status = %d, want 304 without a body", rec.Code)
+	}
+
+	rec = s.do(http.MethodGet, target+"?size=64&margin=0&fg=%23f00", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("GET qr with options: status = %d, want another image", rec.Code)
+	}
+	img, err = png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	// Les modules sombres prennent la couleur fg, le reste garde le fond blanc par défaut
+	colors := make(map[color.NRGBA]bool)
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			colors[color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)] = true
+		}
+	}
+	red, white := color.NRGBA{R: 0xff, A: 0xff}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if len(colors) != 2 || !colors[red] || !colors[white] {
+		t.Errorf("image colours = %v, want red on white", colors)
+	}
+
+	rec = s.do(http.MethodGet, target+"?format=svg&bg=ffeedd", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" || !strings.Contains(rec.Body.String(), `fill="#ffeedd"`) {
+		t.Errorf("GET qr in SVG: status = %d, content type %q, body %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	for _, query := range []string{"format=gif", "size=10", "size=big", "margin=-1", "ecc=X", "fg=red", "size=32&margin=32"} {
+		if rec := s.do(http.MethodGet, target+"?"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET qr?%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/links/missing/qr", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET qr of an unknown link: status = %d, want 404", rec.Code)
+	}
+}
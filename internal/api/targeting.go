@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TargetingRuleRequest représente une règle de ciblage dans les requêtes JSON.
+// Les conditions vides correspondent à tous les visiteurs ; au moins une condition est requise.
+type TargetingRuleRequest struct {
+	OS        string   `json:"os"`        // ios, android, windows, macos, linux
+	Device    string   `json:"device"`    // mobile, tablet, desktop, bot
+	Language  string   `json:"language"`  // ex: fr ou fr-CA
+	Countries []string `json:"countries"` // Codes pays ISO résolus par GeoIP, ex: ["FR", "BE"]
+	TargetURL string   `json:"target_url" binding:"required,url"`
+}
+
+// SetTargetingRulesRequest représente le corps de la requête de remplacement des règles d'un lien.
+type SetTargetingRulesRequest struct {
+	Rules []TargetingRuleRequest `json:"rules" binding:"dive"`
+}
+
+// toTargetingRules convertit les règles reçues en modèles, dans l'ordre de la requête.
+func toTargetingRules(requests []TargetingRuleRequest) []models.TargetingRule {
+	rules := make([]models.TargetingRule, len(requests))
+	for i, req := range requests {
+		rules[i] = models.TargetingRule{
+			OS:        req.OS,
+			Device:    req.Device,
+			Language:  req.Language,
+			Countries: strings.Join(req.Countries, ","),
+			TargetURL: req.TargetURL,
+		}
+	}
+	return rules
+}
+
+// targetingRulesJSON prépare les règles d'un lien pour une réponse JSON.
+func targetingRulesJSON(rules []models.TargetingRule) []gin.H {
+	result := make([]gin.H, len(rules))
+	for i, rule := range rules {
+		countries := []string{}
+		if rule.Countries != "" {
+			countries = strings.Split(rule.Countries, ",")
+		}
+		result[i] = gin.H{
+			"id":         rule.ID,
+			"position":   rule.Position,
+			"os":         rule.OS,
+			"device":     rule.Device,
+			"language":   rule.Language,
+			"countries":  countries,
+			"target_url": rule.TargetURL,
+		}
+	}
+	return result
+}
+
+// GetTargetingRulesHandler gère la récupération des règles de ciblage d'un lien.
+func GetTargetingRulesHandler(linkService *services.LinkService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
+
+		link, err := linkService.GetLinkByShortCodeWithMessage(domainID, shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			log.Printf("Error retrieving link for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"short_code": link.ShortCode,
+			"rules":      targetingRulesJSON(link.TargetingRules),
+		})
+	}
+}
+
+// SetTargetingRulesHandler gère le remplacement de l'ensemble des règles de ciblage d'un lien.
+func SetTargetingRulesHandler(linkService *services.LinkService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
+
+		var req SetTargetingRulesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		link, err := linkService.SetTargetingRules(domainID, shortCode, toTargetingRules(req.Rules))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			if errors.Is(err, targeting.ErrInvalidRule) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error setting targeting rules for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"short_code": link.ShortCode,
+			"rules":      targetingRulesJSON(link.TargetingRules),
+		})
+	}
+}
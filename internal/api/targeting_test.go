@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+const (
+	iPhoneUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0"
+)
+
+func TestTargetedRedirect(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","targeting_rules":[
+		{"os":"ios","target_url":"https://apps.apple.com/app"},
+		{"os":"android","target_url":"https://play.google.com/app"},
+		{"language":"fr","target_url":"https://example.com/fr"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, rec, &created)
+
+	tests := []struct {
+		userAgent, language string
+		want                string
+		rule                bool
+	}{
+		{iPhoneUserAgent, "fr-FR", "https://apps.apple.com/app", true},
+		{androidUserAgent, "", "https://play.google.com/app", true},
+		{desktopUserAgent, "fr-CA,en;q=0.5", "https://example.com/fr", true},
+		{desktopUserAgent, "en-US", "https://example.com", false},
+	}
+	for _, tt := range tests {
+		rec := s.do(http.MethodGet, "/"+created.ShortCode, "", "User-Agent", tt.userAgent, "Accept-Language", tt.language)
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s / %q: Location = %q, want %q", tt.userAgent, tt.language, got, tt.want)
+		}
+		// La règle appliquée est enregistrée sur le clic
+		event := <-s.cfg.ClickEventsChannel
+		if (event.RuleID != nil) != tt.rule {
+			t.Errorf("%s / %q: click RuleID = %v, want a rule: %v", tt.userAgent, tt.language, event.RuleID, tt.rule)
+		}
+	}
+}
+
+func TestSetTargetingRules(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com"}`)
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, rec, &created)
+	rulesURL := "/api/v1/links/" + created.ShortCode + "/rules"
+
+	// Sans jeton d'API, les règles ne peuvent pas détourner le trafic du lien
+	if rec := s.do(http.MethodPut, rulesURL, `{"rules":[{"os":"ios","target_url":"https://attacker.test"}]}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("PUT without token: status = %d, want 401", rec.Code)
+	}
+
+	rec = s.do(http.MethodPut, rulesURL, `{"rules":[{"device":"Tablet","countries":["fr","be"],"target_url":"https://example.com/tablet"}]}`, authHeader...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var got struct {
+		Rules []struct {
+			Device    string   `json:"device"`
+			Countries []string `json:"countries"`
+		} `json:"rules"`
+	}
+	decodeJSON(t, s.do(http.MethodGet, rulesURL, ""), &got)
+	if len(got.Rules) != 1 || got.Rules[0].Device != "tablet" || len(got.Rules[0].Countries) != 2 || got.Rules[0].Countries[1] != "BE" {
+		t.Errorf("GET rules = %+v, want one normalized tablet rule for FR and BE", got.Rules)
+	}
+
+	for _, body := range []string{
+		`{"rules":[{"os":"beos","target_url":"https://example.com"}]}`,
+		`{"rules":[{"target_url":"https://example.com"}]}`,
+		`{"rules":[{"os":"ios"}]}`,
+	} {
+		if rec := s.do(http.MethodPut, rulesURL, body, authHeader...); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodPut, "/api/v1/links/missing/rules", `{"rules":[]}`, authHeader...); rec.Code != http.StatusNotFound {
+		t.Errorf("PUT on unknown link: status = %d, want 404", rec.Code)
+	}
+
+	if rec := s.do(http.MethodPut, rulesURL, `{"rules":[]}`, authHeader...); rec.Code != http.StatusOK {
+		t.Fatalf("PUT no rules: status = %d, want 200", rec.Code)
+	}
+	decodeJSON(t, s.do(http.MethodGet, rulesURL, ""), &got)
+	if len(got.Rules) != 0 {
+		t.Errorf("rules after clearing = %+v, want none", got.Rules)
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// variantCookieMaxAge est la durée de vie (en secondes) du cookie mémorisant la variante d'un visiteur.
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
+// VariantRequest représente une variante A/B dans les requêtes JSON.
+type VariantRequest struct {
+	Name      string `json:"name" binding:"max=50"`
+	TargetURL string `json:"target_url" binding:"required,url"`
+	Weight    int    `json:"weight" binding:"min=0"`
+}
+
+// SetVariantsRequest représente le corps de la requête de remplacement des variantes d'un lien.
+type SetVariantsRequest struct {
+	Variants []VariantRequest `json:"variants" binding:"dive"`
+}
+
+// toVariants convertit les variantes reçues en modèles.
+func toVariants(requests []VariantRequest) []models.LinkVariant {
+	variants := make([]models.LinkVariant, len(requests))
+	for i, req := range requests {
+		variants[i] = models.LinkVariant{
+			Name:      req.Name,
+			TargetURL: req.TargetURL,
+			Weight:    req.Weight,
+		}
+	}
+	return variants
+}
+
+// variantsJSON prépare les variantes d'un lien pour une réponse JSON.
+func variantsJSON(variants []models.LinkVariant) []gin.H {
+	result := make([]gin.H, len(variants))
+	for i, variant := range variants {
+		result[i] = gin.H{
+			"id":         variant.ID,
+			"name":       variant.Name,
+			"target_url": variant.TargetURL,
+			"weight":     variant.Weight,
+		}
+	}
+	return result
+}
+
+// variantStatsJSON prépare les clics par variante pour une réponse JSON.
+func variantStatsJSON(stats []services.VariantClicks) []gin.H {
+	result := make([]gin.H, len(stats))
+	for i, stat := range stats {
+		result[i] = gin.H{
+			"id":         stat.Variant.ID,
+			"name":       stat.Variant.Name,
+			"target_url": stat.Variant.TargetURL,
+			"weight":     stat.Variant.Weight,
+			"clicks":     stat.Clicks,
+		}
+	}
+	return result
+}
+
+// variantCookieName renvoie le nom du cookie mémorisant la variante d'un visiteur pour un lien.
+func variantCookieName(link *models.Link) string {
+	return fmt.Sprintf("us_variant_%d", link.ID)
+}
+
+// stickyVariantID renvoie la variante mémorisée dans le cookie du visiteur (0 si absente ou si le lien n'est pas "sticky").
+func stickyVariantID(c *gin.Context, link *models.Link) uint {
+	if !link.StickyVariant || len(link.Variants) == 0 {
+		return 0
+	}
+	value, err := c.Cookie(variantCookieName(link))
+	if err != nil {
+		return 0
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// rememberVariant mémorise la variante choisie dans un cookie pour les prochaines visites.
+func rememberVariant(c *gin.Context, link *models.Link, variant *models.LinkVariant) {
+	if !link.StickyVariant || variant == nil {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookieName(link), strconv.FormatUint(uint64(variant.ID), 10), variantCookieMaxAge, "/", "", false, true)
+}
+
+// SetVariantsHandler gère le remplacement de l'ensemble des variantes A/B d'un lien.
+func SetVariantsHandler(linkService *services.LinkService, domainService *services.DomainService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		domainID, ok := requestDomainID(c, domainService)
+		if !ok {
+			return
+		}
+
+		var req SetVariantsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		link, err := linkService.SetVariants(domainID, shortCode, toVariants(req.Variants))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+				return
+			}
+			if errors.Is(err, services.ErrInvalidVariant) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error setting variants for %s: %v", shortCode, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":     link.ShortCode,
+			"sticky_variant": link.StickyVariant,
+			"variants":       variantsJSON(link.Variants),
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStickyVariants(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com","sticky_variant":true,"variants":[
+		{"name":"a","target_url":"https://example.com/a","weight":1},
+		{"name":"b","target_url":"https://example.com/b","weight":1}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var created struct {
+		ShortCode string `json:"short_code"`
+		Variants  []struct {
+			ID        uint   `json:"id"`
+			TargetURL string `json:"target_url"`
+		} `json:"variants"`
+	}
+	decodeJSON(t, rec, &created)
+	if len(created.Variants) != 2 {
+		t.Fatalf("created variants = %+v, want 2", created.Variants)
+	}
+
+	rec = s.do(http.MethodGet, "/"+created.ShortCode, "")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !strings.HasPrefix(cookies[0].Name, "us_variant_") || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v, want one HttpOnly variant cookie", cookies)
+	}
+	first := rec.Header().Get("Location")
+	event := <-s.cfg.ClickEventsChannel
+	if event.VariantID == nil {
+		t.Fatal("click has no variant")
+	}
+
+	// Avec le cookie, le visiteur retrouve toujours la même variante
+	cookie := cookies[0].Name + "=" + cookies[0].Value
+	for i := 0; i < 20; i++ {
+		rec := s.do(http.MethodGet, "/"+created.ShortCode, "", "Cookie", cookie)
+		if got := rec.Header().Get("Location"); got != first {
+			t.Fatalf("visit %d with cookie went to %q, want %q", i, got, first)
+		}
+	}
+	s.recordClicks(t)
+
+	var stats struct {
+		Variants []struct {
+			TargetURL string `json:"target_url"`
+			Clicks    int    `json:"clicks"`
+		} `json:"variants"`
+	}
+	decodeJSON(t, s.do(http.MethodGet, "/api/v1/links/"+created.ShortCode+"/stats", ""), &stats)
+	total := 0
+	for _, variant := range stats.Variants {
+		if variant.TargetURL == first {
+			total += variant.Clicks
+		}
+	}
+	if len(stats.Variants) != 2 || total != 20 {
+		t.Errorf("variant stats = %+v, want 20 clicks on %s", stats.Variants, first)
+	}
+}
+
+func TestSetVariants(t *testing.T) {
+	s := newTestServer(t)
+
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeJSON(t, s.do(http.MethodPost, "/api/v1/links", `{"long_url":"https://example.com"}`), &created)
+	variantsURL := "/api/v1/links/" + created.ShortCode + "/variants"
+
+	if rec := s.do(http.MethodPut, variantsURL, `{"variants":[{"target_url":"https://attacker.test","weight":1}]}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("PUT without token: status = %d, want 401", rec.Code)
+	}
+
+	rec := s.do(http.MethodPut, variantsURL, `{"variants":[{"target_url":"https://example.com/only","weight":1}]}`, authHeader...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodGet, "/"+created.ShortCode, ""); rec.Header().Get("Location") != "https://example.com/only" {
+		t.Errorf("Location = %q, want the only variant", rec.Header().Get("Location"))
+	}
+	// Un lien sans "sticky_variant" ne dépose pas de cookie
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("cookies = %+v, want none", cookies)
+	}
+
+	for _, body := range []string{
+		`{"variants":[{"target_url":"https://example.com/a","weight":0}]}`,
+		`{"variants":[{"target_url":"https://example.com/a","weight":-1}]}`,
+		`{"variants":[{"weight":1}]}`,
+	} {
+		if rec := s.do(http.MethodPut, variantsURL, body, authHeader...); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := s.do(http.MethodPut, "/api/v1/links/missing/variants", `{"variants":[]}`, authHeader...); rec.Code != http.StatusNotFound {
+		t.Errorf("PUT on unknown link: status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,46 @@
+// Package cache fournit un cache Redis partagé entre les instances du serveur :
+// recherche des liens par code court et compteurs de clics atomiques par lien.
+// Il s'applique en décorant les repositories, sans modifier les services.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// DriverRedis est la valeur de cache.driver qui active le cache Redis.
+const DriverRedis = "redis"
+
+// Decorate enveloppe les repositories de liens et de clics avec le cache configuré (section cache).
+// Sans cache, les repositories sont renvoyés tels quels. La fonction renvoyée ferme la connexion Redis.
+func Decorate(cfg *config.Config, repos repository.Repositories) (repository.Repositories, func() error, error) {
+	switch cfg.Cache.Driver {
+	case "":
+		return repos, func() error { return nil }, nil
+	case DriverRedis:
+	default:
+		return repos, nil, fmt.Errorf("unsupported cache driver %q (expected %s or empty)", cfg.Cache.Driver, DriverRedis)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Cache.Redis.Addr,
+		Password: cfg.Cache.Redis.Password,
+		DB:       cfg.Cache.Redis.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return repos, nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.Cache.Redis.Addr, err)
+	}
+
+	ttl := time.Duration(cfg.Cache.LinkTTL) * time.Second
+	repos.Links = NewLinkCache(repos.Links, client, cfg.Cache.KeyPrefix, ttl)
+	repos.Clicks = NewClickCounter(repos.Clicks, client, cfg.Cache.KeyPrefix)
+	return repos, client.Close, nil
+}
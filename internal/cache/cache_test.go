@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+func TestDecorate(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+
+	cfg := &config.Config{}
+	decorated, closeFn, err := Decorate(cfg, repos)
+	if err != nil || decorated.Links != repos.Links || decorated.Clicks != repos.Clicks {
+		t.Fatalf("Decorate without driver = %+v, %v, want the repositories unchanged", decorated, err)
+	}
+	closeFn()
+
+	cfg.Cache.Driver = "memcached"
+	if _, _, err := Decorate(cfg, repos); err == nil {
+		t.Error("Decorate accepted an unsupported driver")
+	}
+
+	server := miniredis.RunT(t)
+	cfg.Cache.Driver = DriverRedis
+	cfg.Cache.Redis.Addr = server.Addr()
+	decorated, closeFn, err = Decorate(cfg, repos)
+	if err != nil {
+		t.Fatalf("Decorate: %v", err)
+	}
+	defer closeFn()
+	if _, ok := decorated.Links.(*LinkCache); !ok {
+		t.Errorf("Links = %T, want *LinkCache", decorated.Links)
+	}
+	if _, ok := decorated.Clicks.(*ClickCounter); !ok {
+		t.Errorf("Clicks = %T, want *ClickCounter", decorated.Clicks)
+	}
+	if decorated.Domains != repos.Domains {
+		t.Error("Decorate replaced a repository it does not cache")
+	}
+
+	server.Close()
+	if _, _, err := Decorate(cfg, repos); err == nil {
+		t.Error("Decorate succeeded without a reachable Redis server")
+	}
+}
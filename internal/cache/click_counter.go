@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// incrIfExists incrémente un compteur seulement s'il a déjà été initialisé depuis la base :
+// un INCR sur une clé absente partirait de zéro et ignorerait les clics déjà enregistrés.
+var incrIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("INCR", KEYS[1])
+end
+return 0`)
+
+// ClickCounter est un ClickRepository qui tient dans Redis un compteur atomique de clics par lien,
+// pour que les statistiques n'aient pas à compter la table des clics.
+// Un compteur absent est initialisé par un COUNT en base à sa première lecture, puis incrémenté à chaque clic enregistré.
+// Un clic enregistré entre ce COUNT et l'initialisation peut être manqué ; supprimer la clé force un recomptage.
+type ClickCounter struct {
+	repository.ClickRepository
+	client *redis.Client
+	prefix string
+}
+
+// NewClickCounter crée des compteurs de clics Redis devant clickRepo.
+func NewClickCounter(clickRepo repository.ClickRepository, client *redis.Client, prefix string) *ClickCounter {
+	return &ClickCounter{ClickRepository: clickRepo, client: client, prefix: prefix}
+}
+
+// counterKey renvoie la clé du compteur de clics d'un lien.
+func (c *ClickCounter) counterKey(linkID uint) string {
+	return fmt.Sprintf("%sclicks:%d", c.prefix, linkID)
+}
+
+// CreateClick enregistre le clic puis incrémente le compteur du lien.
+// Si l'incrément échoue, le compteur est supprimé pour être recompté depuis la base.
+func (c *ClickCounter) CreateClick(click *models.Click) error {
+	if err := c.ClickRepository.CreateClick(click); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	key := c.counterKey(click.LinkID)
+	if err := incrIfExists.Run(ctx, c.client, []string{key}).Err(); err != nil {
+		log.Printf("Attention: impossible d'incrémenter le compteur de clics du lien %d: %v", click.LinkID, err)
+		c.client.Del(ctx, key)
+	}
+	return nil
+}
+
+// CountClicksByLinkID renvoie le compteur du lien, initialisé depuis la base s'il est absent.
+func (c *ClickCounter) CountClicksByLinkID(linkID uint) (int, error) {
+	counts, err := c.CountClicksByLinkIDs([]uint{linkID})
+	if err != nil {
+		return 0, err
+	}
+	return counts[linkID], nil
+}
+
+// CountClicksByLinkIDs lit les compteurs de plusieurs liens en une requête et ne compte en base que les absents.
+// En cas d'erreur Redis, les clics sont comptés en base.
+func (c *ClickCounter) CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error) {
+	if len(linkIDs) == 0 {
+		return map[uint]int{}, nil
+	}
+	ctx := context.Background()
+	keys := make([]string, len(linkIDs))
+	for i, id := range linkIDs {
+		keys[i] = c.counterKey(id)
+	}
+
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("Attention: cache Redis indisponible, comptage des clics en base: %v", err)
+		return c.ClickRepository.CountClicksByLinkIDs(linkIDs)
+	}
+
+	counts := make(map[uint]int, len(linkIDs))
+	var missing []uint
+	for i, value := range values {
+		var count int
+		if s, ok := value.(string); ok {
+			if _, err := fmt.Sscan(s, &count); err == nil {
+				counts[linkIDs[i]] = count
+				continue
+			}
+		}
+		missing = append(missing, linkIDs[i])
+	}
+	if len(missing) == 0 {
+		return counts, nil
+	}
+
+	missingCounts, err := c.ClickRepository.CountClicksByLinkIDs(missing)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range missing {
+			counts[id] = missingCounts[id]
+			pipe.SetNX(ctx, c.counterKey(id), missingCounts[id], 0)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Attention: impossible d'initialiser les compteurs de clics: %v", err)
+	}
+	return counts, nil
+}
+
+// DeleteClicksByIPAddresses supprime les clics puis les compteurs des liens concernés,
+// qui seront recomptés depuis la base à leur prochaine lecture : sans cela, ils compteraient encore les clics effacés.
+func (c *ClickCounter) DeleteClicksByIPAddresses(addresses []string) (int64, error) {
+	linkIDs, err := c.ClickRepository.FindLinkIDsByIPAddresses(addresses)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := c.ClickRepository.DeleteClicksByIPAddresses(addresses)
+	if err != nil || len(linkIDs) == 0 {
+		return deleted, err
+	}
+
+	keys := make([]string, len(linkIDs))
+	for i, id := range linkIDs {
+		keys[i] = c.counterKey(id)
+	}
+	if err := c.client.Del(context.Background(), keys...).Err(); err != nil {
+		return deleted, fmt.Errorf("clicks deleted but failed to reset %d click counter(s): %w", len(keys), err)
+	}
+	return deleted, nil
+}
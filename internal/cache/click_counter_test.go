@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis démarre un serveur Redis en mémoire et renvoie un client connecté.
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	// Sans nouvelle tentative, pour que les tests de repli sur la base ne patientent pas
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// newTestLink enregistre un lien dans les repositories en mémoire.
+func newTestLink(t *testing.T, repos repository.Repositories, code string) *models.Link {
+	t.Helper()
+	link := &models.Link{
+		ShortCode:      code,
+		LongURL:        "https://example.com/" + code,
+		RedirectStatus: models.DefaultRedirectStatus,
+		QueryConflict:  models.QueryConflictLink,
+	}
+	if err := repos.Links.CreateLink(link); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	return link
+}
+
+func createTestClick(t *testing.T, clicks repository.ClickRepository, linkID uint, ip string) {
+	t.Helper()
+	if err := clicks.CreateClick(&models.Click{LinkID: linkID, Timestamp: time.Now(), IPAddress: ip}); err != nil {
+		t.Fatalf("CreateClick: %v", err)
+	}
+}
+
+func assertClickCount(t *testing.T, counter *ClickCounter, linkID uint, want int) {
+	t.Helper()
+	got, err := counter.CountClicksByLinkID(linkID)
+	if err != nil {
+		t.Fatalf("CountClicksByLinkID(%d): %v", linkID, err)
+	}
+	if got != want {
+		t.Errorf("CountClicksByLinkID(%d) = %d, want %d", linkID, got, want)
+	}
+}
+
+func TestClickCounterResetOnErasure(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	counter := NewClickCounter(repos.Clicks, client, "t:")
+
+	link := newTestLink(t, repos, "erased")
+	other := newTestLink(t, repos, "kept")
+	createTestClick(t, counter, link.ID, "192.0.2.1")
+	createTestClick(t, counter, link.ID, "192.0.2.2")
+	createTestClick(t, counter, other.ID, "192.0.2.2")
+	assertClickCount(t, counter, link.ID, 2)
+	assertClickCount(t, counter, other.ID, 1)
+
+	deleted, err := counter.DeleteClicksByIPAddresses([]string{"192.0.2.1"})
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteClicksByIPAddresses = %d, %v, want 1, nil", deleted, err)
+	}
+	if server.Exists(counter.counterKey(link.ID)) {
+		t.Error("counter of the link whose clicks were erased was kept")
+	}
+	if !server.Exists(counter.counterKey(other.ID)) {
+		t.Error("counter of an unaffected link was reset")
+	}
+	assertClickCount(t, counter, link.ID, 1)
+	assertClickCount(t, counter, other.ID, 1)
+}
+
+func TestClickCounterStartsFromDatabaseCount(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	counter := NewClickCounter(repos.Clicks, client, "t:")
+	link := newTestLink(t, repos, "abc")
+
+	// Clics enregistrés avant l'activation du cache
+	createTestClick(t, repos.Clicks, link.ID, "192.0.2.1")
+	createTestClick(t, repos.Clicks, link.ID, "192.0.2.1")
+
+	// Un clic sur un compteur absent ne doit pas le créer à 1
+	createTestClick(t, counter, link.ID, "192.0.2.1")
+	if server.Exists(counter.counterKey(link.ID)) {
+		t.Fatal("a click on a missing counter initialised it")
+	}
+	assertClickCount(t, counter, link.ID, 3)
+	if got, _ := server.Get(counter.counterKey(link.ID)); got != "3" {
+		t.Errorf("counter = %q after first read, want 3", got)
+	}
+
+	createTestClick(t, counter, link.ID, "192.0.2.1")
+	if got, _ := server.Get(counter.counterKey(link.ID)); got != "4" {
+		t.Errorf("counter = %q after a click, want 4", got)
+	}
+	assertClickCount(t, counter, link.ID, 4)
+}
+
+func TestClickCounterCountsSeveralLinks(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	counter := NewClickCounter(repos.Clicks, client, "t:")
+	cached := newTestLink(t, repos, "cached")
+	missing := newTestLink(t, repos, "missing")
+	createTestClick(t, repos.Clicks, missing.ID, "192.0.2.1")
+	server.Set(counter.counterKey(cached.ID), "7")
+
+	counts, err := counter.CountClicksByLinkIDs([]uint{cached.ID, missing.ID})
+	if err != nil {
+		t.Fatalf("CountClicksByLinkIDs: %v", err)
+	}
+	if counts[cached.ID] != 7 || counts[missing.ID] != 1 {
+		t.Errorf("CountClicksByLinkIDs = %v, want %d:7 and %d:1", counts, cached.ID, missing.ID)
+	}
+	if got, _ := server.Get(counter.counterKey(missing.ID)); got != "1" {
+		t.Errorf("missing counter = %q, want it initialised to 1", got)
+	}
+}
+
+func TestClickCounterFallsBackWhenRedisIsDown(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	counter := NewClickCounter(repos.Clicks, client, "t:")
+	link := newTestLink(t, repos, "abc")
+	createTestClick(t, counter, link.ID, "192.0.2.1")
+	assertClickCount(t, counter, link.ID, 1)
+	server.Close()
+
+	// Le clic est enregistré en base même si l'incrément échoue, et le comptage se fait en base
+	createTestClick(t, counter, link.ID, "192.0.2.1")
+	assertClickCount(t, counter, link.ID, 2)
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// LinkCache est un LinkRepository qui met en cache dans Redis les liens recherchés par code court.
+// Chaque lien en cache est accompagné d'une clé d'index par ID, pour que les modifications
+// (réglages, règles, variantes, utilisations consommées) invalident l'entrée sur toutes les instances.
+// Une erreur Redis n'interrompt jamais une redirection : la recherche se replie sur le repository décoré.
+type LinkCache struct {
+	repository.LinkRepository
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewLinkCache crée un cache de liens devant linkRepo. Une durée de vie nulle désactive l'expiration.
+func NewLinkCache(linkRepo repository.LinkRepository, client *redis.Client, prefix string, ttl time.Duration) *LinkCache {
+	return &LinkCache{LinkRepository: linkRepo, client: client, prefix: prefix, ttl: ttl}
+}
+
+// linkKey renvoie la clé du lien en cache pour un domaine et un code court.
+func (c *LinkCache) linkKey(domainID uint, shortCode string) string {
+	return fmt.Sprintf("%slink:%d:%s", c.prefix, domainID, shortCode)
+}
+
+// indexKey renvoie la clé qui associe l'ID d'un lien à sa clé en cache.
+func (c *LinkCache) indexKey(linkID uint) string {
+	return fmt.Sprintf("%slink-id:%d", c.prefix, linkID)
+}
+
+// GetLinkByShortCode renvoie le lien en cache ou, à défaut, le lit dans le repository décoré et le met en cache.
+func (c *LinkCache) GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error) {
+	ctx := context.Background()
+	key := c.linkKey(domainID, shortCode)
+
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err == nil {
+		var link models.Link
+		if err := json.Unmarshal(data, &link); err == nil {
+			return &link, nil
+		}
+		log.Printf("Attention: lien en cache illisible (%s), relecture en base", key)
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("Attention: cache Redis indisponible, lecture en base: %v", err)
+	}
+
+	link, err := c.LinkRepository.GetLinkByShortCode(domainID, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(link); err == nil {
+		_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, c.ttl)
+			pipe.Set(ctx, c.indexKey(link.ID), key, c.ttl)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Attention: impossible de mettre le lien %s en cache: %v", key, err)
+		}
+	}
+	return link, nil
+}
+
+// UpdateLinkFields met à jour le lien puis invalide son entrée en cache.
+func (c *LinkCache) UpdateLinkFields(linkID uint, fields map[string]interface{}) error {
+	if err := c.LinkRepository.UpdateLinkFields(linkID, fields); err != nil {
+		return err
+	}
+	c.invalidate(linkID)
+	return nil
+}
+
+// ReplaceTargetingRules remplace les règles de ciblage du lien puis invalide son entrée en cache.
+func (c *LinkCache) ReplaceTargetingRules(linkID uint, rules []models.TargetingRule) error {
+	if err := c.LinkRepository.ReplaceTargetingRules(linkID, rules); err != nil {
+		return err
+	}
+	c.invalidate(linkID)
+	return nil
+}
+
+// ReplaceVariants remplace les variantes A/B du lien puis invalide son entrée en cache.
+func (c *LinkCache) ReplaceVariants(linkID uint, variants []models.LinkVariant) error {
+	if err := c.LinkRepository.ReplaceVariants(linkID, variants); err != nil {
+		return err
+	}
+	c.invalidate(linkID)
+	return nil
+}
+
+// ClaimLinkUse consomme une utilisation du lien puis invalide son entrée en cache,
+// pour que le nombre d'utilisations affiché reste exact. La réservation elle-même reste en base.
+func (c *LinkCache) ClaimLinkUse(linkID uint) (bool, error) {
+	claimed, err := c.LinkRepository.ClaimLinkUse(linkID)
+	if claimed {
+		c.invalidate(linkID)
+	}
+	return claimed, err
+}
+
+// invalidate supprime l'entrée en cache d'un lien. La modification est déjà enregistrée en base :
+// un échec est seulement journalisé, l'entrée expirera au bout de sa durée de vie.
+func (c *LinkCache) invalidate(linkID uint) {
+	ctx := context.Background()
+	indexKey := c.indexKey(linkID)
+	key, err := c.client.Get(ctx, indexKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return
+	}
+	if err == nil {
+		err = c.client.Del(ctx, key, indexKey).Err()
+	}
+	if err != nil {
+		log.Printf("Attention: impossible d'invalider le lien %d en cache: %v", linkID, err)
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+func TestLinkCacheHitAndMiss(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	links := NewLinkCache(repos.Links, client, "t:", 0)
+	link := newTestLink(t, repos, "abc")
+
+	if _, err := links.GetLinkByShortCode(0, "missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetLinkByShortCode(missing) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if server.Exists(links.linkKey(0, "missing")) {
+		t.Error("an unknown code was cached")
+	}
+
+	got, err := links.GetLinkByShortCode(0, "abc")
+	if err != nil || got.ID != link.ID {
+		t.Fatalf("GetLinkByShortCode(abc) = %+v, %v", got, err)
+	}
+	if !server.Exists(links.linkKey(0, "abc")) || !server.Exists(links.indexKey(link.ID)) {
+		t.Fatal("link was not cached after a miss")
+	}
+
+	// Modification directe en base : la lecture suivante doit venir du cache
+	if err := repos.Links.UpdateLinkFields(link.ID, map[string]interface{}{"interstitial": true}); err != nil {
+		t.Fatalf("UpdateLinkFields: %v", err)
+	}
+	got, err = links.GetLinkByShortCode(0, "abc")
+	if err != nil || got.Interstitial {
+		t.Errorf("cache hit returned %+v, %v, want the cached link", got, err)
+	}
+}
+
+func TestLinkCacheTTL(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	links := NewLinkCache(repos.Links, client, "t:", time.Minute)
+	newTestLink(t, repos, "abc")
+
+	if _, err := links.GetLinkByShortCode(0, "abc"); err != nil {
+		t.Fatalf("GetLinkByShortCode: %v", err)
+	}
+	if ttl := server.TTL(links.linkKey(0, "abc")); ttl != time.Minute {
+		t.Errorf("TTL = %s, want 1m", ttl)
+	}
+}
+
+func TestLinkCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(links *LinkCache, linkID uint) error
+		check  func(link *models.Link) bool
+	}{
+		{"UpdateLinkFields", func(links *LinkCache, linkID uint) error {
+			return links.UpdateLinkFields(linkID, map[string]interface{}{"interstitial": true})
+		}, func(link *models.Link) bool { return link.Interstitial }},
+		{"ReplaceTargetingRules", func(links *LinkCache, linkID uint) error {
+			return links.ReplaceTargetingRules(linkID, []models.TargetingRule{{OS: "ios", TargetURL: "https://example.com/ios"}})
+		}, func(link *models.Link) bool { return len(link.TargetingRules) == 1 }},
+		{"ReplaceVariants", func(links *LinkCache, linkID uint) error {
+			return links.ReplaceVariants(linkID, []models.LinkVariant{{Name: "A", TargetURL: "https://example.com/a", Weight: 1}})
+		}, func(link *models.Link) bool { return len(link.Variants) == 1 }},
+		{"ClaimLinkUse", func(links *LinkCache, linkID uint) error {
+			_, err := links.ClaimLinkUse(linkID)
+			return err
+		}, func(link *models.Link) bool { return link.UseCount == 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newTestRedis(t)
+			repos := repository.NewMemoryRepositories()
+			links := NewLinkCache(repos.Links, client, "t:", 0)
+			link := newTestLink(t, repos, "abc")
+
+			if _, err := links.GetLinkByShortCode(0, "abc"); err != nil {
+				t.Fatalf("GetLinkByShortCode: %v", err)
+			}
+			if err := tt.update(links, link.ID); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if server.Exists(links.linkKey(0, "abc")) || server.Exists(links.indexKey(link.ID)) {
+				t.Error("cache entry was not invalidated")
+			}
+			got, err := links.GetLinkByShortCode(0, "abc")
+			if err != nil {
+				t.Fatalf("GetLinkByShortCode: %v", err)
+			}
+			if !tt.check(got) {
+				t.Errorf("GetLinkByShortCode after update = %+v, want the updated link", got)
+			}
+		})
+	}
+}
+
+func TestLinkCacheFallsBackWhenRedisIsDown(t *testing.T) {
+	server, client := newTestRedis(t)
+	repos := repository.NewMemoryRepositories()
+	links := NewLinkCache(repos.Links, client, "t:", 0)
+	link := newTestLink(t, repos, "abc")
+	server.Close()
+
+	got, err := links.GetLinkByShortCode(0, "abc")
+	if err != nil || got.ID != link.ID {
+		t.Fatalf("GetLinkByShortCode with Redis down = %+v, %v, want the link from the repository", got, err)
+	}
+	if err := links.UpdateLinkFields(link.ID, map[string]interface{}{"interstitial": true}); err != nil {
+		t.Errorf("UpdateLinkFields with Redis down: %v", err)
+	}
+}
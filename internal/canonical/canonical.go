@@ -0,0 +1,124 @@
+// Package canonical ramène les URLs longues à une forme canonique, pour détecter les doublons.
+package canonical
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidURL est renvoyée lorsqu'une URL ne peut pas être canonicalisée (URL relative ou illisible).
+var ErrInvalidURL = errors.New("invalid URL")
+
+// defaultPorts associe chaque schéma à son port implicite, retiré de la forme canonique.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalizer calcule la forme canonique des URLs :
+// schéma et hôte en minuscules, port par défaut retiré, chemin sans barre oblique finale,
+// paramètres de requête triés et paramètres de suivi configurés supprimés.
+// Un Canonicalizer nil est valide et ne supprime aucun paramètre.
+type Canonicalizer struct {
+	stripExact    map[string]bool
+	stripPrefixes []string
+}
+
+// New crée un Canonicalizer qui supprime les paramètres listés.
+// Un motif terminé par "*" supprime tous les paramètres qui commencent par ce préfixe (ex: utm_*).
+func New(stripParams []string) *Canonicalizer {
+	c := &Canonicalizer{stripExact: make(map[string]bool)}
+	for _, param := range stripParams {
+		param = strings.ToLower(strings.TrimSpace(param))
+		switch {
+		case param == "":
+		case strings.HasSuffix(param, "*"):
+			c.stripPrefixes = append(c.stripPrefixes, strings.TrimSuffix(param, "*"))
+		default:
+			c.stripExact[param] = true
+		}
+	}
+	return c
+}
+
+// Canonicalize renvoie la forme canonique de rawURL. Deux URLs équivalentes ont la même forme canonique.
+func (c *Canonicalizer) Canonicalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidURL, rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := u.Hostname(), u.Port()
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]" // IPv6
+	} else {
+		u.Host = host
+	}
+
+	// "/docs/" et "/docs" désignent la même page ; la racine garde sa barre oblique
+	// On travaille sur le chemin échappé pour ne pas confondre "/a%2Fb" et "/a/b"
+	escapedPath := strings.TrimRight(u.EscapedPath(), "/")
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	if u.Path, err = url.PathUnescape(escapedPath); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	u.RawPath = escapedPath
+
+	u.RawQuery = c.canonicalQuery(u.Query())
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// canonicalQuery trie les paramètres par nom (en conservant l'ordre des valeurs répétées)
+// et retire les paramètres de suivi.
+func (c *Canonicalizer) canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !c.stripped(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// stripped indique si un paramètre de requête doit être retiré de la forme canonique.
+func (c *Canonicalizer) stripped(key string) bool {
+	if c == nil {
+		return false
+	}
+	key = strings.ToLower(key)
+	if c.stripExact[key] {
+		return true
+	}
+	for _, prefix := range c.stripPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package canonical
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := New([]string{"utm_*", "FBCLID", " "})
+	tests := []struct {
+		url, want string
+	}{
+		{"https://example.com", "https://example.com/"},
+		{"HTTPS://Example.COM/", "https://example.com/"},
+		{"https://example.com:443/docs/", "https://example.com/docs"},
+		{"http://example.com:80/docs", "http://example.com/docs"},
+		{"http://example.com:8080/docs", "http://example.com:8080/docs"},
+		{"https://example.com./docs", "https://example.com/docs"},
+		{"https://[2001:DB8::1]:443/", "https://[2001:db8::1]/"},
+		{"https://example.com/Docs", "https://example.com/Docs"},
+		{"https://example.com/a%2Fb/", "https://example.com/a%2Fb"},
+		{"https://example.com/?b=2&a=1&b=1", "https://example.com/?a=1&b=2&b=1"},
+		{"https://example.com/?utm_source=x&fbclid=y&id=3", "https://example.com/?id=3"},
+		{"https://example.com/?UTM_Medium=x", "https://example.com/"},
+		{"https://example.com/?", "https://example.com/"},
+		{"https://example.com/page#top", "https://example.com/page#top"},
+		{"  https://example.com/page  ", "https://example.com/page"},
+	}
+	for _, tt := range tests {
+		got, err := c.Canonicalize(tt.url)
+		if err != nil {
+			t.Errorf("Canonicalize(%q): %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeKeepsParamsWithoutStripList(t *testing.T) {
+	for _, c := range []*Canonicalizer{nil, New(nil)} {
+		got, err := c.Canonicalize("https://example.com/?utm_source=x")
+		if err != nil || got != "https://example.com/?utm_source=x" {
+			t.Errorf("Canonicalize = %q, %v, want the utm parameter kept", got, err)
+		}
+	}
+}
+
+func TestCanonicalizeRejectsInvalidURLs(t *testing.T) {
+	for _, url := range []string{"/relative/path", "example.com/page", "mailto:someone@example.com", "https://exa mple.com/%zz"} {
+		if _, err := New(nil).Canonicalize(url); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Canonicalize(%q) error = %v, want ErrInvalidURL", url, err)
+		}
+	}
+}
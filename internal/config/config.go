@@ -5,6 +5,8 @@ import (
 	"log" // Pour logger les informations ou erreurs de chargement de config
 
 	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
 	"github.com/spf13/viper" // La bibliothèque pour la gestion de configuration
 )
 
@@ -13,23 +15,81 @@ import (
 // (ou des variables d'environnement) aux champs de la structure Go.
 type Config struct {
 	Server struct {
-		Port    int    `mapstructure:"port"`
-		BaseURL string `mapstructure:"base_url"`
+		Port            int      `mapstructure:"port"`
+		BaseURL         string   `mapstructure:"base_url"`
+		TrustedProxies  []string `mapstructure:"trusted_proxies"`   // Proxys (CIDR ou adresses) dont les en-têtes d'adresse client sont crus
+		ClientIPHeaders []string `mapstructure:"client_ip_headers"` // En-têtes d'adresse client lus, par ordre de priorité
 	} `mapstructure:"server"`
 
 	Database struct {
-		Name string `mapstructure:"name"`
+		Driver       string `mapstructure:"driver"`         // sqlite, postgres ou memory
+		Name         string `mapstructure:"name"`           // Fichier SQLite
+		DSN          string `mapstructure:"dsn"`            // Chaîne de connexion PostgreSQL
+		MaxOpenConns int    `mapstructure:"max_open_conns"` // Nombre maximal de connexions ouvertes (0 = illimité)
 	} `mapstructure:"database"`
 
 	Analytics struct {
-		BufferSize  int `mapstructure:"buffer_size"`
-		WorkerCount int `mapstructure:"worker_count"`
+		BufferSize            int `mapstructure:"buffer_size"`
+		WorkerCount           int `mapstructure:"worker_count"`
+		RollupIntervalMinutes int `mapstructure:"rollup_interval_minutes"` // Intervalle d'agrégation des clics (0 = désactivée)
+		RollupDelayMinutes    int `mapstructure:"rollup_delay_minutes"`    // Délai après la fin d'une heure avant de l'agréger
 	} `mapstructure:"analytics"`
 
 	Monitor struct {
 		IntervalMinutes int `mapstructure:"interval_minutes"`
 	} `mapstructure:"monitor"`
 
+	// Bases GeoIP locales au format MaxMind (MMDB). Un chemin vide désactive l'enrichissement correspondant.
+	GeoIP struct {
+		CityDatabase string `mapstructure:"city_database"`
+		ASNDatabase  string `mapstructure:"asn_database"`
+	} `mapstructure:"geoip"`
+
+	// Stratégie de génération des codes courts (random, sequential, hashids ou words)
+	ShortCode shortcode.Options `mapstructure:"shortcode"`
+
+	// Déduplication des liens : une URL longue identique une fois canonicalisée réutilise le lien existant du propriétaire
+	Dedupe struct {
+		Default     bool     `mapstructure:"default"`      // Valeur du flag dedupe quand la requête ne le précise pas
+		StripParams []string `mapstructure:"strip_params"` // Paramètres de suivi ignorés (un "*" final désigne un préfixe)
+	} `mapstructure:"dedupe"`
+
+	// Authentification des routes réservées (modification des liens et des domaines, exports, import)
+	Auth struct {
+		APITokens []string `mapstructure:"api_tokens"` // Jetons acceptés dans l'en-tête "Authorization: Bearer <jeton>"
+	} `mapstructure:"auth"`
+
+	// QR codes des liens courts
+	QR struct {
+		CacheSize int `mapstructure:"cache_size"` // Nombre d'images rendues gardées en mémoire (0 = pas de cache)
+	} `mapstructure:"qr"`
+
+	// Clés d'idempotence des créations de liens (en-tête Idempotency-Key)
+	Idempotency struct {
+		WindowHours int `mapstructure:"window_hours"` // Durée de conservation des réponses, en heures (0 = en-tête ignoré)
+	} `mapstructure:"idempotency"`
+
+	// Confidentialité des clics : enregistrement des adresses IP et respect de DNT / Sec-GPC
+	Privacy privacy.Options `mapstructure:"privacy"`
+
+	// Rétention des clics bruts : au-delà, ils sont supprimés après agrégation (les totaux restent exacts)
+	Retention struct {
+		ClickDays       int `mapstructure:"click_days"`       // Durée de conservation des clics bruts, en jours (0 = illimitée)
+		IntervalMinutes int `mapstructure:"interval_minutes"` // Intervalle entre deux purges automatiques
+	} `mapstructure:"retention"`
+
+	// Cache partagé entre les instances : recherche des liens et compteurs de clics
+	Cache struct {
+		Driver    string `mapstructure:"driver"`     // "" (désactivé) ou redis
+		KeyPrefix string `mapstructure:"key_prefix"` // Préfixe des clés, pour partager un serveur Redis
+		LinkTTL   int    `mapstructure:"link_ttl"`   // Durée de vie d'un lien en cache, en secondes
+		Redis     struct {
+			Addr     string `mapstructure:"addr"`
+			Password string `mapstructure:"password"`
+			DB       int    `mapstructure:"db"`
+		} `mapstructure:"redis"`
+	} `mapstructure:"cache"`
+
 	// Channel pour les événements de clic (ajouté dynamiquement)
 	ClickEventsChannel chan models.ClickEvent `mapstructure:"-"`
 }
@@ -50,10 +110,51 @@ func LoadConfig() (*Config, error) {
 	// server.port, server.base	_url etc.
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.base_url", "http://localhost:8080")
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.client_ip_headers", []string{"X-Forwarded-For", "X-Real-IP"})
+	viper.SetDefault("database.driver", "sqlite")
 	viper.SetDefault("database.name", "url_shortener.db")
+	viper.SetDefault("database.dsn", "")
+	viper.SetDefault("database.max_open_conns", 0)
 	viper.SetDefault("analytics.buffer_size", 1000)
 	viper.SetDefault("analytics.worker_count", 5)
+	viper.SetDefault("analytics.rollup_interval_minutes", 5)
+	viper.SetDefault("analytics.rollup_delay_minutes", 2)
 	viper.SetDefault("monitor.interval_minutes", 5)
+	viper.SetDefault("geoip.city_database", "")
+	viper.SetDefault("geoip.asn_database", "")
+	viper.SetDefault("shortcode.strategy", shortcode.StrategyRandom)
+	viper.SetDefault("shortcode.length", shortcode.DefaultLength)
+	viper.SetDefault("shortcode.alphabet", shortcode.DefaultAlphabet)
+	viper.SetDefault("shortcode.grow_after", 2)
+	viper.SetDefault("shortcode.salt", "")
+	viper.SetDefault("shortcode.word_count", 3)
+	viper.SetDefault("shortcode.separator", "-")
+	viper.SetDefault("shortcode.unambiguous", false)
+	viper.SetDefault("shortcode.case_insensitive", false)
+	viper.SetDefault("shortcode.blocklist", []string{})
+	viper.SetDefault("dedupe.default", false)
+	viper.SetDefault("dedupe.strip_params", []string{"utm_*", "fbclid", "gclid", "msclkid", "mc_cid", "mc_eid"})
+
+	viper.SetDefault("auth.api_tokens", []string{})
+	viper.SetDefault("idempotency.window_hours", 24)
+	viper.SetDefault("qr.cache_size", 256)
+
+	viper.SetDefault("privacy.ip_mode", privacy.ModeFull)
+	viper.SetDefault("privacy.ipv4_prefix", 24)
+	viper.SetDefault("privacy.ipv6_prefix", 48)
+	viper.SetDefault("privacy.hash_secret", "")
+	viper.SetDefault("privacy.honor_dnt", false)
+
+	viper.SetDefault("retention.click_days", 0)
+	viper.SetDefault("retention.interval_minutes", 60)
+
+	viper.SetDefault("cache.driver", "")
+	viper.SetDefault("cache.key_prefix", "url-shortener:")
+	viper.SetDefault("cache.link_ttl", 300)
+	viper.SetDefault("cache.redis.addr", "localhost:6379")
+	viper.SetDefault("cache.redis.password", "")
+	viper.SetDefault("cache.redis.db", 0)
 
 	//gestion des erreurs
 	if err := viper.ReadInConfig(); err != nil {
@@ -67,8 +168,8 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
 	// Log  pour vérifier la config chargée
-	log.Printf("Configuration loaded: Server Port=%d, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
-		cfg.Server.Port, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
+	log.Printf("Configuration loaded: Server Port=%d, DB Driver=%s, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
+		cfg.Server.Port, cfg.Database.Driver, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
 
 	return &cfg, nil // Retourne la configuration chargée
 }
@@ -0,0 +1,57 @@
+// Package database ouvre la connexion GORM correspondant au pilote configuré.
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/armanceau/go-url-shortener/internal/config"
+	"github.com/glebarez/sqlite" // Pure go SQLite driver, checkout https://github.com/glebarez/sqlite for details
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Pilotes de base de données supportés (clé database.driver de la configuration).
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory" // Stockage en mémoire, sans base SQL : seul le serveur le supporte
+)
+
+// ErrNoSQLDatabase est renvoyée par Open lorsque le pilote configuré n'a pas de base SQL (pilote memory).
+var ErrNoSQLDatabase = errors.New("the memory driver has no SQL database: only the server command supports it")
+
+// Open ouvre la base de données configurée : un fichier SQLite (database.name)
+// ou un serveur PostgreSQL (database.dsn). C'est la seule fabrique utilisée par les commandes.
+// Avec le pilote memory, il renvoie ErrNoSQLDatabase.
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Database.Driver {
+	case "", DriverSQLite:
+		dialector = sqlite.Open(cfg.Database.Name)
+	case DriverMemory:
+		return nil, ErrNoSQLDatabase
+	case DriverPostgres:
+		if cfg.Database.DSN == "" {
+			return nil, fmt.Errorf("database.dsn is required for the %s driver", DriverPostgres)
+		}
+		dialector = postgres.Open(cfg.Database.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (expected %s, %s or %s)", cfg.Database.Driver, DriverSQLite, DriverPostgres, DriverMemory)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Une instance partage son pool entre les requêtes HTTP, les workers de clics et le moniteur
+	if cfg.Database.MaxOpenConns > 0 {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	}
+	return db, nil
+}
@@ -0,0 +1,258 @@
+// Package export écrit les liens et les clics au format CSV, JSON Lines ou Parquet,
+// enregistrement par enregistrement, pour les charger dans un entrepôt de données.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats d'export supportés.
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// parquetRowGroupSize est le nombre d'enregistrements d'un groupe de lignes Parquet,
+// c'est-à-dire le nombre maximal d'enregistrements gardés en mémoire avant leur écriture.
+const parquetRowGroupSize = 10000
+
+// ErrInvalidFormat est renvoyée lorsque le format d'export demandé n'est pas supporté.
+var ErrInvalidFormat = errors.New("export format must be one of csv, jsonl or parquet")
+
+// IsValidFormat indique si le format d'export est supporté.
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatJSONL, FormatParquet:
+		return true
+	}
+	return false
+}
+
+// ContentType renvoie le type MIME d'un format d'export.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.apache.parquet"
+}
+
+// ParseTime lit une borne de période : date (2006-01-02, à minuit UTC) ou horodatage RFC 3339.
+// Une valeur vide renvoie l'instant zéro, qui ne borne pas la période.
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t, nil
+}
+
+// Record est un enregistrement exportable : il sait se décrire en colonnes CSV.
+type Record interface {
+	LinkRecord | ClickRecord
+	csvHeader() []string
+	csvRow() []string
+}
+
+// Writer écrit des enregistrements dans un format d'export. Close doit être appelé pour terminer le fichier.
+type Writer[T Record] interface {
+	Write(record T) error
+	Close() error
+}
+
+// NewWriter crée un Writer du format demandé sur w.
+func NewWriter[T Record](format string, w io.Writer) (Writer[T], error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter[T]{w: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		return &jsonlWriter[T]{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter[T]{w: parquet.NewGenericWriter[T](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+// csvWriter écrit une ligne d'en-tête puis une ligne par enregistrement.
+type csvWriter[T Record] struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWriter[T]) Write(record T) error {
+	if !c.headerWritten {
+		if err := c.w.Write(record.csvHeader()); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+	return c.w.Write(record.csvRow())
+}
+
+func (c *csvWriter[T]) Close() error {
+	if !c.headerWritten {
+		var zero T
+		if err := c.w.Write(zero.csvHeader()); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter écrit un objet JSON par ligne.
+type jsonlWriter[T Record] struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlWriter[T]) Write(record T) error { return j.encoder.Encode(record) }
+
+func (j *jsonlWriter[T]) Close() error { return nil }
+
+// parquetWriter écrit les enregistrements par groupes de lignes ; le pied du fichier est écrit par Close.
+type parquetWriter[T Record] struct {
+	w *parquet.GenericWriter[T]
+}
+
+func (p *parquetWriter[T]) Write(record T) error {
+	_, err := p.w.Write([]T{record})
+	return err
+}
+
+func (p *parquetWriter[T]) Close() error { return p.w.Close() }
+
+// LinkRecord est la forme exportée d'un lien.
+type LinkRecord struct {
+	ID             uint64    `json:"id" parquet:"id"`
+	Domain         string    `json:"domain" parquet:"domain"`
+	ShortCode      string    `json:"short_code" parquet:"short_code"`
+	LongURL        string    `json:"long_url" parquet:"long_url"`
+	Owner          string    `json:"owner" parquet:"owner"`
+	MaxUses        int64     `json:"max_uses" parquet:"max_uses"`
+	UseCount       int64     `json:"use_count" parquet:"use_count"`
+	RedirectStatus int64     `json:"redirect_status" parquet:"redirect_status"`
+	UTMSource      string    `json:"utm_source" parquet:"utm_source"`
+	UTMMedium      string    `json:"utm_medium" parquet:"utm_medium"`
+	UTMCampaign    string    `json:"utm_campaign" parquet:"utm_campaign"`
+	UTMTerm        string    `json:"utm_term" parquet:"utm_term"`
+	UTMContent     string    `json:"utm_content" parquet:"utm_content"`
+	ImportedClicks int64     `json:"imported_clicks" parquet:"imported_clicks"`
+	CreatedAt      time.Time `json:"created_at" parquet:"created_at,timestamp(microsecond)"`
+}
+
+// NewLinkRecord convertit un lien ; domain est le nom d'hôte de son domaine (vide pour le domaine par défaut).
+func NewLinkRecord(link *models.Link, domain string) LinkRecord {
+	return LinkRecord{
+		ID:             uint64(link.ID),
+		Domain:         domain,
+		ShortCode:      link.ShortCode,
+		LongURL:        link.LongURL,
+		Owner:          link.Owner,
+		MaxUses:        int64(link.MaxUses),
+		UseCount:       int64(link.UseCount),
+		RedirectStatus: int64(link.EffectiveRedirectStatus()),
+		UTMSource:      link.UTM.Source,
+		UTMMedium:      link.UTM.Medium,
+		UTMCampaign:    link.UTM.Campaign,
+		UTMTerm:        link.UTM.Term,
+		UTMContent:     link.UTM.Content,
+		ImportedClicks: int64(link.ImportedClicks),
+		CreatedAt:      link.CreatedAt.UTC(),
+	}
+}
+
+func (LinkRecord) csvHeader() []string {
+	return []string{"id", "domain", "short_code", "long_url", "owner", "max_uses", "use_count", "redirect_status",
+		"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "imported_clicks", "created_at"}
+}
+
+func (l LinkRecord) csvRow() []string {
+	return []string{
+		strconv.FormatUint(l.ID, 10), l.Domain, l.ShortCode, l.LongURL, l.Owner,
+		strconv.FormatInt(l.MaxUses, 10), strconv.FormatInt(l.UseCount, 10), strconv.FormatInt(l.RedirectStatus, 10),
+		l.UTMSource, l.UTMMedium, l.UTMCampaign, l.UTMTerm, l.UTMContent, strconv.FormatInt(l.ImportedClicks, 10),
+		l.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// ClickRecord est la forme exportée d'un clic. RuleID et VariantID sont nuls si aucune règle ou variante n'a servi.
+type ClickRecord struct {
+	ID        uint64    `json:"id" parquet:"id"`
+	LinkID    uint64    `json:"link_id" parquet:"link_id"`
+	Timestamp time.Time `json:"timestamp" parquet:"timestamp,timestamp(microsecond)"`
+	UserAgent string    `json:"user_agent" parquet:"user_agent"`
+	IPAddress string    `json:"ip_address" parquet:"ip_address"`
+	RuleID    *uint64   `json:"rule_id" parquet:"rule_id,optional"`
+	VariantID *uint64   `json:"variant_id" parquet:"variant_id,optional"`
+	Country   string    `json:"country" parquet:"country"`
+	Region    string    `json:"region" parquet:"region"`
+	City      string    `json:"city" parquet:"city"`
+	ASN       uint64    `json:"asn" parquet:"asn"`
+	ASOrg     string    `json:"as_org" parquet:"as_org"`
+}
+
+// NewClickRecord convertit un clic.
+func NewClickRecord(click *models.Click) ClickRecord {
+	return ClickRecord{
+		ID:        uint64(click.ID),
+		LinkID:    uint64(click.LinkID),
+		Timestamp: click.Timestamp.UTC(),
+		UserAgent: click.UserAgent,
+		IPAddress: click.IPAddress,
+		RuleID:    optionalID(click.RuleID),
+		VariantID: optionalID(click.VariantID),
+		Country:   click.Country,
+		Region:    click.Region,
+		City:      click.City,
+		ASN:       uint64(click.ASN),
+		ASOrg:     click.ASOrg,
+	}
+}
+
+func (ClickRecord) csvHeader() []string {
+	return []string{"id", "link_id", "timestamp", "user_agent", "ip_address", "rule_id", "variant_id",
+		"country", "region", "city", "asn", "as_org"}
+}
+
+func (c ClickRecord) csvRow() []string {
+	return []string{
+		strconv.FormatUint(c.ID, 10), strconv.FormatUint(c.LinkID, 10), c.Timestamp.Format(time.RFC3339Nano),
+		c.UserAgent, c.IPAddress, formatOptionalID(c.RuleID), formatOptionalID(c.VariantID),
+		c.Country, c.Region, c.City, strconv.FormatUint(c.ASN, 10), c.ASOrg,
+	}
+}
+
+// optionalID convertit un identifiant optionnel.
+func optionalID(id *uint) *uint64 {
+	if id == nil {
+		return nil
+	}
+	value := uint64(*id)
+	return &value
+}
+
+// formatOptionalID rend un identifiant optionnel en CSV (vide si absent).
+func formatOptionalID(id *uint64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(*id, 10)
+}
@@ -0,0 +1,153 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"2024-05-10", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-10T15:04:05Z", time.Date(2024, 5, 10, 15, 4, 5, 0, time.UTC)},
+		{"2024-05-10T17:04:05+02:00", time.Date(2024, 5, 10, 15, 4, 5, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.value)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"10/05/2024", "2024-05-10 15:04", "yesterday"} {
+		if _, err := ParseTime(value); err == nil {
+			t.Errorf("ParseTime(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatJSONL, FormatParquet} {
+		if !IsValidFormat(format) {
+			t.Errorf("IsValidFormat(%q) = false", format)
+		}
+	}
+	if IsValidFormat("xlsx") || IsValidFormat("") {
+		t.Error("IsValidFormat accepts unsupported formats")
+	}
+	if _, err := NewWriter[LinkRecord]("xlsx", &bytes.Buffer{}); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("NewWriter(xlsx) error = %v, want ErrInvalidFormat", err)
+	}
+	if got := ContentType(FormatJSONL); got != "application/x-ndjson" {
+		t.Errorf("ContentType(jsonl) = %q", got)
+	}
+}
+
+// testClicks renvoie deux clics, dont un sur une variante A/B.
+func testClicks() []ClickRecord {
+	variantID := uint(4)
+	at := time.Date(2024, 5, 10, 15, 4, 5, 0, time.UTC)
+	return []ClickRecord{
+		NewClickRecord(&models.Click{ID: 1, LinkID: 2, Timestamp: at, UserAgent: `Agent "quoted", with comma`, Country: "FR", ASN: 64500}),
+		NewClickRecord(&models.Click{ID: 2, LinkID: 2, Timestamp: at.Add(time.Minute), VariantID: &variantID}),
+	}
+}
+
+// writeAll écrit les enregistrements au format demandé et renvoie le fichier produit.
+func writeAll[T Record](t *testing.T, format string, records []T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter[T](format, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", format, err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewReader(writeAll(t, FormatCSV, testClicks()))).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "id,link_id,timestamp,user_agent,ip_address,rule_id,variant_id,country,region,city,asn,as_org" {
+		t.Fatalf("CSV = %v, want a header and 2 rows", rows)
+	}
+	if rows[1][3] != `Agent "quoted", with comma` || rows[1][2] != "2024-05-10T15:04:05Z" || rows[1][10] != "64500" {
+		t.Errorf("first row = %v", rows[1])
+	}
+	if rows[1][6] != "" || rows[2][6] != "4" {
+		t.Errorf("variant_id columns = %q and %q, want empty and 4", rows[1][6], rows[2][6])
+	}
+
+	// Un export vide contient tout de même l'en-tête
+	if got := string(writeAll[LinkRecord](t, FormatCSV, nil)); !strings.HasPrefix(got, "id,domain,short_code,") || strings.Count(got, "\n") != 1 {
+		t.Errorf("empty CSV = %q, want only the header", got)
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(writeAll(t, FormatJSONL, testClicks()))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d JSON lines, want 2", len(lines))
+	}
+	var second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[1], err)
+	}
+	if second["variant_id"] != float64(4) || second["rule_id"] != nil || second["timestamp"] != "2024-05-10T15:05:05Z" {
+		t.Errorf("second line = %v", second)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	created := time.Date(2024, 5, 10, 15, 4, 5, 0, time.UTC)
+	links := []LinkRecord{
+		NewLinkRecord(&models.Link{ID: 1, ShortCode: "abc", LongURL: "https://example.com", CreatedAt: created, UTM: models.UTMParams{Source: "mail"}}, ""),
+		NewLinkRecord(&models.Link{ID: 2, ShortCode: "def", LongURL: "https://example.org", CreatedAt: created, MaxUses: 5}, "brand.test"),
+	}
+	data := writeAll(t, FormatParquet, links)
+
+	read, err := parquet.Read[LinkRecord](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid Parquet file: %v", err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("%d Parquet rows, want 2", len(read))
+	}
+	for i := range links {
+		if read[i] != links[i] {
+			t.Errorf("row %d = %+v, want %+v", i, read[i], links[i])
+		}
+	}
+	if read[0].RedirectStatus != int64(models.DefaultRedirectStatus) {
+		t.Errorf("redirect_status = %d, want the effective default", read[0].RedirectStatus)
+	}
+
+	clicks := testClicks()
+	data = writeAll(t, FormatParquet, clicks)
+	readClicks, err := parquet.Read[ClickRecord](bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(readClicks) != 2 {
+		t.Fatalf("Parquet clicks = %+v, %v", readClicks, err)
+	}
+	if readClicks[0].VariantID != nil || readClicks[1].VariantID == nil || *readClicks[1].VariantID != 4 {
+		t.Errorf("variant_id = %v and %v, want null and 4", readClicks[0].VariantID, readClicks[1].VariantID)
+	}
+}
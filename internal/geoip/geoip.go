@@ -0,0 +1,135 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location regroupe les informations géographiques et réseau associées à une adresse IP.
+type Location struct {
+	Country string // Code pays ISO 3166-1 alpha-2 (ex: FR)
+	Region  string // Nom de la subdivision principale (ex: Île-de-France)
+	City    string // Nom de la ville
+	ASN     uint   // Numéro de système autonome
+	ASOrg   string // Organisation propriétaire de l'AS
+}
+
+// cityRecord reprend les champs utiles d'une base au format GeoLite2/GeoIP2 City ou Country.
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord reprend les champs d'une base au format GeoLite2/GeoIP2 ASN.
+type asnRecord struct {
+	Number uint   `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+// Resolver résout des adresses IP en localisation à partir de bases MaxMind (MMDB) locales.
+// Un Resolver nil ou sans base ouverte est valide : il renvoie simplement des localisations vides,
+// ce qui permet au pipeline de clics de fonctionner sans base GeoIP.
+type Resolver struct {
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+}
+
+// Open ouvre les bases MMDB configurées. Un chemin vide désactive la base correspondante.
+func Open(cityPath, asnPath string) (*Resolver, error) {
+	r := &Resolver{}
+	if cityPath != "" {
+		reader, err := maxminddb.Open(cityPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GeoIP city database %s: %w", cityPath, err)
+		}
+		r.city = reader
+	}
+	if asnPath != "" {
+		reader, err := maxminddb.Open(asnPath)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to open GeoIP ASN database %s: %w", asnPath, err)
+		}
+		r.asn = reader
+	}
+	return r, nil
+}
+
+// Enabled indique si au moins une base GeoIP est disponible.
+func (r *Resolver) Enabled() bool {
+	return r != nil && (r.city != nil || r.asn != nil)
+}
+
+// Lookup renvoie la localisation d'une adresse IP. Les champs inconnus restent vides :
+// une IP invalide, privée ou absente des bases n'est pas une erreur.
+func (r *Resolver) Lookup(ip string) Location {
+	var loc Location
+	if !r.Enabled() {
+		return loc
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return loc
+	}
+
+	if r.city != nil {
+		var record cityRecord
+		if err := r.city.Lookup(parsed, &record); err == nil {
+			loc.Country = record.Country.ISOCode
+			loc.City = record.City.Names["en"]
+			if len(record.Subdivisions) > 0 {
+				loc.Region = record.Subdivisions[0].Names["en"]
+			}
+		}
+	}
+	if r.asn != nil {
+		var record asnRecord
+		if err := r.asn.Lookup(parsed, &record); err == nil {
+			loc.ASN = record.Number
+			loc.ASOrg = record.Org
+		}
+	}
+	return loc
+}
+
+// Country renvoie uniquement le code pays d'une adresse IP ("" si inconnu).
+// Utilisée dans le chemin de redirection : seule la base City/Country est consultée.
+func (r *Resolver) Country(ip string) string {
+	if r == nil || r.city == nil {
+		return ""
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	var record cityRecord
+	if err := r.city.Lookup(parsed, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+// Close ferme les bases ouvertes.
+func (r *Resolver) Close() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	if r.city != nil {
+		errs = append(errs, r.city.Close())
+	}
+	if r.asn != nil {
+		errs = append(errs, r.asn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package geoip
+
+import "testing"
+
+// Bases générées par testdata/gen
+const (
+	testCityDB = "testdata/GeoIP2-City-Test.mmdb"
+	testASNDB  = "testdata/GeoLite2-ASN-Test.mmdb"
+)
+
+func openTestResolver(t *testing.T, cityPath, asnPath string) *Resolver {
+	t.Helper()
+	r, err := Open(cityPath, asnPath)
+	if err != nil {
+		t.Fatalf("Open(%q, %q): %v", cityPath, asnPath, err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestLookup(t *testing.T) {
+	paris := Location{Country: "FR", Region: "Île-de-France", City: "Paris", ASN: 64500, ASOrg: "Example Transit"}
+	tests := []struct {
+		name string
+		city string // Chemin de la base City (vide = désactivée)
+		asn  string // Chemin de la base ASN (vide = désactivée)
+		ip   string
+		want Location
+	}{
+		{name: "city and ASN", city: testCityDB, asn: testASNDB, ip: "81.2.69.160", want: paris},
+		{name: "country only record", city: testCityDB, asn: testASNDB, ip: "2001:db8:100::1", want: Location{Country: "BE"}},
+		{name: "ASN only record", city: testCityDB, asn: testASNDB, ip: "1.1.1.1", want: Location{ASN: 64501, ASOrg: "Example DNS"}},
+		{name: "unknown IP", city: testCityDB, asn: testASNDB, ip: "8.8.8.8"},
+		{name: "private IP", city: testCityDB, asn: testASNDB, ip: "192.168.1.10"},
+		{name: "loopback IPv6", city: testCityDB, asn: testASNDB, ip: "::1"},
+		{name: "invalid IP", city: testCityDB, asn: testASNDB, ip: "not-an-ip"},
+		{name: "city database only", city: testCityDB, ip: "81.2.69.160", want: Location{Country: "FR", Region: "Île-de-France", City: "Paris"}},
+		{name: "ASN database only", asn: testASNDB, ip: "81.2.69.160", want: Location{ASN: 64500, ASOrg: "Example Transit"}},
+		{name: "no database", ip: "81.2.69.160"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := openTestResolver(t, tt.city, tt.asn)
+			if got := r.Lookup(tt.ip); got != tt.want {
+				t.Errorf("Lookup(%q) = %+v, want %+v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilResolver(t *testing.T) {
+	var r *Resolver
+	if r.Enabled() {
+		t.Error("nil resolver is enabled")
+	}
+	if got := r.Lookup("81.2.69.160"); got != (Location{}) {
+		t.Errorf("Lookup = %+v, want an empty location", got)
+	}
+	if got := r.Country("81.2.69.160"); got != "" {
+		t.Errorf("Country = %q, want empty", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestCountry(t *testing.T) {
+	r := openTestResolver(t, testCityDB, testASNDB)
+	for ip, want := range map[string]string{
+		"81.2.69.160":     "FR",
+		"2001:db8:100::1": "BE",
+		"1.1.1.1":         "",
+		"10.0.0.1":        "",
+		"not-an-ip":       "",
+	} {
+		if got := r.Country(ip); got != want {
+			t.Errorf("Country(%q) = %q, want %q", ip, got, want)
+		}
+	}
+	if got := openTestResolver(t, "", testASNDB).Country("81.2.69.160"); got != "" {
+		t.Errorf("Country without city database = %q, want empty", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if r := openTestResolver(t, "", ""); r.Enabled() {
+		t.Error("resolver without database is enabled")
+	}
+	if !openTestResolver(t, testCityDB, "").Enabled() || !openTestResolver(t, "", testASNDB).Enabled() {
+		t.Error("resolver with one database is not enabled")
+	}
+	if _, err := Open("testdata/missing.mmdb", ""); err == nil {
+		t.Error("Open accepted a missing city database")
+	}
+	if _, err := Open(testCityDB, "testdata/missing.mmdb"); err == nil {
+		t.Error("Open accepted a missing ASN database")
+	}
+}
@@ -0,0 +1,69 @@
+// Commande gen écrit les bases MMDB de test du package geoip (City et ASN).
+// Elle a son propre module pour ne pas ajouter mmdbwriter aux dépendances du projet :
+//
+//	cd internal/geoip/testdata/gen && go run .
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+func main() {
+	write("../GeoIP2-City-Test.mmdb", "GeoIP2-City", map[string]mmdbtype.Map{
+		// Paris, avec subdivision
+		"81.2.69.0/24": {
+			"country": mmdbtype.Map{"iso_code": mmdbtype.String("FR")},
+			"subdivisions": mmdbtype.Slice{
+				mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("Île-de-France")}},
+			},
+			"city": mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("Paris"), "fr": mmdbtype.String("Paris")}},
+		},
+		// Pays seul, comme dans une base Country
+		"2001:db8:100::/48": {
+			"country": mmdbtype.Map{"iso_code": mmdbtype.String("BE")},
+		},
+	})
+	write("../GeoLite2-ASN-Test.mmdb", "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"81.2.69.0/24": {
+			"autonomous_system_number":       mmdbtype.Uint32(64500),
+			"autonomous_system_organization": mmdbtype.String("Example Transit"),
+		},
+		"1.1.1.0/24": {
+			"autonomous_system_number":       mmdbtype.Uint32(64501),
+			"autonomous_system_organization": mmdbtype.String("Example DNS"),
+		},
+	})
+}
+
+func write(path, databaseType string, networks map[string]mmdbtype.Map) {
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		DatabaseType:            databaseType,
+		RecordSize:              24,
+		IncludeReservedNetworks: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for cidr, record := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := tree.Insert(network, record); err != nil {
+			log.Fatal(err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := tree.WriteTo(file); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,389 @@
+// Package importer lit des liens à importer depuis un fichier CSV ou JSON, qu'il provienne d'un export
+// de ce service ou d'un autre raccourcisseur (Bitly, Rebrandly, YOURLS, Shlink, ...).
+//
+// Les colonnes sont reconnues par leur nom, sans tenir compte de la casse ni de la ponctuation
+// (long_url, longUrl et "Long URL" sont équivalents), parmi plusieurs synonymes courants.
+// Les colonnes inconnues sont ignorées.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+// Formats d'import supportés. Le format JSON accepte un tableau d'objets ou un objet par ligne (JSON Lines).
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ErrInvalidFormat est renvoyée lorsque le format d'import demandé n'est pas supporté.
+var ErrInvalidFormat = errors.New("import format must be csv or json")
+
+// ErrMissingURL est renvoyée lorsqu'aucune colonne du fichier ne désigne l'URL longue.
+var ErrMissingURL = errors.New("no long URL column found (expected long_url, url, destination, ...)")
+
+// Row est un lien lu dans le fichier. Les champs absents du fichier restent à leur valeur zéro.
+type Row struct {
+	Line int // Ligne du fichier CSV ou rang de l'objet JSON (à partir de 1), pour le rapport d'import
+
+	Domain         string           // Nom d'hôte du domaine du lien
+	ShortCode      string           // Code court à conserver (vide = code généré)
+	LongURL        string           // URL de destination
+	Owner          string           // Propriétaire du lien
+	CreatedAt      time.Time        // Date de création d'origine (zéro = date de l'import)
+	Clicks         int              // Nombre de clics historiques
+	MaxUses        int              // Nombre maximal d'utilisations
+	RedirectStatus int              // Code HTTP de redirection
+	UTM            models.UTMParams // Paramètres UTM
+
+	Err error // Valeur illisible dans la ligne : la ligne est rejetée par l'import
+}
+
+// field désigne un champ de Row alimenté par une colonne.
+type field int
+
+const (
+	fieldLongURL field = iota
+	fieldShortCode
+	fieldShortURL
+	fieldDomain
+	fieldOwner
+	fieldCreatedAt
+	fieldClicks
+	fieldMaxUses
+	fieldRedirectStatus
+	fieldUTMSource
+	fieldUTMMedium
+	fieldUTMCampaign
+	fieldUTMTerm
+	fieldUTMContent
+)
+
+// columns associe les noms de colonnes normalisés (voir normalizeColumn) aux champs de Row.
+// Une URL courte complète (shortUrl, link de Bitly) ne sert qu'à en extraire le code.
+var columns = map[string]field{
+	"longurl": fieldLongURL, "url": fieldLongURL, "destination": fieldLongURL, "destinationurl": fieldLongURL,
+	"target": fieldLongURL, "targeturl": fieldLongURL, "originalurl": fieldLongURL, "longlink": fieldLongURL,
+
+	"shortcode": fieldShortCode, "code": fieldShortCode, "keyword": fieldShortCode, "slashtag": fieldShortCode,
+	"slug": fieldShortCode, "alias": fieldShortCode, "backhalf": fieldShortCode, "customalias": fieldShortCode,
+
+	"shorturl": fieldShortURL, "shortlink": fieldShortURL, "link": fieldShortURL, "bitlink": fieldShortURL,
+
+	"domain": fieldDomain, "owner": fieldOwner,
+
+	"createdat": fieldCreatedAt, "created": fieldCreatedAt, "createdon": fieldCreatedAt, "datecreated": fieldCreatedAt,
+	"creationdate": fieldCreatedAt, "timestamp": fieldCreatedAt, "date": fieldCreatedAt,
+
+	"clicks": fieldClicks, "clickcount": fieldClicks, "totalclicks": fieldClicks, "visits": fieldClicks,
+	"visitscount": fieldClicks, "hits": fieldClicks, "importedclicks": fieldClicks,
+
+	"maxuses": fieldMaxUses, "redirectstatus": fieldRedirectStatus,
+
+	"utmsource": fieldUTMSource, "utmmedium": fieldUTMMedium, "utmcampaign": fieldUTMCampaign,
+	"utmterm": fieldUTMTerm, "utmcontent": fieldUTMContent,
+}
+
+// timeLayouts sont les formats de date acceptés, essayés dans l'ordre. Sans fuseau, la date est en UTC.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02",
+}
+
+// IsValidFormat indique si le format d'import est supporté.
+func IsValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON
+}
+
+// DetectFormat déduit le format d'un nom de fichier (.csv, .json, .jsonl, .ndjson) ou d'un type MIME.
+// Il renvoie une chaîne vide si le format n'est pas reconnu.
+func DetectFormat(nameOrContentType string) string {
+	value := strings.ToLower(strings.TrimSpace(nameOrContentType))
+	if mediaType, _, ok := strings.Cut(value, ";"); ok {
+		value = strings.TrimSpace(mediaType)
+	}
+	switch value {
+	case "text/csv", "application/csv":
+		return FormatCSV
+	case "application/json", "application/x-ndjson", "application/jsonl":
+		return FormatJSON
+	}
+	switch path.Ext(value) {
+	case ".csv":
+		return FormatCSV
+	case ".json", ".jsonl", ".ndjson":
+		return FormatJSON
+	}
+	return ""
+}
+
+// Read lit toutes les lignes du fichier. Une erreur n'est renvoyée que si le fichier lui-même est illisible ;
+// une valeur invalide dans une ligne est signalée par Row.Err.
+func Read(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSON:
+		return readJSON(r)
+	}
+	return nil, ErrInvalidFormat
+}
+
+// readCSV lit un fichier CSV dont la première ligne nomme les colonnes. Le séparateur ";" est détecté.
+func readCSV(r io.Reader) ([]Row, error) {
+	buffered := bufio.NewReader(r)
+	skipBOM(buffered)
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	if first, _ := buffered.Peek(4096); bytes.Count(firstLine(first), []byte(";")) > bytes.Count(firstLine(first), []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	if !hasURLColumn(header) {
+		return nil, ErrMissingURL
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				values[name] = record[i]
+			}
+		}
+		rows = append(rows, newRow(line, values))
+	}
+}
+
+// firstLine renvoie la première ligne d'un début de fichier.
+func firstLine(data []byte) []byte {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return line
+}
+
+// readJSON lit un tableau d'objets, ou une suite d'objets (JSON Lines).
+func readJSON(r io.Reader) ([]Row, error) {
+	buffered := bufio.NewReader(r)
+	skipBOM(buffered)
+	decoder := json.NewDecoder(buffered)
+	decoder.UseNumber()
+
+	array := false
+	if first, err := firstNonSpace(buffered); err == nil && first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read JSON: %w", err)
+		}
+		array = true
+	}
+
+	var rows []Row
+	urlFound := false
+	for index := 1; ; index++ {
+		if array && !decoder.More() {
+			break
+		}
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if !array && err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to read JSON object %d: %w", index, err)
+		}
+
+		values := make(map[string]string, len(object))
+		names := make([]string, 0, len(object))
+		for name, value := range object {
+			if text, ok := jsonText(value); ok {
+				values[name] = text
+				names = append(names, name)
+			}
+		}
+		urlFound = urlFound || hasURLColumn(names)
+		rows = append(rows, newRow(index, values))
+	}
+	if len(rows) > 0 && !urlFound {
+		return nil, ErrMissingURL
+	}
+	return rows, nil
+}
+
+// skipBOM consomme la marque d'ordre des octets UTF-8 que certains tableurs placent en tête de fichier.
+func skipBOM(r *bufio.Reader) {
+	if bom, err := r.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		r.Discard(3)
+	}
+}
+
+// firstNonSpace renvoie le premier caractère significatif sans le consommer.
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], nil
+		}
+		if _, err := r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// jsonText convertit une valeur JSON scalaire en texte. Les objets et tableaux imbriqués sont ignorés.
+func jsonText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+// normalizeColumn met un nom de colonne sous sa forme comparée : minuscules, sans ponctuation ni espaces.
+func normalizeColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// hasURLColumn indique si l'une des colonnes désigne l'URL longue.
+func hasURLColumn(names []string) bool {
+	for _, name := range names {
+		if f, ok := columns[normalizeColumn(name)]; ok && f == fieldLongURL {
+			return true
+		}
+	}
+	return false
+}
+
+// newRow construit une ligne à partir des valeurs indexées par nom de colonne.
+// Un code court explicite est prioritaire sur celui extrait d'une URL courte complète.
+func newRow(line int, values map[string]string) Row {
+	row := Row{Line: line}
+	var shortURL string
+	for name, raw := range values {
+		f, ok := columns[normalizeColumn(name)]
+		value := strings.TrimSpace(raw)
+		if !ok || value == "" {
+			continue
+		}
+
+		var err error
+		switch f {
+		case fieldLongURL:
+			row.LongURL = value
+		case fieldShortCode:
+			row.ShortCode = value
+		case fieldShortURL:
+			shortURL = value
+		case fieldDomain:
+			row.Domain = value
+		case fieldOwner:
+			row.Owner = value
+		case fieldCreatedAt:
+			row.CreatedAt, err = parseTime(value)
+		case fieldClicks:
+			row.Clicks, err = parseCount(value)
+		case fieldMaxUses:
+			row.MaxUses, err = parseCount(value)
+		case fieldRedirectStatus:
+			row.RedirectStatus, err = strconv.Atoi(value)
+		case fieldUTMSource:
+			row.UTM.Source = value
+		case fieldUTMMedium:
+			row.UTM.Medium = value
+		case fieldUTMCampaign:
+			row.UTM.Campaign = value
+		case fieldUTMTerm:
+			row.UTM.Term = value
+		case fieldUTMContent:
+			row.UTM.Content = value
+		}
+		if err != nil && row.Err == nil {
+			row.Err = fmt.Errorf("column %s: %w", name, err)
+		}
+	}
+	if row.ShortCode == "" && shortURL != "" {
+		row.ShortCode = codeFromShortURL(shortURL)
+	}
+	return row
+}
+
+// parseTime lit une date dans l'un des formats de timeLayouts ou un horodatage Unix en secondes.
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseCount lit un nombre entier positif ou nul (les exports écrivent parfois "12.0").
+func parseCount(value string) (int, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 || number != float64(int(number)) {
+		return 0, fmt.Errorf("invalid count %q", value)
+	}
+	return int(number), nil
+}
+
+// codeFromShortURL extrait le code d'une URL courte complète (https://bit.ly/abc ou bit.ly/abc) : son dernier segment de chemin.
+func codeFromShortURL(shortURL string) string {
+	if !strings.Contains(shortURL, "://") {
+		shortURL = "https://" + shortURL
+	}
+	parsed, err := url.Parse(shortURL)
+	if err != nil {
+		return ""
+	}
+	code := path.Base(strings.TrimSuffix(parsed.Path, "/"))
+	if code == "." || code == "/" {
+		return ""
+	}
+	return code
+}
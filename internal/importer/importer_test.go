@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "\xEF\xBB\xBFShort URL;Long URL;Created At;Total Clicks;Owner\n" +
+		"https://bit.ly/abc;https://example.com/a;2023-05-01 10:00:00;12.0;alice\n" +
+		"bit.ly/xyz;https://example.com/b;not-a-date;3;\n"
+
+	rows, err := Read(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("%d rows, want 2", len(rows))
+	}
+
+	first := rows[0]
+	if first.Line != 2 || first.ShortCode != "abc" || first.LongURL != "https://example.com/a" || first.Owner != "alice" {
+		t.Errorf("first row = %+v", first)
+	}
+	if want := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC); !first.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", first.CreatedAt, want)
+	}
+	if first.Clicks != 12 || first.Err != nil {
+		t.Errorf("Clicks = %d, Err = %v, want 12 and no error", first.Clicks, first.Err)
+	}
+
+	if rows[1].ShortCode != "xyz" || rows[1].Err == nil {
+		t.Errorf("second row = %+v, want code xyz and a date error", rows[1])
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	array := `[{"url": "https://example.com/a", "slug": "abc", "clicks": 4, "timestamp": 1700000000},
+		{"destination": "https://example.com/b", "utm_source": "mail", "nested": {"ignored": true}}]`
+	lines := "{\"url\": \"https://example.com/a\", \"slug\": \"abc\", \"clicks\": 4, \"timestamp\": 1700000000}\n" +
+		"{\"destination\": \"https://example.com/b\", \"utm_source\": \"mail\"}\n"
+
+	for name, input := range map[string]string{"array": array, "lines": lines} {
+		rows, err := Read(FormatJSON, strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: Read: %v", name, err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%s: %d rows, want 2", name, len(rows))
+		}
+		if rows[0].Line != 1 || rows[0].ShortCode != "abc" || rows[0].Clicks != 4 || !rows[0].CreatedAt.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("%s: first row = %+v", name, rows[0])
+		}
+		if rows[1].Line != 2 || rows[1].LongURL != "https://example.com/b" || rows[1].UTM.Source != "mail" {
+			t.Errorf("%s: second row = %+v", name, rows[1])
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if _, err := Read("xml", strings.NewReader("")); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("xml: err = %v, want ErrInvalidFormat", err)
+	}
+	if _, err := Read(FormatCSV, strings.NewReader("code,owner\nabc,alice\n")); !errors.Is(err, ErrMissingURL) {
+		t.Errorf("CSV without URL: err = %v, want ErrMissingURL", err)
+	}
+	if _, err := Read(FormatJSON, strings.NewReader(`[{"code": "abc"}]`)); !errors.Is(err, ErrMissingURL) {
+		t.Errorf("JSON without URL: err = %v, want ErrMissingURL", err)
+	}
+	if rows, err := Read(FormatCSV, strings.NewReader("")); err != nil || len(rows) != 0 {
+		t.Errorf("empty CSV: %d rows, err = %v", len(rows), err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"links.csv":                FormatCSV,
+		"EXPORT.JSONL":             FormatJSON,
+		"links.ndjson":             FormatJSON,
+		"text/csv; charset=utf-8":  FormatCSV,
+		"application/json":         FormatJSON,
+		"links.xlsx":               "",
+		"application/octet-stream": "",
+	}
+	for input, want := range tests {
+		if got := DetectFormat(input); got != want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if !IsValidFormat(FormatCSV) || !IsValidFormat(FormatJSON) || IsValidFormat("xml") {
+		t.Error("IsValidFormat accepts only csv and json")
+	}
+}
@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Structures figées du schéma initial versionné. Elles reprennent le schéma créé jusqu'ici par
+// AutoMigrate : sur une base existante, l'étape Up ne fait donc qu'ajouter ce qui manque.
+
+type domain0001 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Host      string `gorm:"uniqueIndex;size:255;not null"`
+	BaseURL   string `gorm:"size:255;not null"`
+	CreatedAt time.Time
+}
+
+func (domain0001) TableName() string { return "domains" }
+
+type counter0001 struct {
+	Name  string `gorm:"primaryKey;size:50"`
+	Value uint64 `gorm:"not null;default:0"`
+}
+
+func (counter0001) TableName() string { return "counters" }
+
+type utm0001 struct {
+	Source   string `gorm:"size:100;index"`
+	Medium   string `gorm:"size:100;index"`
+	Campaign string `gorm:"size:100;index"`
+	Term     string `gorm:"size:100"`
+	Content  string `gorm:"size:100"`
+}
+
+type link0001 struct {
+	ID             uint    `gorm:"primaryKey"`
+	DomainID       uint    `gorm:"not null;default:0;uniqueIndex:idx_links_domain_code;index:idx_links_dedupe"`
+	ShortCode      string  `gorm:"uniqueIndex:idx_links_domain_code;size:32;not null"`
+	LongURL        string  `gorm:"not null"`
+	CanonicalURL   string  `gorm:"size:2048;not null;default:'';index:idx_links_dedupe"`
+	Owner          string  `gorm:"size:100;not null;default:'';index:idx_links_dedupe"`
+	MaxUses        int     `gorm:"not null;default:0"`
+	UseCount       int     `gorm:"not null;default:0"`
+	RedirectStatus int     `gorm:"not null;default:302"`
+	Interstitial   bool    `gorm:"not null;default:false"`
+	PassQuery      bool    `gorm:"not null;default:false"`
+	QueryConflict  string  `gorm:"size:16;not null;default:'link'"`
+	PassPath       bool    `gorm:"not null;default:false"`
+	StickyVariant  bool    `gorm:"not null;default:false"`
+	UTM            utm0001 `gorm:"embedded;embeddedPrefix:utm_"`
+	CreatedAt      time.Time
+
+	TargetingRules []targetingRule0001 `gorm:"foreignKey:LinkID;constraint:OnDelete:CASCADE"`
+	Variants       []linkVariant0001   `gorm:"foreignKey:LinkID;constraint:OnDelete:CASCADE"`
+}
+
+func (link0001) TableName() string { return "links" }
+
+type targetingRule0001 struct {
+	ID        uint   `gorm:"primaryKey"`
+	LinkID    uint   `gorm:"index;not null"`
+	Position  int    `gorm:"not null"`
+	OS        string `gorm:"size:20"`
+	Device    string `gorm:"size:20"`
+	Language  string `gorm:"size:35"`
+	Countries string `gorm:"size:100"`
+	TargetURL string `gorm:"not null"`
+}
+
+func (targetingRule0001) TableName() string { return "targeting_rules" }
+
+type linkVariant0001 struct {
+	ID        uint   `gorm:"primaryKey"`
+	LinkID    uint   `gorm:"index;not null"`
+	Name      string `gorm:"size:50"`
+	TargetURL string `gorm:"not null"`
+	Weight    int    `gorm:"not null"`
+}
+
+func (linkVariant0001) TableName() string { return "link_variants" }
+
+type click0001 struct {
+	ID        uint     `gorm:"primaryKey"`
+	LinkID    uint     `gorm:"index"`
+	Link      link0001 `gorm:"foreignKey:LinkID"`
+	Timestamp time.Time
+	UserAgent string `gorm:"size:255"`
+	IPAddress string `gorm:"size:50"`
+	RuleID    *uint  `gorm:"index"`
+	VariantID *uint  `gorm:"index"`
+	Country   string `gorm:"size:2;index"`
+	Region    string `gorm:"size:100"`
+	City      string `gorm:"size:100"`
+	ASN       uint
+	ASOrg     string `gorm:"size:255"`
+}
+
+func (click0001) TableName() string { return "clicks" }
+
+// initialSchema crée les tables des domaines, compteurs, liens, règles de ciblage, variantes et clics.
+// Les bases créées avant les migrations versionnées portaient un index d'unicité globale
+// des codes courts, remplacé par l'unicité par domaine (idx_links_domain_code).
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&domain0001{}, &counter0001{}, &link0001{}, &targetingRule0001{}, &linkVariant0001{}, &click0001{}); err != nil {
+			return err
+		}
+		if tx.Migrator().HasIndex(&link0001{}, "idx_links_short_code") {
+			return tx.Migrator().DropIndex(&link0001{}, "idx_links_short_code")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&click0001{}, &linkVariant0001{}, &targetingRule0001{}, &link0001{}, &counter0001{}, &domain0001{})
+	},
+}
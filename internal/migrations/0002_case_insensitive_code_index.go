@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// caseInsensitiveCodeIndex ajoute l'index sur LOWER(short_code) utilisé par la recherche
+// insensible à la casse (shortcode.case_insensitive). GORM ne sait pas décrire un index
+// sur une expression : il est créé en SQL, compatible SQLite et PostgreSQL.
+var caseInsensitiveCodeIndex = Migration{
+	Version: 2,
+	Name:    "case_insensitive_code_index",
+	Up: func(tx *gorm.DB) error {
+		return tx.Exec("CREATE INDEX IF NOT EXISTS idx_links_domain_code_lower ON links (domain_id, LOWER(short_code))").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("DROP INDEX IF EXISTS idx_links_domain_code_lower").Error
+	},
+}
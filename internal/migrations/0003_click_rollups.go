@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Structures figées des tables d'agrégats de clics.
+
+type clickRollup0003 struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"not null"`
+	Bucket    time.Time `gorm:"not null"`
+	Country   string    `gorm:"size:2;not null;default:''"`
+	Region    string    `gorm:"size:100;not null;default:''"`
+	City      string    `gorm:"size:100;not null;default:''"`
+	VariantID uint      `gorm:"not null;default:0"`
+	Clicks    int       `gorm:"not null"`
+}
+
+type rollupWatermark0003 struct {
+	Granularity string    `gorm:"primaryKey;size:16"`
+	Until       time.Time `gorm:"not null"`
+}
+
+func (rollupWatermark0003) TableName() string { return "rollup_watermarks" }
+
+// rollupTables0003 sont les tables d'agrégats, qui partagent la même structure.
+// Leurs index sont créés en SQL, les noms d'index devant être uniques dans la base.
+var rollupTables0003 = []string{"click_rollups_hourly", "click_rollups_daily"}
+
+// clickRollups ajoute les agrégats horaires et journaliers des clics (par lien, localisation et variante)
+// et la table des positions d'agrégation, lues par les statistiques à la place de la table des clics.
+var clickRollups = Migration{
+	Version: 3,
+	Name:    "click_rollups",
+	Up: func(tx *gorm.DB) error {
+		for _, table := range rollupTables0003 {
+			if err := tx.Table(table).AutoMigrate(&clickRollup0003{}); err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_" + table + "_link_bucket ON " + table + " (link_id, bucket)").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_" + table + "_bucket ON " + table + " (bucket)").Error; err != nil {
+				return err
+			}
+		}
+		// L'agrégation parcourt les clics par période
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_clicks_timestamp ON clicks (timestamp)").Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(&rollupWatermark0003{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Exec("DROP INDEX IF EXISTS idx_clicks_timestamp").Error; err != nil {
+			return err
+		}
+		for _, table := range rollupTables0003 {
+			if err := tx.Migrator().DropTable(table); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropTable(&rollupWatermark0003{})
+	},
+}
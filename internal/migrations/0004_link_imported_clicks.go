@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Structure figée de la colonne ajoutée à la table links.
+type link0004 struct {
+	ImportedClicks int `gorm:"not null;default:0"`
+}
+
+func (link0004) TableName() string { return "links" }
+
+// linkImportedClicks ajoute aux liens le nombre de clics historiques repris d'un autre service lors d'un import.
+var linkImportedClicks = Migration{
+	Version: 4,
+	Name:    "link_imported_clicks",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&link0004{}, "ImportedClicks")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&link0004{}, "ImportedClicks")
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Structure figée de la table des clés d'idempotence.
+type idempotencyKey0005 struct {
+	Scope       string `gorm:"primaryKey;size:100"`
+	Key         string `gorm:"primaryKey;column:idempotency_key;size:255"`
+	RequestHash string `gorm:"size:64;not null"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null;index:idx_idempotency_keys_expires_at"`
+}
+
+func (idempotencyKey0005) TableName() string { return "idempotency_keys" }
+
+// idempotencyKeys ajoute la table des réponses enregistrées pour les requêtes de création
+// portant un en-tête Idempotency-Key.
+var idempotencyKeys = Migration{
+	Version: 5,
+	Name:    "idempotency_keys",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&idempotencyKey0005{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&idempotencyKey0005{})
+	},
+}
@@ -0,0 +1,196 @@
+// Package migrations gère l'évolution versionnée du schéma de la base de données.
+//
+// Chaque migration possède un numéro de version croissant, une étape Up et une étape Down.
+// Les versions appliquées sont enregistrées dans la table schema_migrations.
+// Une migration ne doit jamais être modifiée une fois publiée : elle décrit le schéma
+// à l'aide de ses propres structures figées, indépendantes de internal/models.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaMismatch est renvoyée lorsque la version du schéma ne correspond pas à celle attendue par le binaire.
+var ErrSchemaMismatch = errors.New("database schema version mismatch")
+
+// errDryRun provoque l'annulation de la transaction d'un essai à blanc.
+var errDryRun = errors.New("dry run")
+
+// Migration décrit une évolution du schéma et la façon de l'annuler.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration est une version appliquée, enregistrée dans la table schema_migrations.
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"` // Numéro de version de la migration
+	Name      string    `gorm:"size:100;not null"`              // Nom de la migration
+	AppliedAt time.Time `gorm:"not null"`                       // Horodatage de l'application
+}
+
+// MigrationStatus indique si une migration connue est appliquée.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// all est la liste ordonnée des migrations. Les nouvelles migrations s'ajoutent à la fin.
+var all = []Migration{
+	initialSchema,
+	caseInsensitiveCodeIndex,
+	clickRollups,
+	linkImportedClicks,
+	idempotencyKeys,
+}
+
+// Latest renvoie la version du schéma attendue par ce binaire.
+func Latest() uint {
+	return all[len(all)-1].Version
+}
+
+// CurrentVersion renvoie la version la plus élevée appliquée à la base (0 si aucune).
+func CurrentVersion(db *gorm.DB) (uint, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	var current uint
+	for version := range applied {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// CheckVersion renvoie ErrSchemaMismatch si la base n'est pas exactement à la version attendue.
+func CheckVersion(db *gorm.DB) error {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current < Latest():
+		return fmt.Errorf("%w: database is at version %d, expected %d (run 'url-shortener migrate up')", ErrSchemaMismatch, current, Latest())
+	case current > Latest():
+		return fmt.Errorf("%w: database is at version %d, newer than the %d supported by this binary", ErrSchemaMismatch, current, Latest())
+	}
+	return nil
+}
+
+// Status renvoie l'état de toutes les migrations connues, dans l'ordre.
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(all))
+	for i, migration := range all {
+		record, ok := applied[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: ok, AppliedAt: record.AppliedAt}
+	}
+	return statuses, nil
+}
+
+// Up applique les migrations en attente, chacune dans sa propre transaction, et les renvoie.
+// En essai à blanc (dryRun), elles sont exécutées dans une transaction unique puis annulées.
+func Up(db *gorm.DB, dryRun bool) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range all {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, run(db, pending, dryRun, func(tx *gorm.DB, migration Migration) error {
+		if err := migration.Up(tx); err != nil {
+			return fmt.Errorf("migration %d (%s) up: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+	})
+}
+
+// Down annule les steps dernières migrations appliquées, de la plus récente à la plus ancienne, et les renvoie.
+func Down(db *gorm.DB, steps int, dryRun bool) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("number of migrations to roll back must be positive, got %d", steps)
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []Migration
+	for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if _, ok := applied[all[i].Version]; ok {
+			reverted = append(reverted, all[i])
+		}
+	}
+
+	return reverted, run(db, reverted, dryRun, func(tx *gorm.DB, migration Migration) error {
+		if err := migration.Down(tx); err != nil {
+			return fmt.Errorf("migration %d (%s) down: %w", migration.Version, migration.Name, err)
+		}
+		return tx.Delete(&SchemaMigration{}, migration.Version).Error
+	})
+}
+
+// run exécute step pour chaque migration. Hors essai à blanc, chaque migration a sa transaction :
+// une erreur laisse la base à la dernière version réussie. En essai à blanc, tout est annulé.
+func run(db *gorm.DB, migrations []Migration, dryRun bool, step func(tx *gorm.DB, migration Migration) error) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+	if dryRun {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&SchemaMigration{}); err != nil {
+				return err
+			}
+			for _, migration := range migrations {
+				if err := step(tx, migration); err != nil {
+					return err
+				}
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			return nil
+		}
+		return err
+	}
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	for _, migration := range migrations {
+		if err := db.Transaction(func(tx *gorm.DB) error { return step(tx, migration) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedMigrations renvoie les versions enregistrées dans schema_migrations (vide si la table n'existe pas encore).
+func appliedMigrations(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	applied := make(map[uint]SchemaMigration)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+	var records []SchemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,209 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tables sont les tables créées par l'ensemble des migrations.
+var tables = []string{
+	"domains", "counters", "links", "targeting_rules", "link_variants", "clicks",
+	"click_rollups_hourly", "click_rollups_daily", "rollup_watermarks", "idempotency_keys",
+}
+
+// forEachDatabase exécute test sur une base SQLite vide et, si DATABASE_URL est définie, sur PostgreSQL.
+func forEachDatabase(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestDB(t, sqlite.Open(filepath.Join(t.TempDir(), "test.db"))))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("DATABASE_URL")
+		if dsn == "" {
+			t.Skip("DATABASE_URL is not set")
+		}
+		db := openTestDB(t, postgres.Open(dsn))
+		// La base doit être jetable : toutes ses migrations sont annulées avant le test
+		if _, err := Down(db, len(all), false); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		test(t, db)
+	})
+}
+
+func openTestDB(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func assertVersion(t *testing.T, db *gorm.DB, want uint) {
+	t.Helper()
+	version, err := CurrentVersion(db)
+	if err != nil {
+		t.Fatalf("CurrentVersion: %v", err)
+	}
+	if version != want {
+		t.Fatalf("CurrentVersion = %d, want %d", version, want)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		assertVersion(t, db, 0)
+		if err := CheckVersion(db); !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("CheckVersion on an empty database = %v, want ErrSchemaMismatch", err)
+		}
+
+		applied, err := Up(db, false)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if len(applied) != len(all) {
+			t.Errorf("Up applied %d migrations, want %d", len(applied), len(all))
+		}
+		assertVersion(t, db, Latest())
+		if err := CheckVersion(db); err != nil {
+			t.Errorf("CheckVersion: %v", err)
+		}
+		for _, table := range tables {
+			if !db.Migrator().HasTable(table) {
+				t.Errorf("table %s is missing after Up", table)
+			}
+		}
+		if applied, err := Up(db, false); err != nil || len(applied) != 0 {
+			t.Errorf("second Up = %d migrations, %v, want none", len(applied), err)
+		}
+
+		// Annulation une par une, de la plus récente à la plus ancienne
+		for version := Latest(); version > 0; version-- {
+			reverted, err := Down(db, 1, false)
+			if err != nil {
+				t.Fatalf("Down from version %d: %v", version, err)
+			}
+			if len(reverted) != 1 || reverted[0].Version != version {
+				t.Fatalf("Down from version %d reverted %v", version, reverted)
+			}
+			assertVersion(t, db, version-1)
+		}
+		for _, table := range tables {
+			if db.Migrator().HasTable(table) {
+				t.Errorf("table %s remains after every migration was rolled back", table)
+			}
+		}
+		if reverted, err := Down(db, 1, false); err != nil || len(reverted) != 0 {
+			t.Errorf("Down on an empty schema = %v, %v, want nothing", reverted, err)
+		}
+
+		// Le schéma se recrée après une annulation complète
+		if _, err := Up(db, false); err != nil {
+			t.Fatalf("Up after Down: %v", err)
+		}
+		assertVersion(t, db, Latest())
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		pending, err := Up(db, true)
+		if err != nil {
+			t.Fatalf("Up dry run: %v", err)
+		}
+		if len(pending) != len(all) {
+			t.Errorf("Up dry run listed %d migrations, want %d", len(pending), len(all))
+		}
+		assertVersion(t, db, 0)
+		if db.Migrator().HasTable("links") {
+			t.Error("Up dry run created tables")
+		}
+
+		if _, err := Up(db, false); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if _, err := Down(db, 2, true); err != nil {
+			t.Fatalf("Down dry run: %v", err)
+		}
+		assertVersion(t, db, Latest())
+	})
+}
+
+func TestStatus(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := Up(db, false); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if _, err := Down(db, 1, false); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		statuses, err := Status(db)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		if len(statuses) != len(all) {
+			t.Fatalf("Status returned %d migrations, want %d", len(statuses), len(all))
+		}
+		for i, status := range statuses {
+			if applied := i < len(all)-1; status.Applied != applied {
+				t.Errorf("migration %d applied = %v, want %v", status.Version, status.Applied, applied)
+			}
+		}
+	})
+}
+
+func TestDownRejectsNonPositiveSteps(t *testing.T) {
+	if _, err := Down(nil, 0, false); err == nil {
+		t.Error("Down accepted 0 steps")
+	}
+}
+
+func TestMigrationList(t *testing.T) {
+	names := make(map[string]bool)
+	for i, migration := range all {
+		if migration.Version != uint(i+1) {
+			t.Errorf("migration %d has version %d, want versions numbered from 1 without gaps", i, migration.Version)
+		}
+		if migration.Name == "" || names[migration.Name] {
+			t.Errorf("migration %d has an empty or duplicate name %q", migration.Version, migration.Name)
+		}
+		names[migration.Name] = true
+		if migration.Up == nil || migration.Down == nil {
+			t.Errorf("migration %d (%s) cannot be applied and rolled back", migration.Version, migration.Name)
+		}
+	}
+	if Latest() != uint(len(all)) {
+		t.Errorf("Latest = %d, want %d", Latest(), len(all))
+	}
+}
+
+func TestCheckVersionRejectsNewerSchema(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := Up(db, false); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		// Version appliquée par un binaire plus récent
+		newer := SchemaMigration{Version: Latest() + 1, Name: "from_the_future", AppliedAt: time.Now()}
+		if err := db.Create(&newer).Error; err != nil {
+			t.Fatalf("insert newer version: %v", err)
+		}
+		t.Cleanup(func() { db.Delete(&newer) })
+
+		if err := CheckVersion(db); !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("CheckVersion on a newer schema = %v, want ErrSchemaMismatch", err)
+		}
+	})
+}
@@ -7,15 +7,25 @@ type Click struct {
 	ID        uint      `gorm:"primaryKey"`        // Clé primaire
 	LinkID    uint      `gorm:"index"`             // Clé étrangère vers la table 'links', indexée pour des requêtes efficaces
 	Link      Link      `gorm:"foreignKey:LinkID"` // Relation GORM: indique que LinkID est une FK vers le champ ID de Link
-	Timestamp time.Time // Horodatage précis du clic
-	UserAgent string    `gorm:"size:255"` // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
-	IPAddress string    `gorm:"size:50"`  // Adresse IP de l'utilisateur
+	Timestamp time.Time `gorm:"index"`             // Horodatage précis du clic, indexé pour l'agrégation et la rétention
+	UserAgent string    `gorm:"size:255"`          // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
+	IPAddress string    `gorm:"size:50"`           // Adresse IP de l'utilisateur
+	RuleID    *uint     `gorm:"index"`             // Règle de ciblage ayant déterminé la destination (nil si URL longue par défaut)
+	VariantID *uint     `gorm:"index"`             // Variante A/B choisie pour cette visite (nil si aucune)
+	Country   string    `gorm:"size:2;index"`      // Code pays ISO (enrichissement GeoIP, vide si inconnu)
+	Region    string    `gorm:"size:100"`          // Région / subdivision principale
+	City      string    `gorm:"size:100"`          // Ville
+	ASN       uint      // Numéro de système autonome du réseau d'origine
+	ASOrg     string    `gorm:"size:255"` // Organisation propriétaire de l'AS
 }
 
 // ClickEvent représente un événement de clic brut, destiné à être passé via un channel
 type ClickEvent struct {
-	LinkID    uint      // ID du lien cliqué
-	Timestamp time.Time // Horodatage du clic
-	UserAgent string    // User-Agent du navigateur
-	IPAddress string    // Adresse IP de l'utilisateur
+	LinkID     uint      // ID du lien cliqué
+	Timestamp  time.Time // Horodatage du clic
+	UserAgent  string    // User-Agent du navigateur
+	IPAddress  string    // Adresse IP de l'utilisateur
+	RuleID     *uint     // Règle de ciblage appliquée, le cas échéant
+	VariantID  *uint     // Variante A/B choisie, le cas échéant
+	DoNotTrack bool      // Le visiteur refuse le suivi (DNT / Sec-GPC) : ni IP, ni User-Agent, ni localisation
 }
@@ -0,0 +1,8 @@
+package models
+
+// Counter est un compteur nommé persistant, partagé entre toutes les instances du service.
+// Il alimente les stratégies de génération de codes courts séquentielles.
+type Counter struct {
+	Name  string `gorm:"primaryKey;size:50"` // Nom du compteur (ex: short_codes)
+	Value uint64 `gorm:"not null;default:0"` // Dernière valeur attribuée
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DefaultDomainID identifie le domaine par défaut (Server.BaseURL de la configuration).
+// Les liens créés sans domaine personnalisé appartiennent à cet espace de noms.
+const DefaultDomainID uint = 0
+
+// Domain représente un domaine personnalisé servi par l'instance.
+// Chaque domaine possède son propre espace de noms de codes courts.
+type Domain struct {
+	ID        uint      `gorm:"primaryKey"`                    // Clé primaire
+	Host      string    `gorm:"uniqueIndex;size:255;not null"` // Nom d'hôte en minuscules, sans port (ex: go.marque.fr)
+	BaseURL   string    `gorm:"size:255;not null"`             // URL de base des liens courts (ex: https://go.marque.fr)
+	CreatedAt time.Time // Horodatage de la création du domaine
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IdempotencyKey enregistre la réponse d'une requête portant un en-tête Idempotency-Key,
+// pour la renvoyer telle quelle si le client rejoue la même requête (après un délai d'attente, par exemple).
+type IdempotencyKey struct {
+	Scope       string    `gorm:"primaryKey;size:100"`                        // Méthode et route de la requête (ex: "POST /api/v1/links")
+	Key         string    `gorm:"primaryKey;column:idempotency_key;size:255"` // Valeur de l'en-tête Idempotency-Key
+	RequestHash string    `gorm:"size:64;not null"`                           // Empreinte SHA-256 du corps de la requête d'origine
+	StatusCode  int       `gorm:"not null;default:0"`                         // Statut HTTP de la réponse enregistrée (0 = requête en cours)
+	Response    []byte    // Corps de la réponse enregistrée
+	CreatedAt   time.Time // Horodatage de la première requête
+	ExpiresAt   time.Time `gorm:"not null;index"` // Au-delà, la clé est oubliée et peut être réutilisée
+}
+
+// InProgress indique si la requête d'origine est encore en cours de traitement.
+func (k *IdempotencyKey) InProgress() bool {
+	return k.StatusCode == 0
+}
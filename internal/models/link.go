@@ -1,11 +1,79 @@
 package models
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultRedirectStatus est le code HTTP utilisé lorsque le lien ne précise pas de code de redirection.
+const DefaultRedirectStatus = http.StatusFound
+
+// Politiques de résolution des conflits entre les paramètres de requête entrants et ceux de l'URL longue.
+const (
+	QueryConflictLink    = "link"    // Les paramètres de l'URL longue sont conservés, les entrants en conflit sont ignorés
+	QueryConflictRequest = "request" // Les paramètres entrants remplacent ceux de l'URL longue
+	QueryConflictAppend  = "append"  // Les deux valeurs sont conservées
+)
 
 // Link représente un lien raccourci dans la base de données.
 type Link struct {
-	ID        uint      `gorm:"primaryKey"`                   // Clé primaire
-	ShortCode string    `gorm:"uniqueIndex;size:10;not null"` // Code court unique, indexé, max 10 caractères
-	LongURL   string    `gorm:"not null"`                     // URL longue, ne peut pas être null
-	CreatedAt time.Time // Horodatage de la création du lien
+	ID             uint      `gorm:"primaryKey"`                                                                  // Clé primaire
+	DomainID       uint      `gorm:"not null;default:0;uniqueIndex:idx_links_domain_code;index:idx_links_dedupe"` // Domaine du lien (0 = domaine par défaut)
+	ShortCode      string    `gorm:"uniqueIndex:idx_links_domain_code;size:32;not null"`                          // Code court unique par domaine, max 32 caractères
+	LongURL        string    `gorm:"not null"`                                                                    // URL longue, ne peut pas être null
+	CanonicalURL   string    `gorm:"size:2048;not null;default:'';index:idx_links_dedupe"`                        // Forme canonique de l'URL longue, pour la déduplication
+	Owner          string    `gorm:"size:100;not null;default:'';index:idx_links_dedupe"`                         // Propriétaire du lien (vide = anonyme)
+	MaxUses        int       `gorm:"not null;default:0"`                                                          // Nombre maximal d'utilisations autorisées (0 = illimité)
+	UseCount       int       `gorm:"not null;default:0"`                                                          // Nombre d'utilisations consommées, incrémenté atomiquement à chaque redirection
+	RedirectStatus int       `gorm:"not null;default:302"`                                                        // Code HTTP de redirection propre au lien (301, 302, 307 ou 308)
+	Interstitial   bool      `gorm:"not null;default:false"`                                                      // Affiche une page intermédiaire "Vous allez quitter..." avant la redirection
+	PassQuery      bool      `gorm:"not null;default:false"`                                                      // Transmet les paramètres de requête entrants à l'URL longue
+	QueryConflict  string    `gorm:"size:16;not null;default:'link'"`                                             // Politique de conflit des paramètres (link, request ou append)
+	PassPath       bool      `gorm:"not null;default:false"`                                                      // Ajoute les segments de chemin supplémentaires à l'URL longue
+	StickyVariant  bool      `gorm:"not null;default:false"`                                                      // Conserve la même variante A/B pour un visiteur (via cookie)
+	UTM            UTMParams `gorm:"embedded;embeddedPrefix:utm_"`                                                // Paramètres UTM appliqués à la redirection (colonnes utm_source, utm_medium, ...)
+	ImportedClicks int       `gorm:"not null;default:0"`                                                          // Clics historiques repris d'un autre service lors d'un import, ajoutés aux statistiques
+	CreatedAt      time.Time // Horodatage de la création du lien
+
+	TargetingRules []TargetingRule `gorm:"foreignKey:LinkID;constraint:OnDelete:CASCADE"` // Règles de redirection ciblée, évaluées dans l'ordre de Position
+	Variants       []LinkVariant   `gorm:"foreignKey:LinkID;constraint:OnDelete:CASCADE"` // Destinations pondérées pour les tests A/B
+}
+
+// IsValidRedirectStatus indique si le code HTTP fait partie des codes de redirection supportés.
+func IsValidRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// EffectiveRedirectStatus renvoie le code de redirection à utiliser pour ce lien,
+// avec un repli sur DefaultRedirectStatus pour les liens créés avant l'ajout de ce réglage.
+func (l *Link) EffectiveRedirectStatus() int {
+	if IsValidRedirectStatus(l.RedirectStatus) {
+		return l.RedirectStatus
+	}
+	return DefaultRedirectStatus
+}
+
+// IsLimited indique si le lien a un nombre d'utilisations limité (lien à usage unique ou à N usages).
+func (l *Link) IsLimited() bool {
+	return l.MaxUses > 0
+}
+
+// IsExhausted indique si un lien limité a consommé toutes ses utilisations.
+// Un lien épuisé n'est plus redirigé (410) mais n'est pas supprimé : ses statistiques restent consultables
+// et son code reste réservé, pour ne jamais envoyer à un autre lien les visites destinées à celui-ci.
+func (l *Link) IsExhausted() bool {
+	return l.IsLimited() && l.UseCount >= l.MaxUses
+}
+
+// IsValidQueryConflict indique si la politique de conflit des paramètres de requête est supportée.
+func IsValidQueryConflict(policy string) bool {
+	switch policy {
+	case QueryConflictLink, QueryConflictRequest, QueryConflictAppend:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,12 @@
+package models
+
+// LinkVariant est une destination alternative d'un lien pour les tests A/B et la rotation pondérée.
+// Lorsqu'un lien a des variantes, chaque visite (hors règle de ciblage) est envoyée vers l'une d'elles,
+// choisie aléatoirement proportionnellement à son poids.
+type LinkVariant struct {
+	ID        uint   `gorm:"primaryKey"`     // Clé primaire
+	LinkID    uint   `gorm:"index;not null"` // Clé étrangère vers la table 'links'
+	Name      string `gorm:"size:50"`        // Nom de la variante (ex: A, B, nouvelle-landing)
+	TargetURL string `gorm:"not null"`       // URL de destination de la variante
+	Weight    int    `gorm:"not null"`       // Poids relatif de la variante (0 = désactivée)
+}
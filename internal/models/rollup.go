@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Granularités des agrégats de clics, et tables correspondantes.
+const (
+	RollupHourly = "hourly"
+	RollupDaily  = "daily"
+
+	HourlyRollupTable = "click_rollups_hourly"
+	DailyRollupTable  = "click_rollups_daily"
+
+	// RollupPurged désigne, parmi les positions d'agrégation, la fin de la période dont les clics bruts ont été purgés :
+	// les clics bruts antérieurs sont des clics tardifs, enregistrés après la purge de leur heure.
+	RollupPurged = "purged"
+)
+
+// ClickRollup est le nombre de clics d'un lien sur une période (heure ou jour, en UTC),
+// pour une combinaison de dimensions (localisation et variante A/B).
+// La même structure est stockée dans click_rollups_hourly et click_rollups_daily.
+type ClickRollup struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"not null"`                     // Lien cliqué
+	Bucket    time.Time `gorm:"not null"`                     // Début de la période, en UTC
+	Country   string    `gorm:"size:2;not null;default:''"`   // Code pays ISO (vide si inconnu)
+	Region    string    `gorm:"size:100;not null;default:''"` // Région / subdivision principale
+	City      string    `gorm:"size:100;not null;default:''"` // Ville
+	VariantID uint      `gorm:"not null;default:0"`           // Variante A/B (0 = aucune)
+	Clicks    int       `gorm:"not null"`                     // Nombre de clics
+}
+
+// RollupWatermark indique jusqu'où les clics ont été agrégés pour une granularité :
+// les périodes antérieures à Until sont complètes dans la table d'agrégats correspondante.
+type RollupWatermark struct {
+	Granularity string    `gorm:"primaryKey;size:16"`
+	Until       time.Time `gorm:"not null"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// Systèmes d'exploitation reconnus par les règles de ciblage.
+const (
+	OSIOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSOther   = "other"
+)
+
+// Classes d'appareils reconnues par les règles de ciblage.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// TargetingRule est une règle de redirection ciblée d'un lien.
+// Les règles d'un lien sont évaluées dans l'ordre de Position : la première dont toutes les conditions
+// renseignées correspondent au visiteur fournit l'URL de destination. Une condition vide correspond à tout.
+type TargetingRule struct {
+	ID        uint   `gorm:"primaryKey"`     // Clé primaire
+	LinkID    uint   `gorm:"index;not null"` // Clé étrangère vers la table 'links'
+	Position  int    `gorm:"not null"`       // Ordre d'évaluation de la règle (croissant)
+	OS        string `gorm:"size:20"`        // Système d'exploitation (ios, android, windows, macos, linux)
+	Device    string `gorm:"size:20"`        // Classe d'appareil (mobile, tablet, desktop, bot)
+	Language  string `gorm:"size:35"`        // Langue préférée issue d'Accept-Language (ex: fr ou fr-CA)
+	Countries string `gorm:"size:100"`       // Codes pays ISO séparés par des virgules, résolus par GeoIP (ex: FR,BE)
+	TargetURL string `gorm:"not null"`       // URL de destination si la règle correspond
+}
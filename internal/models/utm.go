@@ -0,0 +1,35 @@
+package models
+
+import "net/url"
+
+// UTMParams regroupe les paramètres de campagne UTM stockés séparément sur un lien.
+// Ils sont ajoutés à l'URL de destination au moment de la redirection et servent de dimensions de filtre dans les statistiques.
+type UTMParams struct {
+	Source   string `gorm:"size:100;index"` // utm_source (ex: newsletter)
+	Medium   string `gorm:"size:100;index"` // utm_medium (ex: email)
+	Campaign string `gorm:"size:100;index"` // utm_campaign (ex: soldes_ete)
+	Term     string `gorm:"size:100"`       // utm_term (mots-clés payants)
+	Content  string `gorm:"size:100"`       // utm_content (variante de contenu)
+}
+
+// IsEmpty indique si aucun paramètre UTM n'est renseigné.
+func (u UTMParams) IsEmpty() bool {
+	return u == UTMParams{}
+}
+
+// Values renvoie les paramètres UTM renseignés sous forme de paramètres de requête (utm_source, utm_medium, ...).
+func (u UTMParams) Values() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestUTMParams(t *testing.T) {
+	if !(UTMParams{}).IsEmpty() {
+		t.Error("zero UTMParams is not empty")
+	}
+
+	utm := UTMParams{Source: "newsletter", Campaign: "soldes ete"}
+	if utm.IsEmpty() {
+		t.Error("UTMParams with a source is empty")
+	}
+	if got, want := utm.Values().Encode(), "utm_campaign=soldes+ete&utm_source=newsletter"; got != want {
+		t.Errorf("Values = %q, want %q", got, want)
+	}
+	if got := (UTMParams{}).Values(); len(got) != 0 {
+		t.Errorf("Values of empty UTMParams = %v, want none", got)
+	}
+}
@@ -53,6 +53,11 @@ func (m *UrlMonitor) checkUrls() {
 	}
 	
 	for _, link := range links {
+		// Les liens à usage limité épuisés ne sont plus servis : inutile de les surveiller.
+		if link.IsExhausted() {
+			continue
+		}
+
 		currentState :=  m.isUrlAccessible(link.LongURL)
 
 		// Protéger l'accès à la map 'knownStates' car 'checkUrls' peut être exécuté concurremment
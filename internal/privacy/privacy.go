@@ -0,0 +1,136 @@
+// Package privacy anonymise les adresses IP des clics selon le mode configuré (section privacy),
+// une fois l'enrichissement GeoIP effectué.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Modes d'enregistrement des adresses IP.
+const (
+	ModeFull     = "full"     // Adresse complète (comportement historique)
+	ModeTruncate = "truncate" // Adresse tronquée à un préfixe réseau
+	ModeHash     = "hash"     // Empreinte salée, dont le sel change chaque jour (UTC)
+	ModeDrop     = "drop"     // Aucune adresse enregistrée
+)
+
+// hashLength est le nombre de caractères hexadécimaux conservés de l'empreinte d'une adresse.
+const hashLength = 32
+
+// ErrInvalidOptions est renvoyée lorsque la configuration de confidentialité est invalide.
+var ErrInvalidOptions = errors.New("invalid privacy options")
+
+// Options est la configuration de confidentialité des clics (section privacy).
+type Options struct {
+	IPMode     string `mapstructure:"ip_mode"`     // full, truncate, hash ou drop
+	IPv4Prefix int    `mapstructure:"ipv4_prefix"` // truncate : bits conservés d'une adresse IPv4
+	IPv6Prefix int    `mapstructure:"ipv6_prefix"` // truncate : bits conservés d'une adresse IPv6
+	HashSecret string `mapstructure:"hash_secret"` // hash : secret des sels quotidiens (aléatoire si vide)
+	HonorDNT   bool   `mapstructure:"honor_dnt"`   // Respecte les en-têtes DNT et Sec-GPC
+}
+
+// Anonymizer transforme les adresses IP avant leur enregistrement.
+type Anonymizer struct {
+	mode       string
+	ipv4Prefix int
+	ipv6Prefix int
+	secret     []byte
+}
+
+// New crée un Anonymizer à partir des options. En mode hash sans secret, un secret aléatoire est tiré :
+// les empreintes ne sont alors comparables qu'au sein d'une même exécution du serveur.
+func New(opts Options) (*Anonymizer, error) {
+	a := &Anonymizer{mode: opts.IPMode, ipv4Prefix: opts.IPv4Prefix, ipv6Prefix: opts.IPv6Prefix, secret: []byte(opts.HashSecret)}
+	switch opts.IPMode {
+	case "":
+		a.mode = ModeFull
+	case ModeFull, ModeDrop, ModeHash:
+	case ModeTruncate:
+		if opts.IPv4Prefix < 0 || opts.IPv4Prefix > 32 || opts.IPv6Prefix < 0 || opts.IPv6Prefix > 128 {
+			return nil, fmt.Errorf("%w: ipv4_prefix must be within 0-32 and ipv6_prefix within 0-128", ErrInvalidOptions)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown ip_mode %q (expected %s, %s, %s or %s)", ErrInvalidOptions, opts.IPMode, ModeFull, ModeTruncate, ModeHash, ModeDrop)
+	}
+	if a.mode == ModeHash && len(a.secret) == 0 {
+		a.secret = make([]byte, 32)
+		if _, err := rand.Read(a.secret); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Mode renvoie le mode d'enregistrement des adresses. Un Anonymizer nil conserve les adresses complètes.
+func (a *Anonymizer) Mode() string {
+	if a == nil {
+		return ModeFull
+	}
+	return a.mode
+}
+
+// Anonymize renvoie la forme enregistrée de l'adresse ip pour un clic survenu à l'instant at.
+func (a *Anonymizer) Anonymize(ip string, at time.Time) string {
+	switch a.Mode() {
+	case ModeDrop:
+		return ""
+	case ModeTruncate:
+		return Truncate(ip, a.ipv4Prefix, a.ipv6Prefix)
+	case ModeHash:
+		if ip == "" {
+			return ""
+		}
+		return a.hash(ip, at)
+	}
+	return ip
+}
+
+// StoredForms renvoie les valeurs sous lesquelles l'adresse ip a pu être enregistrée entre from et until :
+// l'adresse complète et, en mode hash, son empreinte de chaque jour de la période.
+// Les adresses tronquées, partagées par tout un réseau, ne désignent plus une personne et n'en font pas partie.
+func (a *Anonymizer) StoredForms(ip string, from, until time.Time) []string {
+	forms := []string{ip}
+	if a.Mode() != ModeHash {
+		return forms
+	}
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(until); day = day.Add(24 * time.Hour) {
+		forms = append(forms, a.hash(ip, day))
+	}
+	return forms
+}
+
+// hash calcule l'empreinte de l'adresse avec le sel du jour (UTC) de l'instant at.
+func (a *Anonymizer) hash(ip string, at time.Time) string {
+	salt := hmac.New(sha256.New, a.secret)
+	salt.Write([]byte(at.UTC().Format("2006-01-02")))
+
+	mac := hmac.New(sha256.New, salt.Sum(nil))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:hashLength]
+}
+
+// Truncate ne conserve que les ipv4Prefix (IPv4) ou ipv6Prefix (IPv6) premiers bits de l'adresse.
+// Une valeur qui n'est pas une adresse IP est supprimée.
+func Truncate(ip string, ipv4Prefix, ipv6Prefix int) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(ipv4Prefix, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(ipv6Prefix, 128)).String()
+}
+
+// OptedOut indique si la requête demande à ne pas être suivie (DNT: 1 ou Sec-GPC: 1).
+func OptedOut(header http.Header) bool {
+	return header.Get("DNT") == "1" || header.Get("Sec-GPC") == "1"
+}
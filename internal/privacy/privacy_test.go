@@ -0,0 +1,140 @@
+package privacy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func mustNew(t *testing.T, opts Options) *Anonymizer {
+	t.Helper()
+	a, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(%+v): %v", opts, err)
+	}
+	return a
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{IPMode: "scramble"},
+		{IPMode: ModeTruncate, IPv4Prefix: 33, IPv6Prefix: 48},
+		{IPMode: ModeTruncate, IPv4Prefix: 24, IPv6Prefix: -1},
+	} {
+		if _, err := New(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("New(%+v) error = %v, want ErrInvalidOptions", opts, err)
+		}
+	}
+	if mode := mustNew(t, Options{}).Mode(); mode != ModeFull {
+		t.Errorf("default mode = %q, want %q", mode, ModeFull)
+	}
+	if mode := (*Anonymizer)(nil).Mode(); mode != ModeFull {
+		t.Errorf("nil anonymizer mode = %q, want %q", mode, ModeFull)
+	}
+}
+
+func TestAnonymizeModes(t *testing.T) {
+	at := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		opts Options
+		ip   string
+		want string
+	}{
+		{Options{IPMode: ModeFull}, "203.0.113.7", "203.0.113.7"},
+		{Options{IPMode: ModeDrop}, "203.0.113.7", ""},
+		{Options{IPMode: ModeTruncate, IPv4Prefix: 24, IPv6Prefix: 48}, "203.0.113.7", "203.0.113.0"},
+		{Options{IPMode: ModeTruncate, IPv4Prefix: 16, IPv6Prefix: 48}, "203.0.113.7", "203.0.0.0"},
+		{Options{IPMode: ModeTruncate, IPv4Prefix: 24, IPv6Prefix: 48}, "2001:db8:abcd:12::1", "2001:db8:abcd::"},
+		{Options{IPMode: ModeTruncate, IPv4Prefix: 24, IPv6Prefix: 48}, "not-an-ip", ""},
+		{Options{IPMode: ModeHash, HashSecret: "s"}, "", ""},
+	}
+	for _, tt := range tests {
+		if got := mustNew(t, tt.opts).Anonymize(tt.ip, at); got != tt.want {
+			t.Errorf("Anonymize(%q) with %+v = %q, want %q", tt.ip, tt.opts, got, tt.want)
+		}
+	}
+	if got := (*Anonymizer)(nil).Anonymize("203.0.113.7", at); got != "203.0.113.7" {
+		t.Errorf("nil anonymizer Anonymize = %q, want the full address", got)
+	}
+}
+
+func TestHashRotatesDaily(t *testing.T) {
+	a := mustNew(t, Options{IPMode: ModeHash, HashSecret: "secret"})
+	morning := time.Date(2024, 5, 10, 0, 30, 0, 0, time.UTC)
+	evening := time.Date(2024, 5, 10, 23, 30, 0, 0, time.UTC)
+	nextDay := time.Date(2024, 5, 11, 0, 30, 0, 0, time.UTC)
+
+	hash := a.Anonymize("203.0.113.7", morning)
+	if len(hash) != hashLength || hash == "203.0.113.7" {
+		t.Fatalf("hash = %q, want %d hex characters", hash, hashLength)
+	}
+	if got := a.Anonymize("203.0.113.7", evening); got != hash {
+		t.Errorf("hash changed within the same UTC day: %q != %q", got, hash)
+	}
+	// Le jour est celui du fuseau UTC, quel que soit le fuseau de l'horodatage
+	if got := a.Anonymize("203.0.113.7", evening.In(time.FixedZone("UTC+2", 2*3600))); got != hash {
+		t.Errorf("hash depends on the timestamp's time zone: %q != %q", got, hash)
+	}
+	if got := a.Anonymize("203.0.113.7", nextDay); got == hash {
+		t.Error("hash did not change on the next UTC day")
+	}
+	if got := a.Anonymize("203.0.113.8", morning); got == hash {
+		t.Error("two addresses share the same hash")
+	}
+
+	other := mustNew(t, Options{IPMode: ModeHash, HashSecret: "other secret"})
+	if got := other.Anonymize("203.0.113.7", morning); got == hash {
+		t.Error("hash does not depend on the secret")
+	}
+	// Sans secret, un secret aléatoire est tiré : deux instances ne produisent pas la même empreinte
+	if mustNew(t, Options{IPMode: ModeHash}).Anonymize("203.0.113.7", morning) == mustNew(t, Options{IPMode: ModeHash}).Anonymize("203.0.113.7", morning) {
+		t.Error("random secrets produced the same hash")
+	}
+}
+
+func TestStoredForms(t *testing.T) {
+	from := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 5, 12, 9, 0, 0, 0, time.UTC)
+
+	for _, mode := range []string{ModeFull, ModeTruncate, ModeDrop} {
+		forms := mustNew(t, Options{IPMode: mode, IPv4Prefix: 24, IPv6Prefix: 48}).StoredForms("203.0.113.7", from, until)
+		if len(forms) != 1 || forms[0] != "203.0.113.7" {
+			t.Errorf("StoredForms in %s mode = %v, want only the full address", mode, forms)
+		}
+	}
+
+	a := mustNew(t, Options{IPMode: ModeHash, HashSecret: "secret"})
+	forms := a.StoredForms("203.0.113.7", from, until)
+	if len(forms) != 4 || forms[0] != "203.0.113.7" {
+		t.Fatalf("StoredForms = %v, want the full address and 3 daily hashes", forms)
+	}
+	// Chaque clic de la période est retrouvé sous l'une des formes, quelle que soit son heure
+	for at := from; !at.After(until); at = at.Add(time.Hour) {
+		stored := a.Anonymize("203.0.113.7", at)
+		found := false
+		for _, form := range forms {
+			found = found || form == stored
+		}
+		if !found {
+			t.Errorf("click at %s stored as %q is not in %v", at, stored, forms)
+		}
+	}
+}
+
+func TestOptedOut(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		want   bool
+	}{
+		{http.Header{}, false},
+		{http.Header{"Dnt": {"1"}}, true},
+		{http.Header{"Dnt": {"0"}}, false},
+		{http.Header{"Sec-Gpc": {"1"}}, true},
+	}
+	for _, tt := range tests {
+		if got := OptedOut(tt.header); got != tt.want {
+			t.Errorf("OptedOut(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package qr dessine le QR code d'une URL courte en PNG ou en SVG, sans dépendance système.
+// Le symbole est calculé par go-qrcode ; le rendu (taille, marge, couleurs) est fait ici,
+// pour placer les modules sur des pixels entiers et produire un SVG vectoriel.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Formats de rendu supportés.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Bornes et valeurs par défaut des options de rendu.
+const (
+	DefaultSize   = 256 // Côté de l'image, en pixels
+	MinSize       = 32
+	MaxSize       = 4096
+	DefaultMargin = 4 // Zone de silence autour du symbole, en modules (4 selon la norme)
+	MaxMargin     = 32
+	DefaultECC    = "M"
+)
+
+var (
+	// ErrInvalidFormat est renvoyée lorsque le format demandé n'est pas supporté.
+	ErrInvalidFormat = errors.New("QR code format must be png or svg")
+	// ErrInvalidECC est renvoyée lorsque le niveau de correction d'erreurs n'est pas L, M, Q ou H.
+	ErrInvalidECC = errors.New("ecc must be one of L, M, Q or H")
+	// ErrInvalidColor est renvoyée lorsqu'une couleur n'est pas au format #rgb ou #rrggbb.
+	ErrInvalidColor = errors.New("color must be a hexadecimal #rgb or #rrggbb value")
+	// ErrSizeTooSmall est renvoyée lorsque l'image PNG est trop petite pour donner au moins un pixel à chaque module.
+	ErrSizeTooSmall = errors.New("size is too small for this QR code")
+)
+
+// eccLevels associe les niveaux de correction d'erreurs de la norme à ceux de go-qrcode.
+var eccLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,     // ~7 % du symbole récupérable
+	"M": qrcode.Medium,  // ~15 %
+	"Q": qrcode.High,    // ~25 %
+	"H": qrcode.Highest, // ~30 %
+}
+
+// Options regroupe les réglages du rendu d'un QR code.
+type Options struct {
+	Format     string      // png ou svg
+	Size       int         // Côté de l'image en pixels (attributs width/height en SVG)
+	Margin     int         // Zone de silence en modules
+	ECC        string      // Niveau de correction d'erreurs : L, M, Q ou H
+	Foreground color.NRGBA // Couleur des modules
+	Background color.NRGBA // Couleur du fond et de la zone de silence
+}
+
+// DefaultOptions renvoie les options par défaut : PNG de 256 pixels, noir sur blanc, correction M.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+		ECC:        DefaultECC,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Validate vérifie les options et met le niveau de correction en majuscules.
+func (o *Options) Validate() error {
+	if !IsValidFormat(o.Format) {
+		return ErrInvalidFormat
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("size must be between %d and %d pixels", MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("margin must be between 0 and %d modules", MaxMargin)
+	}
+	o.ECC = strings.ToUpper(o.ECC)
+	if _, ok := eccLevels[o.ECC]; !ok {
+		return ErrInvalidECC
+	}
+	return nil
+}
+
+// Key identifie un rendu : deux appels avec le même contenu et la même clé produisent la même image.
+func (o Options) Key(content string) string {
+	return fmt.Sprintf("%s|%d|%d|%s|%s|%s|%s", o.Format, o.Size, o.Margin, o.ECC, FormatColor(o.Foreground), FormatColor(o.Background), content)
+}
+
+// IsValidFormat indique si le format de rendu est supporté.
+func IsValidFormat(format string) bool {
+	return format == FormatPNG || format == FormatSVG
+}
+
+// ContentType renvoie le type MIME d'un format de rendu.
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ParseColor lit une couleur hexadécimale #rgb ou #rrggbb (le # est facultatif, il doit être encodé dans une URL).
+func ParseColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// FormatColor écrit une couleur au format #rrggbb.
+func FormatColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Render dessine le QR code de content selon les options, qui doivent avoir été validées.
+func Render(content string, opts Options) ([]byte, error) {
+	code, err := qrcode.New(content, eccLevels[opts.ECC])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	if opts.Format == FormatSVG {
+		return renderSVG(modules, opts), nil
+	}
+	return renderPNG(modules, opts)
+}
+
+// renderPNG dessine chaque module sur un carré entier de pixels, le symbole étant centré dans l'image :
+// un module étiré sur un nombre fractionnaire de pixels brouillerait la lecture.
+func renderPNG(modules [][]bool, opts Options) ([]byte, error) {
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		return nil, fmt.Errorf("%w (at least %d pixels)", ErrSizeTooSmall, total)
+	}
+	offset := (opts.Size-total*scale)/2 + opts.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{opts.Background, opts.Foreground})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				line := img.Pix[(offset+y*scale+py)*img.Stride:]
+				for px := 0; px < scale; px++ {
+					line[offset+x*scale+px] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG trace les modules sombres en un seul chemin, une série de modules consécutifs d'une ligne par rectangle.
+// Le viewBox est exprimé en modules : l'image reste nette à toute taille.
+func renderSVG(modules [][]bool, opts Options) []byte {
+	total := len(modules) + 2*opts.Margin
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", total, total, FormatColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, FormatColor(opts.Foreground))
+	for y, row := range modules {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/>` + "\n</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,161 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const testURL = "https://sho.rt/abc123"
+
+func TestValidate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ECC = "h"
+	if err := opts.Validate(); err != nil || opts.ECC != "H" {
+		t.Errorf("Validate = %v, ECC = %q, want H", err, opts.ECC)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *Options)
+		want   error // nil = toute erreur
+	}{
+		{"format", func(o *Options) { o.Format = "gif" }, ErrInvalidFormat},
+		{"ecc", func(o *Options) { o.ECC = "X" }, ErrInvalidECC},
+		{"size too small", func(o *Options) { o.Size = MinSize - 1 }, nil},
+		{"size too large", func(o *Options) { o.Size = MaxSize + 1 }, nil},
+		{"negative margin", func(o *Options) { o.Margin = -1 }, nil},
+		{"margin too large", func(o *Options) { o.Margin = MaxMargin + 1 }, nil},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		tt.modify(&opts)
+		err := opts.Validate()
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("%s: Validate error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  color.NRGBA
+	}{
+		{"#000000", color.NRGBA{A: 0xff}},
+		{"ff8800", color.NRGBA{R: 0xff, G: 0x88, A: 0xff}},
+		{"#1aF", color.NRGBA{R: 0x11, G: 0xaa, B: 0xff, A: 0xff}},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "#12", "#12345", "red", "#gggggg", "#1234567"} {
+		if _, err := ParseColor(value); !errors.Is(err, ErrInvalidColor) {
+			t.Errorf("ParseColor(%q) error = %v, want ErrInvalidColor", value, err)
+		}
+	}
+	if got := FormatColor(color.NRGBA{R: 0x11, G: 0xaa, B: 0xff, A: 0xff}); got != "#11aaff" {
+		t.Errorf("FormatColor = %q, want #11aaff", got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	opts := DefaultOptions()
+	other := opts
+	other.Foreground = color.NRGBA{R: 0xff, A: 0xff}
+	if opts.Key(testURL) == other.Key(testURL) || opts.Key(testURL) == opts.Key(testURL+"x") {
+		t.Error("Key does not depend on the colours and the content")
+	}
+	if opts.Key(testURL) != DefaultOptions().Key(testURL) {
+		t.Error("Key differs for identical options")
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = 300
+	opts.Foreground = color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}
+	data, err := Render(testURL, opts)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 300 || bounds.Dy() != 300 {
+		t.Fatalf("image is %dx%d, want 300x300", bounds.Dx(), bounds.Dy())
+	}
+
+	// Chaque module occupe un carré entier de pixels, le symbole étant centré
+	code, err := qrcode.New(testURL, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("qrcode.New: %v", err)
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	offset := (opts.Size-total*scale)/2 + opts.Margin*scale
+	for y, row := range modules {
+		for x, dark := range row {
+			want := opts.Background
+			if dark {
+				want = opts.Foreground
+			}
+			got := color.NRGBAModel.Convert(img.At(offset+x*scale+scale/2, offset+y*scale+scale/2)).(color.NRGBA)
+			if got != want {
+				t.Fatalf("module (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	// La zone de silence reste de la couleur du fond
+	if got := color.NRGBAModel.Convert(img.At(offset-1, offset-1)).(color.NRGBA); got != opts.Background {
+		t.Errorf("quiet zone = %v, want the background", got)
+	}
+
+	opts.Size = MinSize
+	opts.Margin = MaxMargin
+	if _, err := Render(testURL, opts); !errors.Is(err, ErrSizeTooSmall) {
+		t.Errorf("Render in %d pixels with a %d module margin error = %v, want ErrSizeTooSmall", opts.Size, opts.Margin, err)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.Size = MinSize // Le SVG est vectoriel : la taille n'est qu'un attribut
+	opts.Background = color.NRGBA{R: 0xff, G: 0xee, B: 0xdd, A: 0xff}
+	data, err := Render(testURL, opts)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	svg := string(data)
+
+	code, err := qrcode.New(testURL, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("qrcode.New: %v", err)
+	}
+	code.DisableBorder = true
+	total := len(code.Bitmap()) + 2*opts.Margin
+	for _, want := range []string{
+		fmt.Sprintf(`width="32" height="32" viewBox="0 0 %d %d"`, total, total),
+		`<rect width="` + fmt.Sprint(total),
+		`fill="#ffeedd"`,
+		`<path fill="#000000" d="M`,
+		"</svg>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG does not contain %q", want)
+		}
+	}
+}
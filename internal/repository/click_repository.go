@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"sort"
+	"time"
+
 	"github.com/armanceau/go-url-shortener/internal/models"
 	"gorm.io/gorm"
 )
@@ -10,6 +13,21 @@ import (
 type ClickRepository interface {
 	CreateClick(click *models.Click) error
 	CountClicksByLinkID(linkID uint) (int, error)
+	CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error)
+	CountClicksByLocation(linkID uint) ([]LocationCount, error)
+	CountClicksByVariant(linkID uint) (map[uint]int, error)
+	CountClicksByIPAddresses(addresses []string) (int64, error)
+	FindLinkIDsByIPAddresses(addresses []string) ([]uint, error)
+	DeleteClicksByIPAddresses(addresses []string) (int64, error)
+	StreamClicks(since, until time.Time, fn func(click *models.Click) error) error
+}
+
+// LocationCount est le nombre de clics d'un lien pour une localisation (pays, région, ville).
+type LocationCount struct {
+	Country string
+	Region  string
+	City    string
+	Count   int
 }
 
 // GormClickRepository est l'implémentation de l'interface ClickRepository utilisant GORM.
@@ -30,10 +48,171 @@ func (r *GormClickRepository) CreateClick(click *models.Click) error {
 // CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
 // Cette méthode est utilisée pour fournir des statistiques pour une URL courte.
 func (r *GormClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
-	var count int64 
-	err := r.db.Model(&models.Click{}).Where("link_id = ?", linkID).Count(&count).Error
+	counts, err := r.CountClicksByLinkIDs([]uint{linkID})
 	if err != nil {
 		return 0, err
 	}
-	return int(count), nil
+	return counts[linkID], nil
+}
+
+// CountClicksByLinkIDs compte les clics de plusieurs liens en une seule requête groupée par source.
+// Les liens sans clic sont absents de la map renvoyée.
+func (r *GormClickRepository) CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(linkIDs))
+	if len(linkIDs) == 0 {
+		return counts, nil
+	}
+
+	rows, err := countClicks[struct {
+		LinkID uint
+		Total  int
+	}](r.db, "link_id", "link_id IN ?", "link_id IN ?", linkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.LinkID] += row.Total
+	}
+	return counts, nil
+}
+
+// CountClicksByLocation compte les clics d'un lien regroupés par pays, région et ville,
+// du plus grand nombre de clics au plus petit.
+func (r *GormClickRepository) CountClicksByLocation(linkID uint) ([]LocationCount, error) {
+	rows, err := countClicks[struct {
+		Country, Region, City string
+		Total                 int
+	}](r.db, "country, region, city", "link_id = ?", "link_id = ?", linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	byLocation := make(map[LocationCount]int)
+	for _, row := range rows {
+		byLocation[LocationCount{Country: row.Country, Region: row.Region, City: row.City}] += row.Total
+	}
+	locations := make([]LocationCount, 0, len(byLocation))
+	for location, count := range byLocation {
+		location.Count = count
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.City < b.City
+	})
+	return locations, nil
+}
+
+// CountClicksByVariant compte les clics d'un lien regroupés par variante A/B.
+// Les clics sans variante ne sont pas comptés.
+func (r *GormClickRepository) CountClicksByVariant(linkID uint) (map[uint]int, error) {
+	rows, err := countClicks[struct {
+		VariantID uint
+		Total     int
+	}](r.db, "variant_id", "link_id = ? AND variant_id <> 0", "link_id = ? AND variant_id IS NOT NULL", linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.VariantID] += row.Total
+	}
+	return counts, nil
+}
+
+// CountClicksByIPAddresses compte les clics dont l'adresse IP enregistrée fait partie de addresses.
+func (r *GormClickRepository) CountClicksByIPAddresses(addresses []string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Click{}).Where("ip_address IN ?", addresses).Count(&count).Error
+	return count, err
+}
+
+// FindLinkIDsByIPAddresses renvoie, sans doublon, les liens ayant des clics dont l'adresse IP enregistrée fait partie de addresses.
+func (r *GormClickRepository) FindLinkIDsByIPAddresses(addresses []string) ([]uint, error) {
+	var linkIDs []uint
+	err := r.db.Model(&models.Click{}).Where("ip_address IN ?", addresses).Distinct().Order("link_id").Pluck("link_id", &linkIDs).Error
+	return linkIDs, err
+}
+
+// DeleteClicksByIPAddresses supprime les clics dont l'adresse IP enregistrée fait partie de addresses
+// et renvoie le nombre de clics supprimés. Les agrégats, anonymes, ne sont pas modifiés.
+func (r *GormClickRepository) DeleteClicksByIPAddresses(addresses []string) (int64, error) {
+	result := r.db.Where("ip_address IN ?", addresses).Delete(&models.Click{})
+	return result.RowsAffected, result.Error
+}
+
+// StreamClicks parcourt par lots, dans l'ordre des IDs, les clics bruts enregistrés entre since (inclus) et until (exclu).
+// Une borne nulle n'est pas appliquée. Le parcours s'arrête à la première erreur de fn.
+func (r *GormClickRepository) StreamClicks(since, until time.Time, fn func(click *models.Click) error) error {
+	query := r.db.Model(&models.Click{})
+	if !since.IsZero() {
+		query = query.Where("timestamp >= ?", storedTime(since))
+	}
+	if !until.IsZero() {
+		query = query.Where("timestamp < ?", storedTime(until))
+	}
+
+	var clicks []models.Click
+	return query.FindInBatches(&clicks, streamBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range clicks {
+			if err := fn(&clicks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// countClicks exécute un comptage groupé par columns sur les agrégats journaliers (périodes agrégées),
+// les agrégats horaires (jour en cours) et les clics bruts (heure en cours, non encore agrégée).
+// Les lignes des trois sources sont renvoyées telles quelles, dans la colonne total : l'appelant les additionne.
+// Tant qu'aucune agrégation n'a eu lieu, seule la table des clics est lue.
+func countClicks[T any](db *gorm.DB, columns, rollupWhere, clickWhere string, args ...interface{}) ([]T, error) {
+	var watermarks []models.RollupWatermark
+	if err := db.Find(&watermarks).Error; err != nil {
+		return nil, err
+	}
+	var hourly, daily time.Time
+	for _, watermark := range watermarks {
+		switch watermark.Granularity {
+		case models.RollupHourly:
+			hourly = watermark.Until.UTC()
+		case models.RollupDaily:
+			daily = watermark.Until.UTC()
+		}
+	}
+
+	raw := db.Model(&models.Click{}).Select(columns+", COUNT(*) AS total").Where(clickWhere, args...)
+	if !hourly.IsZero() {
+		raw = raw.Where("timestamp >= ?", storedTime(hourly))
+	}
+	queries := []*gorm.DB{raw}
+	if !daily.IsZero() {
+		queries = append(queries, db.Table(models.DailyRollupTable).Select(columns+", SUM(clicks) AS total").
+			Where(rollupWhere, args...).Where("bucket < ?", daily))
+	}
+	if hourly.After(daily) {
+		queries = append(queries, db.Table(models.HourlyRollupTable).Select(columns+", SUM(clicks) AS total").
+			Where(rollupWhere, args...).Where("bucket >= ? AND bucket < ?", daily, hourly))
+	}
+
+	var all []T
+	for _, query := range queries {
+		var rows []T
+		if err := query.Group(columns).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		all = append(all, rows...)
+	}
+	return all, nil
 }
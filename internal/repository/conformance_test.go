@@ -0,0 +1,479 @@
+package repository_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/migrations"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Suite de conformité : chaque test s'exécute sur le stockage en mémoire, sur GORM/SQLite
+// et, si DATABASE_URL est définie, sur GORM/PostgreSQL (voir postgres_test.go),
+// pour que les implémentations restent interchangeables.
+
+// backend crée un jeu de repositories vide.
+type backend struct {
+	name string
+	open func(t *testing.T) repository.Repositories
+}
+
+var backends = []backend{
+	{name: "memory", open: func(t *testing.T) repository.Repositories { return repository.NewMemoryRepositories() }},
+	{name: "sqlite", open: func(t *testing.T) repository.Repositories { return repository.NewGormRepositories(openSQLite(t)) }},
+}
+
+// openSQLite ouvre une base SQLite vide dans un répertoire temporaire et y applique toutes les migrations.
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := migrations.Up(db, false); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// forEachBackend exécute test sur chaque implémentation, dans un sous-test nommé d'après elle.
+func forEachBackend(t *testing.T, test func(t *testing.T, repos repository.Repositories)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b.open(t))
+		})
+	}
+}
+
+func mustCreateLink(t *testing.T, repos repository.Repositories, link *models.Link) *models.Link {
+	t.Helper()
+	if err := repos.Links.CreateLink(link); err != nil {
+		t.Fatalf("CreateLink(%s): %v", link.ShortCode, err)
+	}
+	return link
+}
+
+func mustCreateClick(t *testing.T, repos repository.Repositories, click models.Click) {
+	t.Helper()
+	if err := repos.Clicks.CreateClick(&click); err != nil {
+		t.Fatalf("CreateClick: %v", err)
+	}
+}
+
+func TestLinkCreateAndGet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		link := mustCreateLink(t, repos, &models.Link{
+			ShortCode: "abc",
+			LongURL:   "https://example.com",
+			TargetingRules: []models.TargetingRule{
+				{Position: 2, OS: "ios", TargetURL: "https://example.com/ios"},
+				{Position: 1, OS: "android", TargetURL: "https://example.com/android"},
+			},
+			Variants: []models.LinkVariant{{Name: "A", TargetURL: "https://example.com/a", Weight: 1}},
+		})
+		if link.ID == 0 || link.TargetingRules[0].ID == 0 || link.Variants[0].ID == 0 {
+			t.Fatalf("CreateLink did not assign IDs: %+v", link)
+		}
+
+		got, err := repos.Links.GetLinkByShortCode(0, "abc")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if got.ID != link.ID || got.LongURL != link.LongURL || got.RedirectStatus != models.DefaultRedirectStatus || got.QueryConflict != models.QueryConflictLink {
+			t.Errorf("GetLinkByShortCode = %+v", got)
+		}
+		if len(got.TargetingRules) != 2 || got.TargetingRules[0].OS != "android" || got.TargetingRules[1].OS != "ios" {
+			t.Errorf("rules not ordered by position: %+v", got.TargetingRules)
+		}
+		if len(got.Variants) != 1 || got.Variants[0].LinkID != link.ID {
+			t.Errorf("variants = %+v", got.Variants)
+		}
+
+		if _, err := repos.Links.GetLinkByShortCode(0, "ABC"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCode(ABC) error = %v, want ErrRecordNotFound", err)
+		}
+		if _, err := repos.Links.GetLinkByShortCode(1, "abc"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCode on another domain error = %v, want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestLinkCodesAreUniquePerDomain(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		mustCreateLink(t, repos, &models.Link{ShortCode: "dup", LongURL: "https://example.com/1"})
+		mustCreateLink(t, repos, &models.Link{DomainID: 1, ShortCode: "dup", LongURL: "https://example.com/2"})
+
+		if err := repos.Links.CreateLink(&models.Link{ShortCode: "dup", LongURL: "https://example.com/3"}); err == nil {
+			t.Fatal("CreateLink accepted a duplicated code")
+		}
+
+		// Un lot contenant un doublon n'enregistre aucun lien
+		err := repos.Links.CreateLinks([]*models.Link{
+			{ShortCode: "new1", LongURL: "https://example.com/4"},
+			{ShortCode: "dup", LongURL: "https://example.com/5"},
+		})
+		if err == nil {
+			t.Fatal("CreateLinks accepted a duplicated code")
+		}
+		if _, err := repos.Links.GetLinkByShortCode(0, "new1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("CreateLinks kept part of a failed batch: %v", err)
+		}
+
+		if err := repos.Links.CreateLinks([]*models.Link{
+			{ShortCode: "new1", LongURL: "https://example.com/4"},
+			{ShortCode: "new2", LongURL: "https://example.com/5"},
+		}); err != nil {
+			t.Fatalf("CreateLinks: %v", err)
+		}
+		links, err := repos.Links.GetAllLinks()
+		if err != nil || len(links) != 4 {
+			t.Fatalf("GetAllLinks = %d links, %v; want 4", len(links), err)
+		}
+	})
+}
+
+func TestLinkFoldedLookups(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		first := mustCreateLink(t, repos, &models.Link{ShortCode: "AbC", LongURL: "https://example.com/1"})
+		mustCreateLink(t, repos, &models.Link{ShortCode: "abc", LongURL: "https://example.com/2"})
+		mustCreateLink(t, repos, &models.Link{DomainID: 1, ShortCode: "xyz", LongURL: "https://example.com/3"})
+
+		got, err := repos.Links.GetLinkByShortCodeFold(0, "ABC")
+		if err != nil || got.ID != first.ID {
+			t.Errorf("GetLinkByShortCodeFold(ABC) = %+v, %v; want the oldest link", got, err)
+		}
+		if _, err := repos.Links.GetLinkByShortCodeFold(0, "XYZ"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCodeFold on another domain error = %v, want ErrRecordNotFound", err)
+		}
+
+		found, err := repos.Links.FindShortCodes(0, []string{"abc", "ABC", "nope", "xyz"}, false)
+		if err != nil {
+			t.Fatalf("FindShortCodes: %v", err)
+		}
+		if sort.Strings(found); len(found) != 1 || found[0] != "abc" {
+			t.Errorf("FindShortCodes exact = %v, want [abc]", found)
+		}
+
+		found, err = repos.Links.FindShortCodes(0, []string{"ABC", "nope"}, true)
+		if err != nil {
+			t.Fatalf("FindShortCodes: %v", err)
+		}
+		if sort.Strings(found); len(found) != 2 || found[0] != "AbC" || found[1] != "abc" {
+			t.Errorf("FindShortCodes folded = %v, want [AbC abc]", found)
+		}
+	})
+}
+
+func TestLinkFindByCanonicalURL(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		canonical := "https://example.com/page"
+		exhausted := mustCreateLink(t, repos, &models.Link{ShortCode: "a", LongURL: canonical, CanonicalURL: canonical, Owner: "alice", MaxUses: 1})
+		if ok, err := repos.Links.ClaimLinkUse(exhausted.ID); !ok || err != nil {
+			t.Fatalf("ClaimLinkUse = %v, %v", ok, err)
+		}
+		usable := mustCreateLink(t, repos, &models.Link{ShortCode: "b", LongURL: canonical, CanonicalURL: canonical, Owner: "alice"})
+		mustCreateLink(t, repos, &models.Link{ShortCode: "c", LongURL: canonical, CanonicalURL: canonical, Owner: "bob"})
+
+		got, err := repos.Links.FindLinkByCanonicalURL(0, "alice", canonical)
+		if err != nil || got.ID != usable.ID {
+			t.Errorf("FindLinkByCanonicalURL = %+v, %v; want link b", got, err)
+		}
+		if _, err := repos.Links.FindLinkByCanonicalURL(1, "alice", canonical); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindLinkByCanonicalURL on another domain error = %v, want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestLinkClaimUse(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		link := mustCreateLink(t, repos, &models.Link{ShortCode: "once", LongURL: "https://example.com", MaxUses: 3})
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		claimed := 0
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := repos.Links.ClaimLinkUse(link.ID)
+				if err != nil {
+					t.Errorf("ClaimLinkUse: %v", err)
+				}
+				if ok {
+					mu.Lock()
+					claimed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if claimed != 3 {
+			t.Errorf("claimed %d uses, want 3", claimed)
+		}
+
+		got, _ := repos.Links.GetLinkByShortCode(0, "once")
+		if got.UseCount != 3 || !got.IsExhausted() {
+			t.Errorf("UseCount = %d, want 3", got.UseCount)
+		}
+	})
+}
+
+func TestLinkUpdates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		link := mustCreateLink(t, repos, &models.Link{
+			ShortCode:      "upd",
+			LongURL:        "https://example.com",
+			TargetingRules: []models.TargetingRule{{Position: 1, OS: "ios", TargetURL: "https://example.com/ios"}},
+			Variants:       []models.LinkVariant{{Name: "A", TargetURL: "https://example.com/a", Weight: 1}},
+		})
+
+		err := repos.Links.UpdateLinkFields(link.ID, map[string]interface{}{"redirect_status": 301, "interstitial": true, "pass_query": true})
+		if err != nil {
+			t.Fatalf("UpdateLinkFields: %v", err)
+		}
+		err = repos.Links.ReplaceTargetingRules(link.ID, []models.TargetingRule{
+			{Position: 1, Device: "mobile", TargetURL: "https://example.com/m"},
+			{Position: 0, Language: "fr", TargetURL: "https://example.com/fr"},
+		})
+		if err != nil {
+			t.Fatalf("ReplaceTargetingRules: %v", err)
+		}
+		err = repos.Links.ReplaceVariants(link.ID, []models.LinkVariant{
+			{Name: "B", TargetURL: "https://example.com/b", Weight: 2},
+			{Name: "C", TargetURL: "https://example.com/c", Weight: 3},
+		})
+		if err != nil {
+			t.Fatalf("ReplaceVariants: %v", err)
+		}
+
+		got, err := repos.Links.GetLinkByShortCode(0, "upd")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if got.RedirectStatus != 301 || !got.Interstitial || !got.PassQuery || got.PassPath {
+			t.Errorf("fields not updated: %+v", got)
+		}
+		if len(got.TargetingRules) != 2 || got.TargetingRules[0].Language != "fr" || got.TargetingRules[1].Device != "mobile" {
+			t.Errorf("rules = %+v", got.TargetingRules)
+		}
+		if len(got.Variants) != 2 || got.Variants[0].Name != "B" || got.Variants[1].Weight != 3 {
+			t.Errorf("variants = %+v", got.Variants)
+		}
+
+		if err := repos.Links.ReplaceTargetingRules(link.ID, nil); err != nil {
+			t.Fatalf("ReplaceTargetingRules(nil): %v", err)
+		}
+		if got, _ := repos.Links.GetLinkByShortCode(0, "upd"); len(got.TargetingRules) != 0 {
+			t.Errorf("rules not cleared: %+v", got.TargetingRules)
+		}
+	})
+}
+
+func TestLinkFindByUTMAndStream(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		for i, utm := range []models.UTMParams{
+			{Source: "newsletter", Campaign: "spring"},
+			{Source: "newsletter", Campaign: "summer"},
+			{Source: "twitter", Campaign: "spring"},
+		} {
+			mustCreateLink(t, repos, &models.Link{
+				ShortCode: string(rune('a' + i)),
+				LongURL:   "https://example.com",
+				UTM:       utm,
+				CreatedAt: base.Add(time.Duration(i) * 24 * time.Hour),
+			})
+		}
+
+		links, err := repos.Links.FindLinksByUTM(models.UTMParams{Source: "newsletter"})
+		if err != nil || len(links) != 2 || links[0].ShortCode != "a" || links[1].ShortCode != "b" {
+			t.Errorf("FindLinksByUTM(newsletter) = %+v, %v", links, err)
+		}
+		links, err = repos.Links.FindLinksByUTM(models.UTMParams{Source: "newsletter", Campaign: "spring"})
+		if err != nil || len(links) != 1 || links[0].ShortCode != "a" {
+			t.Errorf("FindLinksByUTM(newsletter, spring) = %+v, %v", links, err)
+		}
+
+		var streamed []string
+		err = repos.Links.StreamLinks(base.Add(time.Hour), base.Add(48*time.Hour), func(link *models.Link) error {
+			streamed = append(streamed, link.ShortCode)
+			return nil
+		})
+		if err != nil || len(streamed) != 1 || streamed[0] != "b" {
+			t.Errorf("StreamLinks = %v, %v; want [b]", streamed, err)
+		}
+	})
+}
+
+func TestClickCounts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		link := mustCreateLink(t, repos, &models.Link{ShortCode: "c1", LongURL: "https://example.com",
+			Variants: []models.LinkVariant{{Name: "A", TargetURL: "https://example.com/a", Weight: 1}}})
+		other := mustCreateLink(t, repos, &models.Link{ShortCode: "c2", LongURL: "https://example.com"})
+		variantID := link.Variants[0].ID
+
+		now := time.Now()
+		mustCreateClick(t, repos, models.Click{LinkID: link.ID, Timestamp: now, IPAddress: "192.0.2.1", Country: "FR", City: "Paris", VariantID: &variantID})
+		mustCreateClick(t, repos, models.Click{LinkID: link.ID, Timestamp: now, IPAddress: "192.0.2.2", Country: "FR", City: "Paris"})
+		mustCreateClick(t, repos, models.Click{LinkID: link.ID, Timestamp: now, IPAddress: "192.0.2.1", Country: "BE"})
+		mustCreateClick(t, repos, models.Click{LinkID: other.ID, Timestamp: now, IPAddress: "192.0.2.3"})
+
+		if count, err := repos.Clicks.CountClicksByLinkID(link.ID); err != nil || count != 3 {
+			t.Errorf("CountClicksByLinkID = %d, %v; want 3", count, err)
+		}
+		if count, err := repos.Links.CountClicksByLinkID(other.ID); err != nil || count != 1 {
+			t.Errorf("Links.CountClicksByLinkID = %d, %v; want 1", count, err)
+		}
+		counts, err := repos.Clicks.CountClicksByLinkIDs([]uint{link.ID, other.ID, 999})
+		if err != nil || counts[link.ID] != 3 || counts[other.ID] != 1 || counts[999] != 0 {
+			t.Errorf("CountClicksByLinkIDs = %v, %v", counts, err)
+		}
+
+		locations, err := repos.Clicks.CountClicksByLocation(link.ID)
+		if err != nil || len(locations) != 2 || locations[0] != (repository.LocationCount{Country: "FR", City: "Paris", Count: 2}) || locations[1].Country != "BE" {
+			t.Errorf("CountClicksByLocation = %+v, %v", locations, err)
+		}
+		variants, err := repos.Clicks.CountClicksByVariant(link.ID)
+		if err != nil || len(variants) != 1 || variants[variantID] != 1 {
+			t.Errorf("CountClicksByVariant = %v, %v", variants, err)
+		}
+	})
+}
+
+func TestClickErasureAndStream(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		link := mustCreateLink(t, repos, &models.Link{ShortCode: "e", LongURL: "https://example.com"})
+		other := mustCreateLink(t, repos, &models.Link{ShortCode: "f", LongURL: "https://example.com"})
+		base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		for i, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1", "192.0.2.3"} {
+			mustCreateClick(t, repos, models.Click{LinkID: link.ID, Timestamp: base.Add(time.Duration(i) * time.Hour), IPAddress: ip})
+		}
+		mustCreateClick(t, repos, models.Click{LinkID: other.ID, Timestamp: base.Add(-time.Hour), IPAddress: "192.0.2.3"})
+
+		addresses := []string{"192.0.2.1", "192.0.2.9"}
+		if count, err := repos.Clicks.CountClicksByIPAddresses(addresses); err != nil || count != 2 {
+			t.Errorf("CountClicksByIPAddresses = %d, %v; want 2", count, err)
+		}
+		if linkIDs, err := repos.Clicks.FindLinkIDsByIPAddresses([]string{"192.0.2.1", "192.0.2.3"}); err != nil || len(linkIDs) != 2 || linkIDs[0] != link.ID || linkIDs[1] != other.ID {
+			t.Errorf("FindLinkIDsByIPAddresses = %v, %v; want [%d %d]", linkIDs, err, link.ID, other.ID)
+		}
+		if deleted, err := repos.Clicks.DeleteClicksByIPAddresses(addresses); err != nil || deleted != 2 {
+			t.Errorf("DeleteClicksByIPAddresses = %d, %v; want 2", deleted, err)
+		}
+		if count, err := repos.Clicks.CountClicksByLinkID(link.ID); err != nil || count != 2 {
+			t.Errorf("CountClicksByLinkID after erasure = %d, %v; want 2", count, err)
+		}
+
+		// Les clics restants restent indexés après la suppression
+		mustCreateClick(t, repos, models.Click{LinkID: link.ID, Timestamp: base.Add(4 * time.Hour), IPAddress: "192.0.2.4"})
+		var streamed []string
+		err := repos.Clicks.StreamClicks(base, base.Add(4*time.Hour), func(click *models.Click) error {
+			streamed = append(streamed, click.IPAddress)
+			return nil
+		})
+		if err != nil || len(streamed) != 2 || streamed[0] != "192.0.2.2" || streamed[1] != "192.0.2.3" {
+			t.Errorf("StreamClicks = %v, %v", streamed, err)
+		}
+		if count, err := repos.Clicks.CountClicksByLinkID(link.ID); err != nil || count != 3 {
+			t.Errorf("CountClicksByLinkID = %d, %v; want 3", count, err)
+		}
+	})
+}
+
+func TestDomains(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		for _, host := range []string{"go.example.com", "a.example.com"} {
+			if err := repos.Domains.CreateDomain(&models.Domain{Host: host, BaseURL: "https://" + host}); err != nil {
+				t.Fatalf("CreateDomain(%s): %v", host, err)
+			}
+		}
+		if err := repos.Domains.CreateDomain(&models.Domain{Host: "go.example.com", BaseURL: "https://x"}); err == nil {
+			t.Error("CreateDomain accepted a duplicated host")
+		}
+
+		domain, err := repos.Domains.GetDomainByHost("go.example.com")
+		if err != nil || domain.BaseURL != "https://go.example.com" {
+			t.Fatalf("GetDomainByHost = %+v, %v", domain, err)
+		}
+		if byID, err := repos.Domains.GetDomainByID(domain.ID); err != nil || byID.Host != domain.Host {
+			t.Errorf("GetDomainByID = %+v, %v", byID, err)
+		}
+		if _, err := repos.Domains.GetDomainByHost("unknown.example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetDomainByHost(unknown) error = %v, want ErrRecordNotFound", err)
+		}
+
+		domains, err := repos.Domains.GetAllDomains()
+		if err != nil || len(domains) != 2 || domains[0].Host != "a.example.com" {
+			t.Errorf("GetAllDomains = %+v, %v", domains, err)
+		}
+	})
+}
+
+func TestCounters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		for want := uint64(1); want <= 3; want++ {
+			if got, err := repos.Counters.NextValue("codes"); err != nil || got != want {
+				t.Fatalf("NextValue = %d, %v; want %d", got, err, want)
+			}
+		}
+		if got, err := repos.Counters.NextValue("other"); err != nil || got != 1 {
+			t.Errorf("NextValue(other) = %d, %v; want 1", got, err)
+		}
+	})
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos repository.Repositories) {
+		now := time.Now()
+		record := func(key string) *models.IdempotencyKey {
+			return &models.IdempotencyKey{Scope: "POST /links", Key: key, RequestHash: "h", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		}
+
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(record("k"), now); err != nil || !reserved {
+			t.Fatalf("first reservation = %v, %v; want reserved", reserved, err)
+		}
+		existing, reserved, err := repos.Idempotency.ReserveIdempotencyKey(record("k"), now)
+		if err != nil || reserved || !existing.InProgress() {
+			t.Fatalf("second reservation = %+v, %v, %v; want the in-progress key", existing, reserved, err)
+		}
+
+		if err := repos.Idempotency.CompleteIdempotencyKey("POST /links", "k", 201, []byte(`{"ok":true}`)); err != nil {
+			t.Fatalf("CompleteIdempotencyKey: %v", err)
+		}
+		existing, reserved, err = repos.Idempotency.ReserveIdempotencyKey(record("k"), now)
+		if err != nil || reserved || existing.StatusCode != 201 || string(existing.Response) != `{"ok":true}` {
+			t.Fatalf("reservation after completion = %+v, %v, %v", existing, reserved, err)
+		}
+
+		// Une clé expirée est de nouveau libre
+		later := now.Add(2 * time.Hour)
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(&models.IdempotencyKey{Scope: "POST /links", Key: "k", RequestHash: "h2", CreatedAt: later, ExpiresAt: later.Add(time.Hour)}, later); err != nil || !reserved {
+			t.Fatalf("reservation of an expired key = %v, %v; want reserved", reserved, err)
+		}
+
+		if err := repos.Idempotency.ReleaseIdempotencyKey("POST /links", "k"); err != nil {
+			t.Fatalf("ReleaseIdempotencyKey: %v", err)
+		}
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(record("k"), now); err != nil || !reserved {
+			t.Fatalf("reservation after release = %v, %v; want reserved", reserved, err)
+		}
+
+		repos.Idempotency.ReserveIdempotencyKey(record("other"), now)
+		if deleted, err := repos.Idempotency.DeleteExpiredIdempotencyKeys(now.Add(2 * time.Hour)); err != nil || deleted != 2 {
+			t.Errorf("DeleteExpiredIdempotencyKeys = %d, %v; want 2", deleted, err)
+		}
+	})
+}
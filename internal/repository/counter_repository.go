@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CounterRepository est une interface qui définit l'accès aux compteurs nommés persistants.
+type CounterRepository interface {
+	NextValue(name string) (uint64, error)
+}
+
+// GormCounterRepository est l'implémentation de CounterRepository utilisant GORM.
+type GormCounterRepository struct {
+	db *gorm.DB // Référence à l'instance de la base de données GORM
+}
+
+// NewCounterRepository crée et retourne une nouvelle instance de GormCounterRepository.
+func NewCounterRepository(db *gorm.DB) *GormCounterRepository {
+	return &GormCounterRepository{db: db}
+}
+
+// NextValue incrémente le compteur (créé à 0 s'il n'existe pas) et renvoie sa nouvelle valeur.
+// L'incrément et la lecture ont lieu dans la même transaction, si bien que deux appels concurrents,
+// même depuis des instances différentes, n'obtiennent jamais la même valeur.
+func (r *GormCounterRepository) NextValue(name string) (uint64, error) {
+	var counter models.Counter
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Counter{Name: name}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Counter{}).Where("name = ?", name).
+			UpdateColumn("value", gorm.Expr("value + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Where("name = ?", name).First(&counter).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return counter.Value, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// DomainRepository est une interface qui définit les méthodes d'accès aux données pour les domaines personnalisés.
+type DomainRepository interface {
+	CreateDomain(domain *models.Domain) error
+	GetDomainByHost(host string) (*models.Domain, error)
+	GetDomainByID(id uint) (*models.Domain, error)
+	GetAllDomains() ([]models.Domain, error)
+}
+
+// GormDomainRepository est l'implémentation de DomainRepository utilisant GORM.
+type GormDomainRepository struct {
+	db *gorm.DB // Référence à l'instance de la base de données GORM
+}
+
+// NewDomainRepository crée et retourne une nouvelle instance de GormDomainRepository.
+func NewDomainRepository(db *gorm.DB) *GormDomainRepository {
+	return &GormDomainRepository{db: db}
+}
+
+// CreateDomain insère un nouveau domaine dans la base de données.
+func (r *GormDomainRepository) CreateDomain(domain *models.Domain) error {
+	return r.db.Create(domain).Error
+}
+
+// GetDomainByHost récupère un domaine par son nom d'hôte.
+// Il renvoie gorm.ErrRecordNotFound si aucun domaine ne correspond.
+func (r *GormDomainRepository) GetDomainByHost(host string) (*models.Domain, error) {
+	var domain models.Domain
+	err := r.db.Where("host = ?", host).First(&domain).Error
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// GetDomainByID récupère un domaine par son identifiant.
+// Il renvoie gorm.ErrRecordNotFound si aucun domaine ne correspond.
+func (r *GormDomainRepository) GetDomainByID(id uint) (*models.Domain, error) {
+	var domain models.Domain
+	err := r.db.First(&domain, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// GetAllDomains récupère tous les domaines personnalisés, triés par nom d'hôte.
+func (r *GormDomainRepository) GetAllDomains() ([]models.Domain, error) {
+	var domains []models.Domain
+	err := r.db.Order("host").Find(&domains).Error
+	return domains, err
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository définit l'accès aux clés d'idempotence des requêtes de création.
+type IdempotencyRepository interface {
+	ReserveIdempotencyKey(record *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(scope, key string, statusCode int, response []byte) error
+	ReleaseIdempotencyKey(scope, key string) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
+}
+
+// GormIdempotencyRepository est l'implémentation de IdempotencyRepository utilisant GORM.
+type GormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository crée et retourne une nouvelle instance de GormIdempotencyRepository.
+func NewIdempotencyRepository(db *gorm.DB) *GormIdempotencyRepository {
+	return &GormIdempotencyRepository{db: db}
+}
+
+// ReserveIdempotencyKey enregistre la clé comme "en cours" si elle est libre (absente ou expirée) et renvoie true.
+// Sinon, la clé enregistrée est renvoyée avec false. L'insertion est atomique : deux requêtes concurrentes,
+// même sur des instances différentes, ne peuvent pas réserver la même clé.
+func (r *GormIdempotencyRepository) ReserveIdempotencyKey(record *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error) {
+	err := r.db.Where("scope = ? AND idempotency_key = ? AND expires_at <= ?", record.Scope, record.Key, storedTime(now)).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := r.db.Where("scope = ? AND idempotency_key = ?", record.Scope, record.Key).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// CompleteIdempotencyKey enregistre la réponse de la requête d'une clé réservée.
+func (r *GormIdempotencyRepository) CompleteIdempotencyKey(scope, key string, statusCode int, response []byte) error {
+	return r.db.Model(&models.IdempotencyKey{}).Where("scope = ? AND idempotency_key = ?", scope, key).
+		Updates(map[string]interface{}{"status_code": statusCode, "response": response}).Error
+}
+
+// ReleaseIdempotencyKey supprime une clé, pour qu'une nouvelle tentative puisse être traitée.
+func (r *GormIdempotencyRepository) ReleaseIdempotencyKey(scope, key string) error {
+	return r.db.Where("scope = ? AND idempotency_key = ?", scope, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpiredIdempotencyKeys supprime les clés expirées et renvoie leur nombre.
+func (r *GormIdempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", storedTime(now)).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"github.com/armanceau/go-url-shortener/internal/models"
 	"gorm.io/gorm"
 )
@@ -8,11 +11,24 @@ import (
 // LinkRepository est une interface qui définit les méthodes d'accès aux données pour les opérations CRUD sur les liens.
 type LinkRepository interface {
 	CreateLink(link *models.Link) error
-	GetLinkByShortCode(shortCode string) (*models.Link, error)
+	CreateLinks(links []*models.Link) error
+	GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error)
+	GetLinkByShortCodeFold(domainID uint, shortCode string) (*models.Link, error)
+	FindShortCodes(domainID uint, codes []string, fold bool) ([]string, error)
+	FindLinkByCanonicalURL(domainID uint, owner, canonicalURL string) (*models.Link, error)
 	GetAllLinks() ([]models.Link, error)
 	CountClicksByLinkID(linkID uint) (int, error)
+	ClaimLinkUse(linkID uint) (bool, error)
+	UpdateLinkFields(linkID uint, fields map[string]interface{}) error
+	FindLinksByUTM(filter models.UTMParams) ([]models.Link, error)
+	ReplaceTargetingRules(linkID uint, rules []models.TargetingRule) error
+	ReplaceVariants(linkID uint, variants []models.LinkVariant) error
+	StreamLinks(since, until time.Time, fn func(link *models.Link) error) error
 }
 
+// streamBatchSize est le nombre de lignes lues par requête lors d'un parcours complet (export).
+const streamBatchSize = 500
+
 // GormLinkRepository est l'implémentation de LinkRepository utilisant GORM.
 type GormLinkRepository struct {
 	db *gorm.DB // Référence à l'instance de la base de données GORM
@@ -28,17 +44,131 @@ func (r *GormLinkRepository) CreateLink(link *models.Link) error {
 	return r.db.Create(link).Error
 }
 
-// GetLinkByShortCode récupère un lien de la base de données en utilisant son shortCode.
-// Il renvoie gorm.ErrRecordNotFound si aucun lien n'est trouvé avec ce shortCode.
-func (r *GormLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+// CreateLinks insère plusieurs liens dans une seule transaction : en cas d'erreur, aucun n'est enregistré.
+func (r *GormLinkRepository) CreateLinks(links []*models.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(links, streamBatchSize).Error
+	})
+}
+
+// GetLinkByShortCode récupère un lien de la base de données en utilisant son domaine et son shortCode,
+// avec ses règles de ciblage triées par ordre d'évaluation et ses variantes A/B.
+// Il renvoie gorm.ErrRecordNotFound si aucun lien n'est trouvé avec ce shortCode sur ce domaine.
+func (r *GormLinkRepository) GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error) {
+	var link models.Link
+	err := r.withRelations().Where("domain_id = ? AND short_code = ?", domainID, shortCode).First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetLinkByShortCodeFold récupère un lien comme GetLinkByShortCode, sans tenir compte de la casse du code.
+// Si plusieurs codes anciens ne diffèrent que par la casse, le plus ancien est renvoyé.
+// L'index idx_links_domain_code_lower (créé par migrate) évite un parcours complet de la table.
+func (r *GormLinkRepository) GetLinkByShortCodeFold(domainID uint, shortCode string) (*models.Link, error) {
 	var link models.Link
-	err := r.db.Where("short_code = ?", shortCode).First(&link).Error
+	err := r.withRelations().Where("domain_id = ? AND LOWER(short_code) = LOWER(?)", domainID, shortCode).
+		Order("id").First(&link).Error
 	if err != nil {
 		return nil, err
 	}
 	return &link, nil
 }
 
+// FindShortCodes renvoie, parmi codes, ceux qui sont déjà enregistrés sur le domaine, tels qu'ils sont enregistrés.
+// Avec fold, la comparaison ne tient pas compte de la casse. Les codes sont vérifiés par lots, en une requête par lot.
+func (r *GormLinkRepository) FindShortCodes(domainID uint, codes []string, fold bool) ([]string, error) {
+	var found []string
+	for start := 0; start < len(codes); start += streamBatchSize {
+		chunk := codes[start:min(start+streamBatchSize, len(codes))]
+		query := r.db.Model(&models.Link{}).Where("domain_id = ?", domainID)
+		if fold {
+			lowered := make([]string, len(chunk))
+			for i, code := range chunk {
+				lowered[i] = strings.ToLower(code)
+			}
+			query = query.Where("LOWER(short_code) IN ?", lowered)
+		} else {
+			query = query.Where("short_code IN ?", chunk)
+		}
+
+		var existing []string
+		if err := query.Pluck("short_code", &existing).Error; err != nil {
+			return nil, err
+		}
+		found = append(found, existing...)
+	}
+	return found, nil
+}
+
+// FindLinkByCanonicalURL récupère le plus ancien lien encore utilisable d'un propriétaire sur un domaine
+// dont l'URL longue a la forme canonique donnée. Les liens à usage limité épuisés sont ignorés.
+// Il renvoie gorm.ErrRecordNotFound si aucun lien ne correspond.
+func (r *GormLinkRepository) FindLinkByCanonicalURL(domainID uint, owner, canonicalURL string) (*models.Link, error) {
+	var link models.Link
+	err := r.withRelations().
+		Where("domain_id = ? AND owner = ? AND canonical_url = ?", domainID, owner, canonicalURL).
+		Where("max_uses = 0 OR use_count < max_uses").
+		Order("id").First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// withRelations précharge les règles de ciblage (dans l'ordre d'évaluation) et les variantes A/B d'un lien.
+func (r *GormLinkRepository) withRelations() *gorm.DB {
+	return r.db.Preload("TargetingRules", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// UpdateLinkFields met à jour uniquement les colonnes fournies d'un lien.
+// On évite volontairement db.Save() qui réécrirait use_count et écraserait les réservations concurrentes.
+func (r *GormLinkRepository) UpdateLinkFields(linkID uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.Link{}).Where("id = ?", linkID).Updates(fields).Error
+}
+
+// ReplaceTargetingRules remplace l'ensemble des règles de ciblage d'un lien dans une transaction.
+func (r *GormLinkRepository) ReplaceTargetingRules(linkID uint, rules []models.TargetingRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.TargetingRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		for i := range rules {
+			rules[i].ID = 0
+			rules[i].LinkID = linkID
+		}
+		return tx.Create(&rules).Error
+	})
+}
+
+// ReplaceVariants remplace l'ensemble des variantes A/B d'un lien dans une transaction.
+func (r *GormLinkRepository) ReplaceVariants(linkID uint, variants []models.LinkVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.LinkVariant{}).Error; err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		for i := range variants {
+			variants[i].ID = 0
+			variants[i].LinkID = linkID
+		}
+		return tx.Create(&variants).Error
+	})
+}
+
 // GetAllLinks récupère tous les liens de la base de données.
 func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
 	var links []models.Link
@@ -49,6 +179,29 @@ func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
 	return links, nil
 }
 
+// FindLinksByUTM récupère les liens dont les paramètres UTM correspondent aux critères renseignés.
+// Les critères vides ne filtrent pas.
+func (r *GormLinkRepository) FindLinksByUTM(filter models.UTMParams) ([]models.Link, error) {
+	query := r.db.Model(&models.Link{})
+	for column, value := range map[string]string{
+		"utm_source":   filter.Source,
+		"utm_medium":   filter.Medium,
+		"utm_campaign": filter.Campaign,
+		"utm_term":     filter.Term,
+		"utm_content":  filter.Content,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	var links []models.Link
+	if err := query.Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
 // CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
 func (r *GormLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
 	var count int64
@@ -58,3 +211,39 @@ func (r *GormLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
 	}
 	return int(count), nil
 }
+
+// ClaimLinkUse consomme atomiquement une utilisation d'un lien limité.
+// La condition et l'incrément sont exécutés dans une seule requête UPDATE, ce qui garantit
+// que deux requêtes concurrentes ne peuvent jamais dépasser MaxUses.
+// Il renvoie false si le lien a déjà atteint sa limite.
+func (r *GormLinkRepository) ClaimLinkUse(linkID uint) (bool, error) {
+	result := r.db.Model(&models.Link{}).
+		Where("id = ? AND (max_uses = 0 OR use_count < max_uses)", linkID).
+		UpdateColumn("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// StreamLinks parcourt par lots, dans l'ordre des IDs, les liens créés entre since (inclus) et until (exclu),
+// sans leurs règles ni leurs variantes. Une borne nulle n'est pas appliquée. Le parcours s'arrête à la première erreur de fn.
+func (r *GormLinkRepository) StreamLinks(since, until time.Time, fn func(link *models.Link) error) error {
+	query := r.db.Model(&models.Link{})
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", storedTime(since))
+	}
+	if !until.IsZero() {
+		query = query.Where("created_at < ?", storedTime(until))
+	}
+
+	var links []models.Link
+	return query.FindInBatches(&links, streamBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range links {
+			if err := fn(&links[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,691 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// MemoryStore contient toutes les données du mode en mémoire (database.driver: memory).
+// Les repositories en mémoire partagent un même MemoryStore, protégé par un unique verrou :
+// les opérations sont donc atomiques entre elles, comme des transactions.
+// Les données sont copiées à l'entrée et à la sortie, si bien que les appelants ne partagent jamais d'état.
+// Les recherches de la redirection (lien par code, domaine par hôte, clics d'un lien) passent par des index,
+// comme les index de la base : leur coût ne dépend pas du nombre de liens ou de clics enregistrés.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	links    map[uint]*models.Link
+	clicks   []models.Click // Dans l'ordre d'enregistrement
+	domains  map[uint]*models.Domain
+	counters map[string]uint64
+	idemKeys map[idempotencyScopeKey]*models.IdempotencyKey
+
+	linksByCode      map[linkCodeKey]uint        // (domaine, code) -> lien
+	linksByFoldCode  map[linkCodeKey][]uint      // (domaine, code en minuscules) -> liens, par ID croissant
+	linksByCanonical map[linkCanonicalKey][]uint // (domaine, propriétaire, URL canonique) -> liens, par ID croissant
+	clicksByLink     map[uint][]int              // lien -> positions de ses clics dans clicks
+	domainsByHost    map[string]uint             // hôte -> domaine
+
+	nextLinkID, nextRuleID, nextVariantID, nextClickID, nextDomainID uint
+}
+
+// linkCodeKey identifie un code court sur un domaine.
+type linkCodeKey struct {
+	domainID uint
+	code     string
+}
+
+// linkCanonicalKey regroupe les liens candidats à la déduplication.
+type linkCanonicalKey struct {
+	domainID            uint
+	owner, canonicalURL string
+}
+
+// NewMemoryStore crée un stockage en mémoire vide.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		links:    make(map[uint]*models.Link),
+		domains:  make(map[uint]*models.Domain),
+		counters: make(map[string]uint64),
+		idemKeys: make(map[idempotencyScopeKey]*models.IdempotencyKey),
+
+		linksByCode:      make(map[linkCodeKey]uint),
+		linksByFoldCode:  make(map[linkCodeKey][]uint),
+		linksByCanonical: make(map[linkCanonicalKey][]uint),
+		clicksByLink:     make(map[uint][]int),
+		domainsByHost:    make(map[string]uint),
+	}
+}
+
+// indexClicks reconstruit l'index des clics par lien après la suppression de clics. Le verrou doit être détenu.
+func (s *MemoryStore) indexClicks() {
+	s.clicksByLink = make(map[uint][]int)
+	for i, click := range s.clicks {
+		s.clicksByLink[click.LinkID] = append(s.clicksByLink[click.LinkID], i)
+	}
+}
+
+// linkClicks renvoie les clics d'un lien, dans l'ordre d'enregistrement. Le verrou doit être détenu.
+func (s *MemoryStore) linkClicks(linkID uint, fn func(click *models.Click)) {
+	for _, i := range s.clicksByLink[linkID] {
+		fn(&s.clicks[i])
+	}
+}
+
+// copyLink renvoie une copie du lien, règles et variantes comprises.
+func copyLink(link *models.Link) *models.Link {
+	copied := *link
+	copied.TargetingRules = append([]models.TargetingRule(nil), link.TargetingRules...)
+	copied.Variants = append([]models.LinkVariant(nil), link.Variants...)
+	return &copied
+}
+
+// MemoryLinkRepository est l'implémentation en mémoire de LinkRepository.
+type MemoryLinkRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryLinkRepository crée un LinkRepository adossé au stockage en mémoire.
+func NewMemoryLinkRepository(store *MemoryStore) *MemoryLinkRepository {
+	return &MemoryLinkRepository{store: store}
+}
+
+// CreateLink enregistre un lien avec ses règles de ciblage et ses variantes, en leur attribuant un ID.
+// Il renvoie gorm.ErrDuplicatedKey si le code court existe déjà sur le domaine.
+func (r *MemoryLinkRepository) CreateLink(link *models.Link) error {
+	return r.CreateLinks([]*models.Link{link})
+}
+
+// CreateLinks enregistre plusieurs liens d'un coup : si l'un des codes courts existe déjà sur son domaine
+// (ou apparaît deux fois), gorm.ErrDuplicatedKey est renvoyée et aucun lien n'est enregistré.
+func (r *MemoryLinkRepository) CreateLinks(links []*models.Link) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	batch := make(map[linkCodeKey]bool, len(links))
+	for _, link := range links {
+		key := linkCodeKey{link.DomainID, link.ShortCode}
+		if _, exists := r.store.linksByCode[key]; exists || batch[key] {
+			return gorm.ErrDuplicatedKey
+		}
+		batch[key] = true
+	}
+
+	for _, link := range links {
+		r.store.nextLinkID++
+		link.ID = r.store.nextLinkID
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = time.Now()
+		}
+		if link.RedirectStatus == 0 {
+			link.RedirectStatus = models.DefaultRedirectStatus
+		}
+		if link.QueryConflict == "" {
+			link.QueryConflict = models.QueryConflictLink
+		}
+		r.assignRules(link.ID, link.TargetingRules)
+		r.assignVariants(link.ID, link.Variants)
+
+		r.store.links[link.ID] = copyLink(link)
+		r.store.linksByCode[linkCodeKey{link.DomainID, link.ShortCode}] = link.ID
+		foldKey := linkCodeKey{link.DomainID, strings.ToLower(link.ShortCode)}
+		r.store.linksByFoldCode[foldKey] = append(r.store.linksByFoldCode[foldKey], link.ID)
+		canonicalKey := linkCanonicalKey{link.DomainID, link.Owner, link.CanonicalURL}
+		r.store.linksByCanonical[canonicalKey] = append(r.store.linksByCanonical[canonicalKey], link.ID)
+	}
+	return nil
+}
+
+// assignRules attribue un ID aux règles d'un lien. Le verrou doit être détenu.
+func (r *MemoryLinkRepository) assignRules(linkID uint, rules []models.TargetingRule) {
+	for i := range rules {
+		r.store.nextRuleID++
+		rules[i].ID = r.store.nextRuleID
+		rules[i].LinkID = linkID
+	}
+}
+
+// assignVariants attribue un ID aux variantes d'un lien. Le verrou doit être détenu.
+func (r *MemoryLinkRepository) assignVariants(linkID uint, variants []models.LinkVariant) {
+	for i := range variants {
+		r.store.nextVariantID++
+		variants[i].ID = r.store.nextVariantID
+		variants[i].LinkID = linkID
+	}
+}
+
+// GetLinkByShortCode récupère un lien par domaine et code court, avec ses règles triées par position.
+func (r *MemoryLinkRepository) GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	id, ok := r.store.linksByCode[linkCodeKey{domainID, shortCode}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.withRelations(r.store.links[id]), nil
+}
+
+// GetLinkByShortCodeFold récupère le plus ancien lien dont le code correspond sans tenir compte de la casse.
+func (r *MemoryLinkRepository) GetLinkByShortCodeFold(domainID uint, shortCode string) (*models.Link, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := r.store.linksByFoldCode[linkCodeKey{domainID, strings.ToLower(shortCode)}]
+	if len(ids) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.withRelations(r.store.links[ids[0]]), nil
+}
+
+// FindShortCodes renvoie, parmi codes, ceux qui sont déjà enregistrés sur le domaine (sans tenir compte de la casse avec fold).
+func (r *MemoryLinkRepository) FindShortCodes(domainID uint, codes []string, fold bool) ([]string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var found []string
+	seen := make(map[uint]bool)
+	for _, code := range codes {
+		var ids []uint
+		if fold {
+			ids = r.store.linksByFoldCode[linkCodeKey{domainID, strings.ToLower(code)}]
+		} else if id, ok := r.store.linksByCode[linkCodeKey{domainID, code}]; ok {
+			ids = []uint{id}
+		}
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				found = append(found, r.store.links[id].ShortCode)
+			}
+		}
+	}
+	return found, nil
+}
+
+// FindLinkByCanonicalURL récupère le plus ancien lien encore utilisable d'un propriétaire pour une URL canonique.
+func (r *MemoryLinkRepository) FindLinkByCanonicalURL(domainID uint, owner, canonicalURL string) (*models.Link, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, id := range r.store.linksByCanonical[linkCanonicalKey{domainID, owner, canonicalURL}] {
+		if link := r.store.links[id]; !link.IsExhausted() {
+			return r.withRelations(link), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// withRelations renvoie une copie du lien avec ses règles triées par position. Le verrou doit être détenu.
+func (r *MemoryLinkRepository) withRelations(found *models.Link) *models.Link {
+	link := copyLink(found)
+	sort.SliceStable(link.TargetingRules, func(i, j int) bool {
+		a, b := link.TargetingRules[i], link.TargetingRules[j]
+		return a.Position < b.Position || a.Position == b.Position && a.ID < b.ID
+	})
+	return link
+}
+
+// GetAllLinks récupère tous les liens, sans leurs règles ni leurs variantes, triés par ID.
+func (r *MemoryLinkRepository) GetAllLinks() ([]models.Link, error) {
+	return r.filterLinks(func(*models.Link) bool { return true }), nil
+}
+
+// FindLinksByUTM récupère les liens dont les paramètres UTM renseignés dans le filtre correspondent.
+func (r *MemoryLinkRepository) FindLinksByUTM(filter models.UTMParams) ([]models.Link, error) {
+	matches := func(value, expected string) bool { return expected == "" || value == expected }
+	return r.filterLinks(func(link *models.Link) bool {
+		return matches(link.UTM.Source, filter.Source) &&
+			matches(link.UTM.Medium, filter.Medium) &&
+			matches(link.UTM.Campaign, filter.Campaign) &&
+			matches(link.UTM.Term, filter.Term) &&
+			matches(link.UTM.Content, filter.Content)
+	}), nil
+}
+
+// filterLinks renvoie les liens qui satisfont match, sans relations, triés par ID.
+func (r *MemoryLinkRepository) filterLinks(match func(link *models.Link) bool) []models.Link {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	links := make([]models.Link, 0, len(r.store.links))
+	for _, link := range r.store.links {
+		if match(link) {
+			copied := *link
+			copied.TargetingRules, copied.Variants = nil, nil
+			links = append(links, copied)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links
+}
+
+// StreamLinks parcourt dans l'ordre des IDs les liens créés entre since (inclus) et until (exclu), sans relations.
+// Les liens sont copiés avant le parcours : fn peut appeler les autres méthodes du repository.
+func (r *MemoryLinkRepository) StreamLinks(since, until time.Time, fn func(link *models.Link) error) error {
+	links := r.filterLinks(func(link *models.Link) bool { return inRange(link.CreatedAt, since, until) })
+	for i := range links {
+		if err := fn(&links[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inRange indique si t est compris entre since (inclus) et until (exclu), une borne nulle n'étant pas appliquée.
+func inRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+// CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
+func (r *MemoryLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
+	return NewMemoryClickRepository(r.store).CountClicksByLinkID(linkID)
+}
+
+// ClaimLinkUse consomme atomiquement une utilisation d'un lien limité, sous le verrou du stockage.
+// Il renvoie false si le lien a déjà atteint sa limite ou n'existe pas.
+func (r *MemoryLinkRepository) ClaimLinkUse(linkID uint) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	link, ok := r.store.links[linkID]
+	if !ok || link.IsExhausted() {
+		return false, nil
+	}
+	link.UseCount++
+	return true, nil
+}
+
+// UpdateLinkFields met à jour les réglages fournis d'un lien, désignés par leur nom de colonne.
+func (r *MemoryLinkRepository) UpdateLinkFields(linkID uint, fields map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	link, ok := r.store.links[linkID]
+	if !ok {
+		return nil // Comme un UPDATE SQL : aucune ligne modifiée
+	}
+	updated := *link
+	for column, value := range fields {
+		var ok bool
+		switch column {
+		case "redirect_status":
+			updated.RedirectStatus, ok = value.(int)
+		case "interstitial":
+			updated.Interstitial, ok = value.(bool)
+		case "pass_query":
+			updated.PassQuery, ok = value.(bool)
+		case "query_conflict":
+			updated.QueryConflict, ok = value.(string)
+		case "pass_path":
+			updated.PassPath, ok = value.(bool)
+		case "sticky_variant":
+			updated.StickyVariant, ok = value.(bool)
+		}
+		if !ok {
+			return fmt.Errorf("memory repository: unsupported update of column %q with %T", column, value)
+		}
+	}
+	*link = updated
+	return nil
+}
+
+// ReplaceTargetingRules remplace l'ensemble des règles de ciblage d'un lien.
+func (r *MemoryLinkRepository) ReplaceTargetingRules(linkID uint, rules []models.TargetingRule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	link, ok := r.store.links[linkID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.assignRules(linkID, rules)
+	link.TargetingRules = append([]models.TargetingRule(nil), rules...)
+	return nil
+}
+
+// ReplaceVariants remplace l'ensemble des variantes A/B d'un lien.
+func (r *MemoryLinkRepository) ReplaceVariants(linkID uint, variants []models.LinkVariant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	link, ok := r.store.links[linkID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.assignVariants(linkID, variants)
+	link.Variants = append([]models.LinkVariant(nil), variants...)
+	return nil
+}
+
+// MemoryClickRepository est l'implémentation en mémoire de ClickRepository.
+type MemoryClickRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryClickRepository crée un ClickRepository adossé au stockage en mémoire.
+func NewMemoryClickRepository(store *MemoryStore) *MemoryClickRepository {
+	return &MemoryClickRepository{store: store}
+}
+
+// CreateClick enregistre un clic en lui attribuant un ID.
+func (r *MemoryClickRepository) CreateClick(click *models.Click) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.nextClickID++
+	click.ID = r.store.nextClickID
+	stored := *click
+	stored.Link = models.Link{}
+	r.store.clicksByLink[click.LinkID] = append(r.store.clicksByLink[click.LinkID], len(r.store.clicks))
+	r.store.clicks = append(r.store.clicks, stored)
+	return nil
+}
+
+// CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
+func (r *MemoryClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
+	counts, err := r.CountClicksByLinkIDs([]uint{linkID})
+	return counts[linkID], err
+}
+
+// CountClicksByLinkIDs compte les clics de plusieurs liens. Les liens sans clic sont absents de la map.
+func (r *MemoryClickRepository) CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[uint]int, len(linkIDs))
+	for _, id := range linkIDs {
+		if count := len(r.store.clicksByLink[id]); count > 0 {
+			counts[id] = count
+		}
+	}
+	return counts, nil
+}
+
+// CountClicksByLocation compte les clics d'un lien par pays, région et ville, du plus grand nombre au plus petit.
+func (r *MemoryClickRepository) CountClicksByLocation(linkID uint) ([]LocationCount, error) {
+	r.store.mu.RLock()
+	counts := make(map[LocationCount]int)
+	r.store.linkClicks(linkID, func(click *models.Click) {
+		counts[LocationCount{Country: click.Country, Region: click.Region, City: click.City}]++
+	})
+	r.store.mu.RUnlock()
+
+	rows := make([]LocationCount, 0, len(counts))
+	for location, count := range counts {
+		location.Count = count
+		rows = append(rows, location)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.City < b.City
+	})
+	return rows, nil
+}
+
+// CountClicksByVariant compte les clics d'un lien par variante A/B. Les clics sans variante ne sont pas comptés.
+func (r *MemoryClickRepository) CountClicksByVariant(linkID uint) (map[uint]int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[uint]int)
+	r.store.linkClicks(linkID, func(click *models.Click) {
+		if click.VariantID != nil {
+			counts[*click.VariantID]++
+		}
+	})
+	return counts, nil
+}
+
+// CountClicksByIPAddresses compte les clics dont l'adresse IP enregistrée fait partie de addresses.
+func (r *MemoryClickRepository) CountClicksByIPAddresses(addresses []string) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matches := addressSet(addresses)
+	var count int64
+	for _, click := range r.store.clicks {
+		if matches[click.IPAddress] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FindLinkIDsByIPAddresses renvoie, par ID croissant, les liens ayant des clics dont l'adresse IP enregistrée fait partie de addresses.
+func (r *MemoryClickRepository) FindLinkIDsByIPAddresses(addresses []string) ([]uint, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matches := addressSet(addresses)
+	seen := make(map[uint]bool)
+	var linkIDs []uint
+	for _, click := range r.store.clicks {
+		if matches[click.IPAddress] && !seen[click.LinkID] {
+			seen[click.LinkID] = true
+			linkIDs = append(linkIDs, click.LinkID)
+		}
+	}
+	sort.Slice(linkIDs, func(i, j int) bool { return linkIDs[i] < linkIDs[j] })
+	return linkIDs, nil
+}
+
+// DeleteClicksByIPAddresses supprime les clics dont l'adresse IP enregistrée fait partie de addresses.
+func (r *MemoryClickRepository) DeleteClicksByIPAddresses(addresses []string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	matches := addressSet(addresses)
+	kept := r.store.clicks[:0]
+	for _, click := range r.store.clicks {
+		if !matches[click.IPAddress] {
+			kept = append(kept, click)
+		}
+	}
+	deleted := int64(len(r.store.clicks) - len(kept))
+	r.store.clicks = kept
+	if deleted > 0 {
+		r.store.indexClicks()
+	}
+	return deleted, nil
+}
+
+// StreamClicks parcourt dans l'ordre d'enregistrement les clics survenus entre since (inclus) et until (exclu).
+// Les clics sont copiés avant le parcours : fn peut appeler les autres méthodes du repository.
+func (r *MemoryClickRepository) StreamClicks(since, until time.Time, fn func(click *models.Click) error) error {
+	r.store.mu.RLock()
+	var clicks []models.Click
+	for _, click := range r.store.clicks {
+		if inRange(click.Timestamp, since, until) {
+			clicks = append(clicks, click)
+		}
+	}
+	r.store.mu.RUnlock()
+
+	for i := range clicks {
+		if err := fn(&clicks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressSet indexe les adresses recherchées.
+func addressSet(addresses []string) map[string]bool {
+	set := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		set[address] = true
+	}
+	return set
+}
+
+// MemoryDomainRepository est l'implémentation en mémoire de DomainRepository.
+type MemoryDomainRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryDomainRepository crée un DomainRepository adossé au stockage en mémoire.
+func NewMemoryDomainRepository(store *MemoryStore) *MemoryDomainRepository {
+	return &MemoryDomainRepository{store: store}
+}
+
+// CreateDomain enregistre un domaine. Il renvoie gorm.ErrDuplicatedKey si l'hôte existe déjà.
+func (r *MemoryDomainRepository) CreateDomain(domain *models.Domain) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.domainsByHost[domain.Host]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	r.store.nextDomainID++
+	domain.ID = r.store.nextDomainID
+	if domain.CreatedAt.IsZero() {
+		domain.CreatedAt = time.Now()
+	}
+	stored := *domain
+	r.store.domains[domain.ID] = &stored
+	r.store.domainsByHost[domain.Host] = domain.ID
+	return nil
+}
+
+// GetDomainByHost récupère un domaine par son nom d'hôte, ou gorm.ErrRecordNotFound.
+func (r *MemoryDomainRepository) GetDomainByHost(host string) (*models.Domain, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	id, ok := r.store.domainsByHost[host]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *r.store.domains[id]
+	return &found, nil
+}
+
+// GetDomainByID récupère un domaine par son identifiant, ou gorm.ErrRecordNotFound.
+func (r *MemoryDomainRepository) GetDomainByID(id uint) (*models.Domain, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	domain, ok := r.store.domains[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *domain
+	return &found, nil
+}
+
+// GetAllDomains récupère tous les domaines, triés par nom d'hôte.
+func (r *MemoryDomainRepository) GetAllDomains() ([]models.Domain, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	domains := make([]models.Domain, 0, len(r.store.domains))
+	for _, domain := range r.store.domains {
+		domains = append(domains, *domain)
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Host < domains[j].Host })
+	return domains, nil
+}
+
+// MemoryCounterRepository est l'implémentation en mémoire de CounterRepository.
+type MemoryCounterRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryCounterRepository crée un CounterRepository adossé au stockage en mémoire.
+func NewMemoryCounterRepository(store *MemoryStore) *MemoryCounterRepository {
+	return &MemoryCounterRepository{store: store}
+}
+
+// NextValue incrémente le compteur et renvoie sa nouvelle valeur.
+func (r *MemoryCounterRepository) NextValue(name string) (uint64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.counters[name]++
+	return r.store.counters[name], nil
+}
+
+// idempotencyScopeKey identifie une clé d'idempotence dans le stockage en mémoire.
+type idempotencyScopeKey struct {
+	scope, key string
+}
+
+// MemoryIdempotencyRepository est l'implémentation en mémoire de IdempotencyRepository.
+type MemoryIdempotencyRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryIdempotencyRepository crée un IdempotencyRepository adossé au stockage en mémoire.
+func NewMemoryIdempotencyRepository(store *MemoryStore) *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{store: store}
+}
+
+// ReserveIdempotencyKey enregistre la clé comme "en cours" si elle est libre (absente ou expirée) et renvoie true,
+// sinon renvoie une copie de la clé enregistrée avec false.
+func (r *MemoryIdempotencyRepository) ReserveIdempotencyKey(record *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := idempotencyScopeKey{record.Scope, record.Key}
+	if existing, ok := r.store.idemKeys[id]; ok && existing.ExpiresAt.After(now) {
+		copied := *existing
+		return &copied, false, nil
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	copied := *record
+	r.store.idemKeys[id] = &copied
+	return record, true, nil
+}
+
+// CompleteIdempotencyKey enregistre la réponse de la requête d'une clé réservée.
+func (r *MemoryIdempotencyRepository) CompleteIdempotencyKey(scope, key string, statusCode int, response []byte) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing, ok := r.store.idemKeys[idempotencyScopeKey{scope, key}]; ok {
+		existing.StatusCode = statusCode
+		existing.Response = append([]byte(nil), response...)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey supprime une clé.
+func (r *MemoryIdempotencyRepository) ReleaseIdempotencyKey(scope, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.idemKeys, idempotencyScopeKey{scope, key})
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys supprime les clés expirées et renvoie leur nombre.
+func (r *MemoryIdempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, existing := range r.store.idemKeys {
+		if !existing.ExpiresAt.After(now) {
+			delete(r.store.idemKeys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/migrations"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Tests d'intégration PostgreSQL, exécutés seulement si DATABASE_URL désigne une base jetable :
+// son schéma est entièrement annulé puis recréé avant chaque test.
+func init() {
+	if os.Getenv("DATABASE_URL") != "" {
+		backends = append(backends, backend{name: "postgres", open: func(t *testing.T) repository.Repositories {
+			return repository.NewGormRepositories(openPostgres(t))
+		}})
+	}
+}
+
+// openPostgres ouvre la base DATABASE_URL avec un schéma vierge à la dernière version.
+func openPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Down(db, int(migrations.Latest()), false); err != nil {
+		t.Fatalf("migrations.Down: %v", err)
+	}
+	if _, err := migrations.Up(db, false); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	return db
+}
+
+// TestPostgresClaimLinkUseConcurrency vérifie que la réservation atomique tient face à des
+// redirections simultanées réparties sur plusieurs connexions.
+func TestPostgresClaimLinkUseConcurrency(t *testing.T) {
+	db := openPostgres(t)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(20)
+	repos := repository.NewGormRepositories(db)
+
+	const maxUses, attempts = 25, 200
+	link := mustCreateLink(t, repos, &models.Link{ShortCode: "rush", LongURL: "https://example.com", MaxUses: maxUses})
+
+	var wg sync.WaitGroup
+	var claimed atomic.Int64
+	start := make(chan struct{})
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			ok, err := repos.Links.ClaimLinkUse(link.ID)
+			if err != nil {
+				t.Errorf("ClaimLinkUse: %v", err)
+			}
+			if ok {
+				claimed.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := claimed.Load(); got != maxUses {
+		t.Errorf("claimed %d uses, want %d", got, maxUses)
+	}
+	got, err := repos.Links.GetLinkByShortCode(0, "rush")
+	if err != nil {
+		t.Fatalf("GetLinkByShortCode: %v", err)
+	}
+	if got.UseCount != maxUses {
+		t.Errorf("UseCount = %d, want %d", got.UseCount, maxUses)
+	}
+}
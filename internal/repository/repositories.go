@@ -0,0 +1,38 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories regroupe les repositories utilisés par le serveur, quel que soit le stockage.
+type Repositories struct {
+	Links       LinkRepository
+	Clicks      ClickRepository
+	Domains     DomainRepository
+	Counters    CounterRepository
+	Idempotency IdempotencyRepository
+	Rollups     RollupRepository // nil en mémoire : les statistiques y sont calculées sur les clics bruts
+}
+
+// NewGormRepositories crée les repositories adossés à une base de données GORM (SQLite ou PostgreSQL).
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Links:       NewLinkRepository(db),
+		Clicks:      NewClickRepository(db),
+		Domains:     NewDomainRepository(db),
+		Counters:    NewCounterRepository(db),
+		Idempotency: NewIdempotencyRepository(db),
+		Rollups:     NewRollupRepository(db),
+	}
+}
+
+// NewMemoryRepositories crée des repositories partageant un même stockage en mémoire, vide au démarrage.
+// Les données sont perdues à l'arrêt : ce mode est destiné aux démonstrations et aux tests de charge.
+func NewMemoryRepositories() Repositories {
+	store := NewMemoryStore()
+	return Repositories{
+		Links:       NewMemoryLinkRepository(store),
+		Clicks:      NewMemoryClickRepository(store),
+		Domains:     NewMemoryDomainRepository(store),
+		Counters:    NewMemoryCounterRepository(store),
+		Idempotency: NewMemoryIdempotencyRepository(store),
+	}
+}
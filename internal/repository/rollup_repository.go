@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RollupRepository définit l'accès aux agrégats horaires et journaliers des clics.
+type RollupRepository interface {
+	GetWatermark(granularity string) (time.Time, error)
+	EarliestClickTime() (time.Time, error)
+	NextClickTime(from time.Time) (time.Time, error)
+	SumClicks(from, until time.Time) ([]models.ClickRollup, error)
+	SumHourlyRollups(from, until time.Time) ([]models.ClickRollup, error)
+	ReplaceRollups(granularity string, from, until time.Time, rollups []models.ClickRollup) error
+	CountClicksBefore(until time.Time) (int64, error)
+	PurgeClickHour(hour time.Time, late bool) (int64, error)
+}
+
+// GormRollupRepository est l'implémentation de RollupRepository utilisant GORM.
+type GormRollupRepository struct {
+	db *gorm.DB
+}
+
+// NewRollupRepository crée et retourne une nouvelle instance de GormRollupRepository.
+func NewRollupRepository(db *gorm.DB) *GormRollupRepository {
+	return &GormRollupRepository{db: db}
+}
+
+// rollupTable renvoie la table d'agrégats d'une granularité.
+func rollupTable(granularity string) string {
+	if granularity == models.RollupDaily {
+		return models.DailyRollupTable
+	}
+	return models.HourlyRollupTable
+}
+
+// storedTime convertit une borne dans le fuseau des horodatages enregistrés (clics, créations de liens),
+// qui sont à l'heure locale du serveur : SQLite compare les dates sous forme de texte.
+func storedTime(t time.Time) time.Time {
+	return t.In(time.Local)
+}
+
+// GetWatermark renvoie la fin de la période déjà agrégée pour une granularité (zéro si jamais agrégée).
+func (r *GormRollupRepository) GetWatermark(granularity string) (time.Time, error) {
+	var watermark models.RollupWatermark
+	err := r.db.Where("granularity = ?", granularity).Limit(1).Find(&watermark).Error
+	return watermark.Until.UTC(), err
+}
+
+// EarliestClickTime renvoie l'horodatage du plus ancien clic enregistré (zéro s'il n'y en a aucun).
+func (r *GormRollupRepository) EarliestClickTime() (time.Time, error) {
+	return r.NextClickTime(time.Time{})
+}
+
+// NextClickTime renvoie l'horodatage du plus ancien clic enregistré à partir de from (zéro s'il n'y en a aucun).
+func (r *GormRollupRepository) NextClickTime(from time.Time) (time.Time, error) {
+	var click models.Click
+	query := r.db.Select("timestamp").Order("timestamp").Limit(1)
+	if !from.IsZero() {
+		query = query.Where("timestamp >= ?", storedTime(from))
+	}
+	err := query.Find(&click).Error
+	return click.Timestamp.UTC(), err
+}
+
+// SumClicks compte les clics bruts de la période [from, until) par lien, localisation et variante.
+// Le champ Bucket des agrégats renvoyés n'est pas renseigné.
+func (r *GormRollupRepository) SumClicks(from, until time.Time) ([]models.ClickRollup, error) {
+	return sumClicks(r.db, from, until)
+}
+
+func sumClicks(db *gorm.DB, from, until time.Time) ([]models.ClickRollup, error) {
+	var rollups []models.ClickRollup
+	err := db.Model(&models.Click{}).
+		Select("link_id, country, region, city, COALESCE(variant_id, 0) AS variant_id, COUNT(*) AS clicks").
+		Where("timestamp >= ? AND timestamp < ?", storedTime(from), storedTime(until)).
+		Group("link_id, country, region, city, COALESCE(variant_id, 0)").
+		Scan(&rollups).Error
+	return rollups, err
+}
+
+// SumHourlyRollups additionne les agrégats horaires de la période [from, until) par lien, localisation et variante.
+// Le champ Bucket des agrégats renvoyés n'est pas renseigné.
+func (r *GormRollupRepository) SumHourlyRollups(from, until time.Time) ([]models.ClickRollup, error) {
+	return sumHourlyRollups(r.db, from, until)
+}
+
+func sumHourlyRollups(db *gorm.DB, from, until time.Time) ([]models.ClickRollup, error) {
+	var rollups []models.ClickRollup
+	err := db.Table(models.HourlyRollupTable).
+		Select("link_id, country, region, city, variant_id, SUM(clicks) AS clicks").
+		Where("bucket >= ? AND bucket < ?", from.UTC(), until.UTC()).
+		Group("link_id, country, region, city, variant_id").
+		Scan(&rollups).Error
+	return rollups, err
+}
+
+// ReplaceRollups remplace les agrégats d'une granularité sur la période [from, until) et avance
+// sa position d'agrégation à until, dans une transaction : une agrégation interrompue peut être relancée.
+func (r *GormRollupRepository) ReplaceRollups(granularity string, from, until time.Time, rollups []models.ClickRollup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := replaceRollups(tx, rollupTable(granularity), from, until, rollups); err != nil {
+			return err
+		}
+		return setWatermark(tx, granularity, until)
+	})
+}
+
+// replaceRollups remplace les agrégats d'une table sur la période [from, until).
+func replaceRollups(tx *gorm.DB, table string, from, until time.Time, rollups []models.ClickRollup) error {
+	if err := tx.Table(table).Where("bucket >= ? AND bucket < ?", from.UTC(), until.UTC()).Delete(&models.ClickRollup{}).Error; err != nil {
+		return err
+	}
+	if len(rollups) == 0 {
+		return nil
+	}
+	for i := range rollups {
+		rollups[i].ID = 0
+		rollups[i].Bucket = rollups[i].Bucket.UTC()
+	}
+	return tx.Table(table).CreateInBatches(&rollups, 500).Error
+}
+
+// addRollups ajoute les clics aux agrégats existants de mêmes dimensions, ou crée ceux qui manquent.
+func addRollups(tx *gorm.DB, table string, rollups []models.ClickRollup) error {
+	for _, rollup := range rollups {
+		result := tx.Table(table).
+			Where("link_id = ? AND bucket = ? AND country = ? AND region = ? AND city = ? AND variant_id = ?",
+				rollup.LinkID, rollup.Bucket.UTC(), rollup.Country, rollup.Region, rollup.City, rollup.VariantID).
+			Update("clicks", gorm.Expr("clicks + ?", rollup.Clicks))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+		rollup.ID = 0
+		rollup.Bucket = rollup.Bucket.UTC()
+		if err := tx.Table(table).Create(&rollup).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRollups porte les agrégats existants de mêmes dimensions au moins aux clics comptés, ou crée ceux qui manquent.
+// Un agrégat n'est jamais diminué : les clics bruts effacés depuis l'agrégation (erase-ip) restent comptés.
+func mergeRollups(tx *gorm.DB, table string, rollups []models.ClickRollup) error {
+	for _, rollup := range rollups {
+		result := tx.Table(table).
+			Where("link_id = ? AND bucket = ? AND country = ? AND region = ? AND city = ? AND variant_id = ?",
+				rollup.LinkID, rollup.Bucket.UTC(), rollup.Country, rollup.Region, rollup.City, rollup.VariantID).
+			Update("clicks", gorm.Expr("CASE WHEN clicks < ? THEN ? ELSE clicks END", rollup.Clicks, rollup.Clicks))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+		rollup.ID = 0
+		rollup.Bucket = rollup.Bucket.UTC()
+		if err := tx.Table(table).Create(&rollup).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setWatermark enregistre la position d'agrégation d'une granularité.
+func setWatermark(tx *gorm.DB, granularity string, until time.Time) error {
+	watermark := models.RollupWatermark{Granularity: granularity, Until: until.UTC()}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "granularity"}},
+		DoUpdates: clause.AssignmentColumns([]string{"until"}),
+	}).Create(&watermark).Error
+}
+
+// CountClicksBefore compte les clics bruts antérieurs à until.
+func (r *GormRollupRepository) CountClicksBefore(until time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Click{}).Where("timestamp < ?", storedTime(until)).Count(&count).Error
+	return count, err
+}
+
+// PurgeClickHour agrège puis supprime les clics bruts de l'heure [hour, hour+1h), dans une transaction, et renvoie
+// le nombre de clics supprimés. Si late est vrai, l'heure a déjà été purgée et ses clics bruts sont des clics tardifs,
+// ajoutés à ses agrégats ; sinon, ses agrégats sont fusionnés avec les clics bruts, ce qui compte aussi les clics
+// enregistrés après son agrégation sans décompter ceux effacés depuis (erase-ip), et la position de purge avance
+// à la fin de l'heure. Un clic tardif d'une localisation dont un clic a aussi été effacé n'est alors pas compté.
+// Si le jour de l'heure est déjà agrégé, son agrégat journalier est recalculé depuis les agrégats horaires.
+func (r *GormRollupRepository) PurgeClickHour(hour time.Time, late bool) (int64, error) {
+	hour = hour.UTC()
+	until := hour.Add(time.Hour)
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		sums, err := sumClicks(tx, hour, until)
+		if err != nil {
+			return err
+		}
+		if late {
+			err = addRollups(tx, models.HourlyRollupTable, withBucket(sums, hour))
+		} else {
+			err = mergeRollups(tx, models.HourlyRollupTable, withBucket(sums, hour))
+		}
+		if err != nil {
+			return err
+		}
+
+		var daily models.RollupWatermark
+		if err := tx.Where("granularity = ?", models.RollupDaily).Limit(1).Find(&daily).Error; err != nil {
+			return err
+		}
+		day := hour.Truncate(24 * time.Hour)
+		if day.Before(daily.Until.UTC()) {
+			daySums, err := sumHourlyRollups(tx, day, day.Add(24*time.Hour))
+			if err != nil {
+				return err
+			}
+			if err := replaceRollups(tx, models.DailyRollupTable, day, day.Add(24*time.Hour), withBucket(daySums, day)); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("timestamp >= ? AND timestamp < ?", storedTime(hour), storedTime(until)).Delete(&models.Click{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if late {
+			return nil
+		}
+		return setWatermark(tx, models.RollupPurged, until)
+	})
+	return deleted, err
+}
+
+// withBucket affecte la période bucket aux agrégats.
+func withBucket(rollups []models.ClickRollup, bucket time.Time) []models.ClickRollup {
+	for i := range rollups {
+		rollups[i].Bucket = bucket
+	}
+	return rollups
+}
@@ -2,11 +2,24 @@ package services
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/armanceau/go-url-shortener/internal/models"
 	"github.com/armanceau/go-url-shortener/internal/repository" // Importe le package repository
 )
 
+// CountryClicks est le nombre de clics pour un pays (code ISO, vide si inconnu).
+type CountryClicks struct {
+	Country string
+	Clicks  int
+}
+
+// GeoBreakdown est la répartition géographique des clics d'un lien.
+type GeoBreakdown struct {
+	Countries []CountryClicks            // Clics par pays, du plus grand nombre au plus petit
+	Locations []repository.LocationCount // Clics par pays, région et ville
+}
+
 // ClickService est une structure qui fournit des méthodes pour la logique métier des clics.
 type ClickService struct {
 	clickRepo repository.ClickRepository
@@ -37,3 +50,62 @@ func (s *ClickService) GetClicksCountByLinkID(linkID uint) (int, error) {
 	}
 	return count, nil
 }
+
+// GetClicksCountByLinkIDs récupère le nombre de clics de plusieurs liens en une seule requête.
+func (s *ClickService) GetClicksCountByLinkIDs(linkIDs []uint) (map[uint]int, error) {
+	counts, err := s.clickRepo.CountClicksByLinkIDs(linkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks for %d links: %w", len(linkIDs), err)
+	}
+	return counts, nil
+}
+
+// GetGeoBreakdown calcule la répartition géographique des clics d'un lien à partir des données GeoIP enregistrées.
+func (s *ClickService) GetGeoBreakdown(linkID uint) (*GeoBreakdown, error) {
+	locations, err := s.clickRepo.CountClicksByLocation(linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by location for linkID %d: %w", linkID, err)
+	}
+
+	// Agréger les localisations par pays, en conservant l'ordre décroissant des clics
+	byCountry := make(map[string]int)
+	var countries []CountryClicks
+	for _, location := range locations {
+		if _, seen := byCountry[location.Country]; !seen {
+			countries = append(countries, CountryClicks{Country: location.Country})
+		}
+		byCountry[location.Country] += location.Count
+	}
+	for i := range countries {
+		countries[i].Clicks = byCountry[countries[i].Country]
+	}
+	sort.SliceStable(countries, func(i, j int) bool { return countries[i].Clicks > countries[j].Clicks })
+
+	return &GeoBreakdown{Countries: countries, Locations: locations}, nil
+}
+
+// GetClicksCountByVariant récupère le nombre de clics par variante A/B d'un lien.
+func (s *ClickService) GetClicksCountByVariant(linkID uint) (map[uint]int, error) {
+	counts, err := s.clickRepo.CountClicksByVariant(linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by variant for linkID %d: %w", linkID, err)
+	}
+	return counts, nil
+}
+
+// EraseClicksByIP supprime les clics enregistrés sous l'une des formes d'une adresse IP (droit à l'effacement).
+// En essai à blanc (dryRun), les clics concernés sont seulement comptés.
+func (s *ClickService) EraseClicksByIP(storedForms []string, dryRun bool) (int64, error) {
+	if dryRun {
+		count, err := s.clickRepo.CountClicksByIPAddresses(storedForms)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count clicks by ip: %w", err)
+		}
+		return count, nil
+	}
+	deleted, err := s.clickRepo.DeleteClicksByIPAddresses(storedForms)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete clicks by ip: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+func TestGetGeoBreakdown(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	clicks := NewClickService(repos.Clicks)
+
+	for _, click := range []models.Click{
+		{Country: "FR", Region: "Île-de-France", City: "Paris"},
+		{Country: "FR", Region: "Île-de-France", City: "Paris"},
+		{Country: "FR", Region: "Île-de-France", City: "Paris"},
+		{Country: "FR", Region: "Occitanie", City: "Toulouse"},
+		{Country: "BE"},
+		{Country: "BE"},
+		{},
+	} {
+		click.LinkID = 1
+		click.Timestamp = time.Now()
+		if err := clicks.RecordClick(&click); err != nil {
+			t.Fatalf("RecordClick: %v", err)
+		}
+	}
+	if err := clicks.RecordClick(&models.Click{LinkID: 2, Timestamp: time.Now(), Country: "DE"}); err != nil {
+		t.Fatalf("RecordClick: %v", err)
+	}
+
+	geo, err := clicks.GetGeoBreakdown(1)
+	if err != nil {
+		t.Fatalf("GetGeoBreakdown: %v", err)
+	}
+	want := []CountryClicks{{"FR", 4}, {"BE", 2}, {"", 1}}
+	if len(geo.Countries) != len(want) {
+		t.Fatalf("Countries = %+v, want %+v", geo.Countries, want)
+	}
+	for i := range want {
+		if geo.Countries[i] != want[i] {
+			t.Errorf("Countries[%d] = %+v, want %+v", i, geo.Countries[i], want[i])
+		}
+	}
+	if len(geo.Locations) != 4 || geo.Locations[0].City != "Paris" || geo.Locations[0].Count != 3 {
+		t.Errorf("Locations = %+v, want 4 locations led by Paris with 3 clicks", geo.Locations)
+	}
+
+	empty, err := clicks.GetGeoBreakdown(3)
+	if err != nil || len(empty.Countries) != 0 || len(empty.Locations) != 0 {
+		t.Errorf("GetGeoBreakdown without clicks = %+v, %v, want an empty breakdown", empty, err)
+	}
+}
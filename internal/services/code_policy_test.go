@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"gorm.io/gorm"
+)
+
+// scriptedCodes est un générateur qui renvoie les codes fournis, dans l'ordre.
+type scriptedCodes struct {
+	codes []string
+}
+
+func (g *scriptedCodes) Generate(attempt int) (string, error) {
+	if len(g.codes) == 0 {
+		return "", errors.New("no more codes")
+	}
+	code := g.codes[0]
+	g.codes = g.codes[1:]
+	return code, nil
+}
+
+// newPolicyLinkService crée un LinkService en mémoire dont le générateur renvoie codes.
+func newPolicyLinkService(opts shortcode.Options, codes ...string) (*LinkService, repository.Repositories) {
+	repos := repository.NewMemoryRepositories()
+	service := NewLinkService(repos.Links, NewClickService(repos.Clicks), &scriptedCodes{codes: codes}, shortcode.NewPolicy(opts), canonical.New(nil))
+	return service, repos
+}
+
+func TestGeneratedCodesSkipBlockedWords(t *testing.T) {
+	service, _ := newPolicyLinkService(shortcode.Options{Blocklist: []string{"bad"}}, "xbadx", "b4d12", "api", "good12")
+
+	link, err := service.CreateLink("https://example.com", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if link.ShortCode != "good12" {
+		t.Errorf("ShortCode = %q, want the first code that is neither blocked nor reserved", link.ShortCode)
+	}
+
+	if err := service.CheckCustomCode("myBadCode"); !errors.Is(err, shortcode.ErrBlockedCode) {
+		t.Errorf("CheckCustomCode(myBadCode) error = %v, want ErrBlockedCode", err)
+	}
+	if err := service.CheckCustomCode("health"); !errors.Is(err, shortcode.ErrBlockedCode) {
+		t.Errorf("CheckCustomCode(health) error = %v, want ErrBlockedCode", err)
+	}
+	if err := service.CheckCustomCode("spring-sale"); err != nil {
+		t.Errorf("CheckCustomCode(spring-sale): %v", err)
+	}
+}
+
+func TestCaseInsensitiveLookup(t *testing.T) {
+	service, repos := newPolicyLinkService(shortcode.Options{CaseInsensitive: true}, "promo", "fresh1")
+	// Codes enregistrés avant l'activation du mode insensible à la casse
+	for _, link := range []*models.Link{
+		{ShortCode: "Promo", LongURL: "https://example.com/old"},
+		{ShortCode: "PROMO", LongURL: "https://example.com/upper"},
+	} {
+		if err := repos.Links.CreateLink(link); err != nil {
+			t.Fatalf("CreateLink: %v", err)
+		}
+	}
+
+	for code, want := range map[string]string{
+		"PROMO": "https://example.com/upper", // La correspondance exacte reste prioritaire
+		"promo": "https://example.com/old",   // Sinon, le plus ancien des codes de même casse repliée
+		"pRoMo": "https://example.com/old",
+	} {
+		link, err := service.GetLinkByShortCode(0, code)
+		if err != nil || link.LongURL != want {
+			t.Errorf("GetLinkByShortCode(%q) = %+v, %v, want %s", code, link, err, want)
+		}
+	}
+
+	// "promo" est déjà pris sans tenir compte de la casse : le générateur doit passer au code suivant
+	link, err := service.CreateLink("https://example.com/new", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if link.ShortCode != "fresh1" {
+		t.Errorf("ShortCode = %q, want fresh1", link.ShortCode)
+	}
+	if taken, err := service.TakenShortCodes(0, []string{"PrOmO", "other"}); err != nil || !taken["PrOmO"] || taken["other"] {
+		t.Errorf("TakenShortCodes = %v, %v, want only PrOmO taken", taken, err)
+	}
+}
+
+func TestCaseSensitiveLookup(t *testing.T) {
+	service, repos := newPolicyLinkService(shortcode.Options{})
+	if err := repos.Links.CreateLink(&models.Link{ShortCode: "Promo", LongURL: "https://example.com"}); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if _, err := service.GetLinkByShortCode(0, "promo"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetLinkByShortCode(promo) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if _, err := service.GetLinkByShortCode(0, "Promo"); err != nil {
+		t.Errorf("GetLinkByShortCode(Promo): %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+// ErrInvalidDomain est renvoyée lorsqu'un nom d'hôte ou une URL de base de domaine est invalide.
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// ErrDomainExists est renvoyée lorsqu'un domaine avec le même nom d'hôte est déjà enregistré.
+var ErrDomainExists = errors.New("domain already exists")
+
+// ErrUnknownDomain est renvoyée lorsqu'un domaine demandé explicitement n'est pas enregistré.
+var ErrUnknownDomain = errors.New("unknown domain")
+
+// DomainService fournit la logique métier des domaines personnalisés :
+// résolution du domaine d'une requête et construction des URLs courtes complètes.
+type DomainService struct {
+	domainRepo     repository.DomainRepository
+	defaultBaseURL string // Server.BaseURL, utilisé pour le domaine par défaut
+}
+
+// NewDomainService crée et retourne une nouvelle instance de DomainService.
+func NewDomainService(domainRepo repository.DomainRepository, defaultBaseURL string) *DomainService {
+	return &DomainService{
+		domainRepo:     domainRepo,
+		defaultBaseURL: strings.TrimRight(defaultBaseURL, "/"),
+	}
+}
+
+// NormalizeHost met un nom d'hôte sous sa forme canonique : minuscules, sans port ni point final.
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// CreateDomain enregistre un nouveau domaine personnalisé.
+// Si baseURL est vide, elle vaut "https://<host>".
+func (s *DomainService) CreateDomain(host, baseURL string) (*models.Domain, error) {
+	host = NormalizeHost(host)
+	if host == "" || strings.ContainsAny(host, "/:?#@ ") {
+		return nil, fmt.Errorf("%w: host %q", ErrInvalidDomain, host)
+	}
+
+	if baseURL == "" {
+		baseURL = "https://" + host
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: base URL %q must be an absolute http(s) URL", ErrInvalidDomain, baseURL)
+	}
+
+	if _, err := s.domainRepo.GetDomainByHost(host); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDomainExists, host)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("database error checking domain uniqueness: %w", err)
+	}
+
+	domain := &models.Domain{
+		Host:    host,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}
+	if err := s.domainRepo.CreateDomain(domain); err != nil {
+		return nil, fmt.Errorf("failed to create domain in database: %w", err)
+	}
+	return domain, nil
+}
+
+// ListDomains renvoie tous les domaines personnalisés enregistrés.
+func (s *DomainService) ListDomains() ([]models.Domain, error) {
+	domains, err := s.domainRepo.GetAllDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	return domains, nil
+}
+
+// ResolveHost renvoie le domaine correspondant à l'en-tête Host d'une requête de redirection.
+// Un hôte inconnu (ex: localhost, IP du serveur) correspond au domaine par défaut.
+func (s *DomainService) ResolveHost(host string) (uint, error) {
+	host = NormalizeHost(host)
+	if host == "" {
+		return models.DefaultDomainID, nil
+	}
+	domain, err := s.domainRepo.GetDomainByHost(host)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.DefaultDomainID, nil
+		}
+		return 0, fmt.Errorf("failed to resolve domain for host %s: %w", host, err)
+	}
+	return domain.ID, nil
+}
+
+// ResolveName renvoie le domaine choisi explicitement par son nom d'hôte (API, CLI).
+// Un nom vide désigne le domaine par défaut ; un nom inconnu renvoie ErrUnknownDomain.
+func (s *DomainService) ResolveName(name string) (uint, error) {
+	host := NormalizeHost(name)
+	if host == "" {
+		return models.DefaultDomainID, nil
+	}
+	domain, err := s.domainRepo.GetDomainByHost(host)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownDomain, host)
+		}
+		return 0, fmt.Errorf("failed to get domain %s: %w", host, err)
+	}
+	return domain.ID, nil
+}
+
+// BaseURL renvoie l'URL de base des liens courts d'un domaine.
+func (s *DomainService) BaseURL(domainID uint) (string, error) {
+	if domainID == models.DefaultDomainID {
+		return s.defaultBaseURL, nil
+	}
+	domain, err := s.domainRepo.GetDomainByID(domainID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get domain %d: %w", domainID, err)
+	}
+	return domain.BaseURL, nil
+}
+
+// FullShortURL construit l'URL courte complète d'un lien sur son domaine.
+func (s *DomainService) FullShortURL(link *models.Link) (string, error) {
+	baseURL, err := s.BaseURL(link.DomainID)
+	if err != nil {
+		return "", err
+	}
+	return baseURL + "/" + link.ShortCode, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"gorm.io/gorm"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host, want string
+	}{
+		{"go.example.com", "go.example.com"},
+		{" Go.Example.COM. ", "go.example.com"},
+		{"go.example.com:8080", "go.example.com"},
+		{"[::1]:8080", "::1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHost(tt.host); got != tt.want {
+			t.Errorf("NormalizeHost(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCreateDomain(t *testing.T) {
+	domains := NewDomainService(repository.NewMemoryRepositories().Domains, "http://sho.rt/")
+
+	domain, err := domains.CreateDomain("Go.Example.com", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+	if domain.Host != "go.example.com" || domain.BaseURL != "https://go.example.com" {
+		t.Errorf("CreateDomain = %+v, want the normalized host and an https base URL", domain)
+	}
+	custom, err := domains.CreateDomain("brand.test", "http://brand.test:8080/")
+	if err != nil {
+		t.Fatalf("CreateDomain with base URL: %v", err)
+	}
+	if custom.BaseURL != "http://brand.test:8080" {
+		t.Errorf("BaseURL = %q, want the trailing slash removed", custom.BaseURL)
+	}
+
+	if _, err := domains.CreateDomain("GO.example.com:443", ""); !errors.Is(err, ErrDomainExists) {
+		t.Errorf("duplicate host error = %v, want ErrDomainExists", err)
+	}
+	for _, tt := range []struct{ host, baseURL string }{
+		{"", ""},
+		{"example.com/path", ""},
+		{"user@example.com", ""},
+		{"ok.test", "ftp://ok.test"},
+		{"ok.test", "/relative"},
+	} {
+		if _, err := domains.CreateDomain(tt.host, tt.baseURL); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("CreateDomain(%q, %q) error = %v, want ErrInvalidDomain", tt.host, tt.baseURL, err)
+		}
+	}
+
+	list, err := domains.ListDomains()
+	if err != nil || len(list) != 2 {
+		t.Errorf("ListDomains = %+v, %v, want 2 domains", list, err)
+	}
+}
+
+func TestResolveDomain(t *testing.T) {
+	domains := NewDomainService(repository.NewMemoryRepositories().Domains, "http://sho.rt")
+	brand, err := domains.CreateDomain("brand.test", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+
+	for host, want := range map[string]uint{
+		"brand.test":      brand.ID,
+		"BRAND.test:8080": brand.ID,
+		"localhost:8080":  models.DefaultDomainID,
+		"":                models.DefaultDomainID,
+	} {
+		if got, err := domains.ResolveHost(host); err != nil || got != want {
+			t.Errorf("ResolveHost(%q) = %d, %v, want %d", host, got, err, want)
+		}
+	}
+
+	if got, err := domains.ResolveName("Brand.test"); err != nil || got != brand.ID {
+		t.Errorf("ResolveName(Brand.test) = %d, %v, want %d", got, err, brand.ID)
+	}
+	if got, err := domains.ResolveName(""); err != nil || got != models.DefaultDomainID {
+		t.Errorf("ResolveName(\"\") = %d, %v, want the default domain", got, err)
+	}
+	if _, err := domains.ResolveName("unknown.test"); !errors.Is(err, ErrUnknownDomain) {
+		t.Errorf("ResolveName(unknown.test) error = %v, want ErrUnknownDomain", err)
+	}
+
+	for _, tt := range []struct {
+		link models.Link
+		want string
+	}{
+		{models.Link{ShortCode: "abc"}, "http://sho.rt/abc"},
+		{models.Link{ShortCode: "abc", DomainID: brand.ID}, "https://brand.test/abc"},
+	} {
+		if got, err := domains.FullShortURL(&tt.link); err != nil || got != tt.want {
+			t.Errorf("FullShortURL(%+v) = %q, %v, want %q", tt.link, got, err, tt.want)
+		}
+	}
+}
+
+func TestShortCodesArePerDomain(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	brand, err := s.domainService.CreateDomain("brand.test", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+
+	for _, link := range []*models.Link{
+		{ShortCode: "promo", LongURL: "https://example.com/default"},
+		{ShortCode: "promo", LongURL: "https://example.com/brand", DomainID: brand.ID},
+	} {
+		if err := s.repos.Links.CreateLink(link); err != nil {
+			t.Fatalf("CreateLink(%+v): %v", link, err)
+		}
+	}
+	if err := s.repos.Links.CreateLink(&models.Link{ShortCode: "promo", LongURL: "https://example.com/again", DomainID: brand.ID}); err == nil {
+		t.Error("the same code was stored twice on a domain")
+	}
+
+	for domainID, want := range map[uint]string{models.DefaultDomainID: "https://example.com/default", brand.ID: "https://example.com/brand"} {
+		link, err := s.linkService.GetLinkByShortCode(domainID, "promo")
+		if err != nil || link.LongURL != want {
+			t.Errorf("GetLinkByShortCode(%d, promo) = %+v, %v, want %s", domainID, link, err, want)
+		}
+	}
+
+	other, err := s.domainService.CreateDomain("other.test", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+	if taken, err := s.linkService.TakenShortCodes(other.ID, []string{"promo"}); err != nil || taken["promo"] {
+		t.Errorf("TakenShortCodes on an empty domain = %v, %v, want promo free", taken, err)
+	}
+	if _, err := s.linkService.GetLinkByShortCode(other.ID, "promo"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetLinkByShortCode on another domain error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
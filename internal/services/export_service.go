@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/export"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+// ExportService exporte les liens et les clics en les lisant par lots depuis les repositories :
+// les données ne sont jamais chargées entièrement en mémoire.
+type ExportService struct {
+	linkRepo      repository.LinkRepository
+	clickRepo     repository.ClickRepository
+	domainService *DomainService
+}
+
+// NewExportService crée et retourne une nouvelle instance de ExportService.
+func NewExportService(linkRepo repository.LinkRepository, clickRepo repository.ClickRepository, domainService *DomainService) *ExportService {
+	return &ExportService{linkRepo: linkRepo, clickRepo: clickRepo, domainService: domainService}
+}
+
+// ExportLinks écrit dans w, au format demandé, les liens créés entre since (inclus) et until (exclu).
+// Il renvoie le nombre de liens exportés.
+func (s *ExportService) ExportLinks(format string, since, until time.Time, w io.Writer) (int, error) {
+	writer, err := export.NewWriter[export.LinkRecord](format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	// Les domaines sont peu nombreux : leurs noms d'hôte sont chargés une fois pour toutes
+	domains, err := s.domainService.ListDomains()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list domains: %w", err)
+	}
+	hosts := make(map[uint]string, len(domains))
+	for _, domain := range domains {
+		hosts[domain.ID] = domain.Host
+	}
+
+	count := 0
+	err = s.linkRepo.StreamLinks(since, until, func(link *models.Link) error {
+		count++
+		return writer.Write(export.NewLinkRecord(link, hosts[link.DomainID]))
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export links: %w", err)
+	}
+	return count, writer.Close()
+}
+
+// ExportClicks écrit dans w, au format demandé, les clics bruts survenus entre since (inclus) et until (exclu).
+// Il renvoie le nombre de clics exportés. Les clics déjà purgés (rétention) ne sont présents que dans les agrégats.
+func (s *ExportService) ExportClicks(format string, since, until time.Time, w io.Writer) (int, error) {
+	writer, err := export.NewWriter[export.ClickRecord](format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = s.clickRepo.StreamClicks(since, until, func(click *models.Click) error {
+		count++
+		return writer.Write(export.NewClickRecord(click))
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export clicks: %w", err)
+	}
+	return count, writer.Close()
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/export"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+)
+
+func TestExportLinks(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	brand, err := s.domainService.CreateDomain("brand.test", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+	may := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i, link := range []*models.Link{
+		{ShortCode: "april", LongURL: "https://example.com/1", CreatedAt: may.AddDate(0, -1, 0)},
+		{ShortCode: "may", LongURL: "https://example.com/2", CreatedAt: may, DomainID: brand.ID},
+		{ShortCode: "june", LongURL: "https://example.com/3", CreatedAt: may.AddDate(0, 1, 0)},
+	} {
+		if err := s.repos.Links.CreateLink(link); err != nil {
+			t.Fatalf("CreateLink %d: %v", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := NewExportService(s.repos.Links, s.repos.Clicks, s.domainService).
+		ExportLinks(export.FormatCSV, may.AddDate(0, 0, -1), may.AddDate(0, 0, 1), &buf)
+	if err != nil {
+		t.Fatalf("ExportLinks: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if count != 1 || len(rows) != 2 {
+		t.Fatalf("ExportLinks = %d links, CSV %v, want only the May link", count, rows)
+	}
+	if rows[1][1] != "brand.test" || rows[1][2] != "may" {
+		t.Errorf("exported row = %v, want may on brand.test", rows[1])
+	}
+}
+
+func TestExportClicks(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	service := NewExportService(s.repos.Links, s.repos.Clicks, s.domainService)
+	at := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for _, timestamp := range []time.Time{at.Add(-time.Hour), at, at.Add(time.Hour)} {
+		if err := s.repos.Clicks.CreateClick(&models.Click{LinkID: 1, Timestamp: timestamp}); err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	// La borne since est incluse, la borne until exclue
+	count, err := service.ExportClicks(export.FormatJSONL, at, at.Add(time.Hour), &buf)
+	if err != nil || count != 1 || bytes.Count(buf.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("ExportClicks = %d, %v, output %q, want 1 click", count, err, buf.String())
+	}
+
+	if _, err := service.ExportClicks("xml", time.Time{}, time.Time{}, &buf); err == nil {
+		t.Error("ExportClicks accepted an unknown format")
+	}
+}
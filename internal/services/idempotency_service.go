@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+// idempotencyLockTimeout est la durée au-delà de laquelle une requête "en cours" est considérée comme abandonnée
+// (instance arrêtée pendant le traitement) : sa clé peut alors être reprise par une nouvelle tentative.
+const idempotencyLockTimeout = time.Minute
+
+// ErrIdempotencyKeyReused est renvoyée lorsqu'une clé d'idempotence est réutilisée avec une requête différente.
+var ErrIdempotencyKeyReused = errors.New("idempotency key has already been used with a different request")
+
+// ErrIdempotencyKeyInProgress est renvoyée lorsque la requête d'origine d'une clé est encore en cours de traitement.
+var ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+
+// IdempotencyService enregistre la réponse des requêtes portant une clé d'idempotence
+// pour la renvoyer telle quelle si le client rejoue la même requête pendant la fenêtre de conservation.
+type IdempotencyService struct {
+	repo   repository.IdempotencyRepository
+	window time.Duration
+}
+
+// NewIdempotencyService crée et retourne une nouvelle instance de IdempotencyService.
+// window est la durée de conservation des réponses.
+func NewIdempotencyService(repo repository.IdempotencyRepository, window time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, window: window}
+}
+
+// Begin réserve la clé pour la requête de corps body, à traiter puis à terminer par Complete ou Release.
+// Si la même requête a déjà reçu une réponse, celle-ci est renvoyée et la requête ne doit pas être traitée à nouveau.
+// Il renvoie ErrIdempotencyKeyReused si le corps diffère de celui de la requête d'origine,
+// et ErrIdempotencyKeyInProgress si la requête d'origine n'est pas terminée.
+func (s *IdempotencyService) Begin(scope, key string, body []byte, now time.Time) (*models.IdempotencyKey, error) {
+	hash := sha256.Sum256(body)
+	record := &models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: hex.EncodeToString(hash[:]),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.window),
+	}
+
+	for retry := 0; ; retry++ {
+		existing, reserved, err := s.repo.ReserveIdempotencyKey(record, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		switch {
+		case reserved:
+			return nil, nil
+		case existing.RequestHash != record.RequestHash:
+			return nil, ErrIdempotencyKeyReused
+		case !existing.InProgress():
+			return existing, nil
+		case retry == 0 && now.Sub(existing.CreatedAt) > idempotencyLockTimeout:
+			// La requête d'origine a été abandonnée : la clé est libérée puis réservée à nouveau
+			if err := s.repo.ReleaseIdempotencyKey(scope, key); err != nil {
+				return nil, fmt.Errorf("failed to release idempotency key: %w", err)
+			}
+		default:
+			return nil, ErrIdempotencyKeyInProgress
+		}
+	}
+}
+
+// Complete enregistre la réponse de la requête d'une clé réservée par Begin.
+func (s *IdempotencyService) Complete(scope, key string, statusCode int, response []byte) error {
+	return s.repo.CompleteIdempotencyKey(scope, key, statusCode, response)
+}
+
+// Release libère une clé réservée par Begin sans enregistrer de réponse (erreur du serveur) :
+// une nouvelle tentative du client sera traitée normalement.
+func (s *IdempotencyService) Release(scope, key string) error {
+	return s.repo.ReleaseIdempotencyKey(scope, key)
+}
+
+// PurgeExpired supprime les clés dont la fenêtre de conservation est écoulée et renvoie leur nombre.
+func (s *IdempotencyService) PurgeExpired(now time.Time) (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyKeys(now)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+const testScope = "POST /api/v1/links"
+
+func TestIdempotencyReplay(t *testing.T) {
+	service := NewIdempotencyService(repository.NewMemoryRepositories().Idempotency, time.Hour)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"long_url":"https://example.com"}`)
+
+	if stored, err := service.Begin(testScope, "key-1", body, now); err != nil || stored != nil {
+		t.Fatalf("first Begin = %+v, %v, want the key reserved", stored, err)
+	}
+	// Tant que la première requête n'est pas terminée, la clé est occupée
+	if _, err := service.Begin(testScope, "key-1", body, now.Add(time.Second)); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("Begin during the request error = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if err := service.Complete(testScope, "key-1", 201, []byte(`{"short_code":"abc"}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	stored, err := service.Begin(testScope, "key-1", body, now.Add(time.Minute))
+	if err != nil || stored == nil || stored.StatusCode != 201 || string(stored.Response) != `{"short_code":"abc"}` {
+		t.Fatalf("replayed Begin = %+v, %v, want the stored 201 response", stored, err)
+	}
+	if _, err := service.Begin(testScope, "key-1", []byte(`{"long_url":"https://other.example"}`), now.Add(time.Minute)); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Begin with another body error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// La clé est propre à une route
+	if stored, err := service.Begin("POST /api/v1/links/batch", "key-1", body, now); err != nil || stored != nil {
+		t.Errorf("Begin on another route = %+v, %v, want the key reserved", stored, err)
+	}
+
+	// Après la fenêtre de conservation, la clé est à nouveau libre puis purgée
+	if stored, err := service.Begin(testScope, "key-1", body, now.Add(2*time.Hour)); err != nil || stored != nil {
+		t.Errorf("Begin after the window = %+v, %v, want the key reserved again", stored, err)
+	}
+	if purged, err := service.PurgeExpired(now.Add(2 * time.Hour)); err != nil || purged != 1 {
+		t.Errorf("PurgeExpired = %d, %v, want the batch key purged", purged, err)
+	}
+}
+
+func TestIdempotencyRelease(t *testing.T) {
+	service := NewIdempotencyService(repository.NewMemoryRepositories().Idempotency, time.Hour)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{}`)
+
+	if _, err := service.Begin(testScope, "key", body, now); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := service.Release(testScope, "key"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if stored, err := service.Begin(testScope, "key", body, now); err != nil || stored != nil {
+		t.Errorf("Begin after Release = %+v, %v, want the key reserved", stored, err)
+	}
+
+	// Une requête abandonnée (instance arrêtée) libère sa clé après idempotencyLockTimeout
+	if stored, err := service.Begin(testScope, "key", body, now.Add(idempotencyLockTimeout+time.Second)); err != nil || stored != nil {
+		t.Errorf("Begin after an abandoned request = %+v, %v, want the key reserved", stored, err)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/armanceau/go-url-shortener/internal/importer"
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+// ImportOptions regroupe les réglages d'un import de liens.
+type ImportOptions struct {
+	Domain string // Domaine imposé à tous les liens, colonne domain ignorée (vide = colonne domain, sinon domaine par défaut)
+	Owner  string // Propriétaire des liens dont la ligne n'en précise pas
+
+	// Atomic n'enregistre rien si une seule ligne est invalide et écrit toutes les autres dans une transaction.
+	// Sinon, les lignes valides sont enregistrées et les lignes invalides seulement signalées.
+	Atomic bool
+	DryRun bool // Valide toutes les lignes sans rien enregistrer (les codes à générer ne sont pas tirés)
+}
+
+// ImportResult est le résultat d'une ligne de l'import.
+type ImportResult struct {
+	Line      int
+	ShortCode string // Code conservé ou généré (vide si la ligne est rejetée ou en essai à blanc)
+	LongURL   string
+	Link      *models.Link // Lien enregistré (nil si la ligne n'a pas été enregistrée)
+	Err       error        // Raison du rejet de la ligne
+}
+
+// ImportReport est le rapport ligne par ligne d'un import.
+type ImportReport struct {
+	Results  []ImportResult
+	Valid    int // Lignes valides
+	Imported int // Liens enregistrés (0 en essai à blanc, ou en mode atomique si une ligne est invalide)
+	Failed   int // Lignes rejetées
+}
+
+// ImportService importe en masse des liens issus d'un autre service ou d'un export.
+type ImportService struct {
+	linkService   *LinkService
+	domainService *DomainService
+}
+
+// NewImportService crée et retourne une nouvelle instance de ImportService.
+func NewImportService(linkService *LinkService, domainService *DomainService) *ImportService {
+	return &ImportService{linkService: linkService, domainService: domainService}
+}
+
+// importChunkSize est le nombre de liens insérés par transaction lors d'un import non atomique.
+const importChunkSize = 500
+
+// Import valide chaque ligne puis enregistre les liens valides.
+// Les codes fournis sont conservés s'ils sont libres (une requête par domaine) ; les autres sont générés une fois
+// tous les codes conservés connus, pour ne jamais en prendre un. La date de création et les clics historiques sont repris tels quels.
+// Hors mode atomique, les liens sont insérés par paquets : seul un paquet en échec est repris ligne par ligne.
+// Une erreur n'est renvoyée que si l'import n'a pas pu être mené (domaine imposé inconnu, base indisponible) ;
+// les lignes invalides sont signalées dans le rapport.
+func (s *ImportService) Import(rows []importer.Row, opts ImportOptions) (*ImportReport, error) {
+	domains := make(map[string]uint)
+	if opts.Domain != "" {
+		domainID, err := s.domainService.ResolveName(opts.Domain)
+		if err != nil {
+			return nil, err
+		}
+		domains[""] = domainID
+	}
+
+	report := &ImportReport{Results: make([]ImportResult, len(rows))}
+	links := make([]*models.Link, len(rows))
+	codes := make(map[codeKey]int, len(rows)) // code -> ligne qui le réserve
+	provided := make(map[uint][]int)          // domaine -> index des lignes dont le code est fourni
+	var domainOrder []uint
+
+	for i, row := range rows {
+		result := &report.Results[i]
+		result.Line, result.ShortCode, result.LongURL = row.Line, row.ShortCode, row.LongURL
+
+		domain := row.Domain
+		if opts.Domain != "" {
+			domain = ""
+		}
+		link, err := s.prepareLink(row, domain, opts.Owner, domains)
+		var rejected *rejectedRowError
+		switch {
+		case errors.As(err, &rejected):
+			result.Err = rejected.err
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+
+		// Un même code ne peut être conservé que pour une ligne du fichier
+		if row.ShortCode != "" {
+			key := s.codeKey(link.DomainID, row.ShortCode)
+			if line, ok := codes[key]; ok {
+				result.Err = fmt.Errorf("short code '%s' is already used on line %d", row.ShortCode, line)
+				continue
+			}
+			codes[key] = row.Line
+			if _, ok := provided[link.DomainID]; !ok {
+				domainOrder = append(domainOrder, link.DomainID)
+			}
+			provided[link.DomainID] = append(provided[link.DomainID], i)
+		}
+		links[i] = link
+	}
+
+	// Les codes fournis déjà enregistrés sont recherchés en une requête par domaine
+	for _, domainID := range domainOrder {
+		indexes := provided[domainID]
+		requested := make([]string, len(indexes))
+		for k, i := range indexes {
+			requested[k] = links[i].ShortCode
+		}
+		taken, err := s.linkService.TakenShortCodes(domainID, requested)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range indexes {
+			if taken[links[i].ShortCode] {
+				report.Results[i].Err = fmt.Errorf("short code '%s' already exists", links[i].ShortCode)
+				links[i] = nil
+			}
+		}
+	}
+
+	for _, link := range links {
+		if link != nil {
+			report.Valid++
+		}
+	}
+	report.Failed = len(rows) - report.Valid
+
+	if opts.DryRun || (opts.Atomic && report.Failed > 0) {
+		return report, nil
+	}
+
+	errs, err := s.linkService.CreatePreparedLinks(links, opts.Atomic, importChunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import links: %w", err)
+	}
+	for i, link := range links {
+		if link == nil {
+			continue
+		}
+		result := &report.Results[i]
+		result.ShortCode = link.ShortCode
+		if errs[i] != nil {
+			result.Err = errs[i]
+			report.Failed++
+			continue
+		}
+		result.Link = link
+		report.Imported++
+	}
+	return report, nil
+}
+
+// codeKey identifie un code court sur un domaine, sans tenir compte de la casse si la politique le demande.
+type codeKey struct {
+	domainID uint
+	code     string
+}
+
+func (s *ImportService) codeKey(domainID uint, code string) codeKey {
+	return codeKey{domainID: domainID, code: s.linkService.FoldCode(code)}
+}
+
+// rejectedRowError signale une ligne invalide, rejetée par l'import, par opposition aux erreurs
+// d'infrastructure qui interrompent l'import.
+type rejectedRowError struct {
+	err error
+}
+
+func (e *rejectedRowError) Error() string { return e.err.Error() }
+func (e *rejectedRowError) Unwrap() error { return e.err }
+
+// reject marque une erreur de validation d'une ligne.
+func reject(err error) error {
+	return &rejectedRowError{err: err}
+}
+
+// prepareLink valide une ligne et construit le lien correspondant, sans code s'il doit être généré.
+// Les erreurs de validation sont marquées par reject. domains met en cache les domaines déjà résolus par leur nom.
+func (s *ImportService) prepareLink(row importer.Row, domain, owner string, domains map[string]uint) (*models.Link, error) {
+	if row.Err != nil {
+		return nil, reject(row.Err)
+	}
+	if row.LongURL == "" {
+		return nil, reject(errors.New("long URL is missing"))
+	}
+	if !IsWebURL(row.LongURL) {
+		return nil, reject(fmt.Errorf("invalid long URL '%s'", row.LongURL))
+	}
+
+	key := NormalizeHost(domain)
+	domainID, ok := domains[key]
+	if !ok {
+		var err error
+		domainID, err = s.domainService.ResolveName(key)
+		if err != nil {
+			if errors.Is(err, ErrUnknownDomain) {
+				return nil, reject(err)
+			}
+			return nil, err
+		}
+		domains[key] = domainID
+	}
+
+	if row.Owner != "" {
+		owner = row.Owner
+	}
+	if len(owner) > 100 {
+		return nil, reject(errors.New("owner must not exceed 100 characters"))
+	}
+	for name, values := range row.UTM.Values() {
+		if len(values[0]) > 100 {
+			return nil, reject(fmt.Errorf("%s must not exceed 100 characters", name))
+		}
+	}
+	link, err := s.linkService.PrepareLink(row.LongURL, CreateLinkOptions{
+		DomainID:       domainID,
+		Owner:          owner,
+		MaxUses:        row.MaxUses,
+		RedirectStatus: row.RedirectStatus,
+		UTM:            row.UTM,
+	})
+	if err != nil {
+		return nil, reject(err)
+	}
+	// Les dates sont enregistrées dans le fuseau local, comme celles des liens créés par le service
+	if !row.CreatedAt.IsZero() {
+		link.CreatedAt = row.CreatedAt.Local()
+	}
+	link.ImportedClicks = row.Clicks
+
+	if row.ShortCode != "" {
+		if err := s.linkService.CheckCustomCode(row.ShortCode); err != nil {
+			return nil, reject(err)
+		}
+		link.ShortCode = row.ShortCode
+	}
+	return link, nil
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/importer"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+)
+
+// countingLinkRepository compte les appels au repository de liens. Si takeOnInsert est défini, le premier
+// CreateLinks qui contient ce code l'enregistre d'abord pour un autre lien, comme une création concurrente.
+type countingLinkRepository struct {
+	repository.LinkRepository
+	createLinks, createLink, findShortCodes, getByCode int
+	takeOnInsert                                       string
+}
+
+func (r *countingLinkRepository) CreateLinks(links []*models.Link) error {
+	r.createLinks++
+	for _, link := range links {
+		if r.takeOnInsert != "" && link.ShortCode == r.takeOnInsert {
+			r.takeOnInsert = ""
+			if err := r.LinkRepository.CreateLink(&models.Link{DomainID: link.DomainID, ShortCode: link.ShortCode, LongURL: "https://example.com/other"}); err != nil {
+				return err
+			}
+		}
+	}
+	return r.LinkRepository.CreateLinks(links)
+}
+
+func (r *countingLinkRepository) CreateLink(link *models.Link) error {
+	r.createLink++
+	return r.LinkRepository.CreateLink(link)
+}
+
+func (r *countingLinkRepository) FindShortCodes(domainID uint, codes []string, fold bool) ([]string, error) {
+	r.findShortCodes++
+	return r.LinkRepository.FindShortCodes(domainID, codes, fold)
+}
+
+func (r *countingLinkRepository) GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error) {
+	r.getByCode++
+	return r.LinkRepository.GetLinkByShortCode(domainID, shortCode)
+}
+
+// testServices regroupe les services de liens sur des repositories en mémoire.
+type testServices struct {
+	repos         repository.Repositories
+	links         *countingLinkRepository
+	linkService   *LinkService
+	domainService *DomainService
+}
+
+func newTestServices(t *testing.T, codes shortcode.Options) *testServices {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	links := &countingLinkRepository{LinkRepository: repos.Links}
+	generator, err := shortcode.New(codes, repos.Counters)
+	if err != nil {
+		t.Fatalf("shortcode.New: %v", err)
+	}
+	return &testServices{
+		repos:         repos,
+		links:         links,
+		linkService:   NewLinkService(links, NewClickService(repos.Clicks), generator, shortcode.NewPolicy(codes), canonical.New(nil)),
+		domainService: NewDomainService(repos.Domains, "http://sho.rt"),
+	}
+}
+
+func (s *testServices) importer() *ImportService {
+	return NewImportService(s.linkService, s.domainService)
+}
+
+func TestImport(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	if _, err := s.linkService.CreateLink("https://example.com/existing", CreateLinkOptions{}); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	existing, _ := s.repos.Links.GetAllLinks()
+	s.links.findShortCodes = 0
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rows := []importer.Row{
+		{Line: 1, ShortCode: "keep", LongURL: "https://example.com/1", CreatedAt: created, Clicks: 42},
+		{Line: 2, LongURL: "https://example.com/2", Owner: "alice"},
+		{Line: 3, ShortCode: "keep", LongURL: "https://example.com/3"},
+		{Line: 4, ShortCode: existing[0].ShortCode, LongURL: "https://example.com/4"},
+		{Line: 5, LongURL: "javascript:alert(1)"},
+		{Line: 6, LongURL: ""},
+		{Line: 7, ShortCode: "api", LongURL: "https://example.com/7"},
+		{Line: 8, LongURL: "https://example.com/8", Domain: "unknown.example"},
+		{Line: 9, LongURL: "https://example.com/9", Err: errors.New("invalid clicks")},
+	}
+	report, err := s.importer().Import(rows, ImportOptions{Owner: "bob"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Valid != 2 || report.Imported != 2 || report.Failed != 7 {
+		t.Errorf("report = %d valid, %d imported, %d failed, want 2, 2, 7", report.Valid, report.Imported, report.Failed)
+	}
+	for i, result := range report.Results {
+		if imported := i < 2; (result.Link != nil) != imported || (result.Err == nil) != imported {
+			t.Errorf("line %d: link = %v, err = %v", result.Line, result.Link, result.Err)
+		}
+	}
+	if !strings.Contains(fmt.Sprint(report.Results[2].Err), "line 1") || !strings.Contains(fmt.Sprint(report.Results[3].Err), "already exists") {
+		t.Errorf("duplicate code errors = %v, %v", report.Results[2].Err, report.Results[3].Err)
+	}
+
+	kept := report.Results[0].Link
+	if kept.ShortCode != "keep" || kept.Owner != "bob" || kept.ImportedClicks != 42 || !kept.CreatedAt.Equal(created) {
+		t.Errorf("kept link = %+v", kept)
+	}
+	generated := report.Results[1]
+	if generated.ShortCode == "" || generated.ShortCode != generated.Link.ShortCode || generated.Link.Owner != "alice" {
+		t.Errorf("generated link result = %+v", generated)
+	}
+	if s.links.getByCode != 0 || s.links.findShortCodes != 2 {
+		t.Errorf("%d GetLinkByShortCode and %d FindShortCodes calls, want 0 and 2 (provided codes, then generated codes)", s.links.getByCode, s.links.findShortCodes)
+	}
+}
+
+func TestImportChecksProvidedCodesOncePerDomain(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	if _, err := s.domainService.CreateDomain("go.example", ""); err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+	var rows []importer.Row
+	for i := 0; i < 50; i++ {
+		domain := ""
+		if i%2 == 1 {
+			domain = "go.example"
+		}
+		rows = append(rows, importer.Row{Line: i + 1, Domain: domain, ShortCode: fmt.Sprintf("code%d", i), LongURL: "https://example.com"})
+	}
+	report, err := s.importer().Import(rows, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Valid != 50 || report.Imported != 0 {
+		t.Errorf("dry run report = %d valid, %d imported, want 50 and 0", report.Valid, report.Imported)
+	}
+	if s.links.findShortCodes != 2 || s.links.getByCode != 0 {
+		t.Errorf("%d FindShortCodes and %d GetLinkByShortCode calls, want one FindShortCodes per domain", s.links.findShortCodes, s.links.getByCode)
+	}
+}
+
+func TestImportCaseInsensitiveCodes(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{CaseInsensitive: true})
+	rows := []importer.Row{
+		{Line: 1, ShortCode: "Promo", LongURL: "https://example.com/1"},
+		{Line: 2, ShortCode: "PROMO", LongURL: "https://example.com/2"},
+	}
+	report, err := s.importer().Import(rows, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Imported != 1 || report.Results[1].Err == nil {
+		t.Fatalf("report = %+v, want the second code rejected as a duplicate", report)
+	}
+
+	report, err = s.importer().Import([]importer.Row{{Line: 1, ShortCode: "promo", LongURL: "https://example.com/3"}}, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Imported != 0 || !strings.Contains(fmt.Sprint(report.Results[0].Err), "already exists") {
+		t.Errorf("result = %+v, want the code rejected as already existing", report.Results[0])
+	}
+}
+
+func TestImportAtomic(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	rows := []importer.Row{
+		{Line: 1, LongURL: "https://example.com/1"},
+		{Line: 2, LongURL: "ftp://example.com/2"},
+	}
+	report, err := s.importer().Import(rows, ImportOptions{Atomic: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Valid != 1 || report.Imported != 0 || s.links.createLinks+s.links.createLink != 0 {
+		t.Errorf("atomic import with an invalid line saved links: %+v", report)
+	}
+
+	report, err = s.importer().Import(rows[:1], ImportOptions{Atomic: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Imported != 1 || s.links.createLinks != 1 || s.links.createLink != 0 {
+		t.Errorf("atomic import = %+v with %d CreateLinks and %d CreateLink calls, want one transaction", report, s.links.createLinks, s.links.createLink)
+	}
+
+	// Un code pris entre la vérification et l'insertion annule tout l'import atomique
+	s.links.takeOnInsert = "late"
+	if _, err := s.importer().Import([]importer.Row{{Line: 1, ShortCode: "late", LongURL: "https://example.com"}, {Line: 2, LongURL: "https://example.com"}}, ImportOptions{Atomic: true}); err == nil {
+		t.Error("atomic import succeeded although a code was taken during the import")
+	}
+	if all, _ := s.repos.Links.GetAllLinks(); len(all) != 2 {
+		t.Errorf("%d links saved, want 2 (the first import and the concurrent link)", len(all))
+	}
+}
+
+func TestImportInsertsInChunks(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	const count = 2*importChunkSize + 10
+	rows := make([]importer.Row, count)
+	for i := range rows {
+		rows[i] = importer.Row{Line: i + 1, LongURL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+	// Une ligne du deuxième paquet voit son code pris par une création concurrente
+	late := importChunkSize + 3
+	rows[late].ShortCode = "late"
+	s.links.takeOnInsert = "late"
+
+	report, err := s.importer().Import(rows, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Imported != count-1 || report.Failed != 1 || report.Results[late].Err == nil {
+		t.Errorf("report = %d imported, %d failed, late line error %v", report.Imported, report.Failed, report.Results[late].Err)
+	}
+	if s.links.createLinks != 3 {
+		t.Errorf("%d CreateLinks calls, want 3 chunks", s.links.createLinks)
+	}
+	if s.links.createLink != importChunkSize {
+		t.Errorf("%d CreateLink calls, want only the failed chunk (%d) retried row by row", s.links.createLink, importChunkSize)
+	}
+	if all, _ := s.repos.Links.GetAllLinks(); len(all) != count {
+		t.Errorf("%d links saved, want %d", len(all), count)
+	}
+}
@@ -1,101 +1,499 @@
 package services
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"log"
-	"math/big"
+	"net/url"
+	"strings"
 
 	"gorm.io/gorm"
 
+	"github.com/armanceau/go-url-shortener/internal/canonical"
 	"github.com/armanceau/go-url-shortener/internal/models"
 	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
 )
 
-// Définition du jeu de caractères pour la génération des codes courts.
-const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+// maxCodeAttempts est le nombre de codes candidats essayés avant d'abandonner la création d'un lien.
+// Les stratégies qui le supportent allongent leurs codes au fil des collisions.
+const maxCodeAttempts = 10
+
+// ErrLinkExhausted est renvoyée lorsqu'un lien à usage limité a déjà consommé toutes ses utilisations.
+var ErrLinkExhausted = errors.New("link has reached its maximum number of uses")
+
+// ErrInvalidRedirectStatus est renvoyée lorsqu'un code de redirection non supporté est demandé.
+var ErrInvalidRedirectStatus = errors.New("redirect status must be one of 301, 302, 307 or 308")
+
+// ErrInvalidQueryConflict est renvoyée lorsqu'une politique de conflit des paramètres inconnue est demandée.
+var ErrInvalidQueryConflict = errors.New("query conflict policy must be one of link, request or append")
+
+// ErrInvalidLongURL est renvoyée lorsque l'URL longue n'est pas une URL http(s) absolue.
+// Les autres schémas (javascript:, data:...) sont refusés : ils s'exécuteraient sur l'origine du raccourcisseur
+// depuis la page intermédiaire.
+var ErrInvalidLongURL = errors.New("long URL must be an absolute http or https URL")
+
+// ErrInvalidVariant est l'erreur de base renvoyée lorsqu'une variante A/B est invalide.
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// CreateLinkOptions regroupe les paramètres optionnels de la création d'un lien.
+type CreateLinkOptions struct {
+	DomainID uint   // Domaine du lien (models.DefaultDomainID = domaine par défaut)
+	Owner    string // Propriétaire du lien, périmètre de la déduplication (vide = anonyme)
+
+	MaxUses        int  // Nombre maximal d'utilisations (0 = illimité, 1 = lien à usage unique)
+	RedirectStatus int  // Code HTTP de redirection (0 = models.DefaultRedirectStatus)
+	Interstitial   bool // Affiche une page intermédiaire avant la redirection
+
+	PassQuery     bool   // Transmet les paramètres de requête entrants à l'URL longue
+	QueryConflict string // Politique de conflit des paramètres ("" = models.QueryConflictLink)
+	PassPath      bool   // Ajoute les segments de chemin supplémentaires à l'URL longue
+
+	UTM models.UTMParams // Paramètres UTM ajoutés à l'URL de destination lors de la redirection
+
+	TargetingRules []models.TargetingRule // Règles de redirection ciblée, dans l'ordre d'évaluation
+
+	Variants      []models.LinkVariant // Destinations pondérées pour les tests A/B
+	StickyVariant bool                 // Conserve la même variante pour un visiteur (via cookie)
+}
+
+// UpdateLinkOptions regroupe les réglages modifiables d'un lien existant.
+// Les champs nil ne sont pas modifiés.
+type UpdateLinkOptions struct {
+	RedirectStatus *int
+	Interstitial   *bool
+	PassQuery      *bool
+	QueryConflict  *string
+	PassPath       *bool
+	StickyVariant  *bool
+}
+
+// LinkClicks associe un lien à son nombre de clics, pour les statistiques agrégées.
+type LinkClicks struct {
+	Link   models.Link
+	Clicks int
+}
 
 // LinkService est une structure qui fournit des méthodes pour la logique métier des liens.
 type LinkService struct {
-	linkRepo     repository.LinkRepository
-	clickService *ClickService
+	linkRepo      repository.LinkRepository
+	clickService  *ClickService
+	codeGenerator shortcode.CodeGenerator
+	codePolicy    *shortcode.Policy
+	canonicalizer *canonical.Canonicalizer
 }
 
 // NewLinkService crée et retourne une nouvelle instance de LinkService.
-func NewLinkService(linkRepo repository.LinkRepository, clickService *ClickService) *LinkService {
+// Un codeGenerator nil utilise la génération aléatoire par défaut (6 caractères parmi 62).
+// La codePolicy (liste de blocage, recherche insensible à la casse) et le canonicalizer
+// (paramètres de suivi à ignorer pour la déduplication) peuvent être nil.
+func NewLinkService(linkRepo repository.LinkRepository, clickService *ClickService, codeGenerator shortcode.CodeGenerator, codePolicy *shortcode.Policy, canonicalizer *canonical.Canonicalizer) *LinkService {
+	if codeGenerator == nil {
+		codeGenerator = shortcode.NewRandom(shortcode.DefaultLength, shortcode.DefaultAlphabet, 0)
+	}
 	return &LinkService{
-		linkRepo:     linkRepo,
-		clickService: clickService,
+		linkRepo:      linkRepo,
+		clickService:  clickService,
+		codeGenerator: codeGenerator,
+		codePolicy:    codePolicy,
+		canonicalizer: canonicalizer,
+	}
+}
+
+// CreateLink crée un nouveau lien raccourci.
+// Il génère un code court unique, puis persiste le lien dans la base de données.
+func (s *LinkService) CreateLink(longURL string, opts CreateLinkOptions) (*models.Link, error) {
+	link, err := s.PrepareLink(longURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.generateShortCodes(opts.DomainID, 1, nil)
+	if err != nil {
+		return nil, err
 	}
+	link.ShortCode = codes[0]
+
+	// Persiste le nouveau lien dans la base de données via le repository
+	err = s.linkRepo.CreateLink(link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link in database: %w", err)
+	}
+	return link, nil
+}
+
+// BatchLinkRequest est un lien à créer dans un lot.
+type BatchLinkRequest struct {
+	LongURL string
+	Options CreateLinkOptions
+	Dedupe  bool // Réutilise le lien existant du propriétaire pour la même URL canonique (y compris un lien créé plus tôt dans le lot)
+}
+
+// BatchLinkResult est le résultat de la création d'un lien d'un lot.
+type BatchLinkResult struct {
+	Link         *models.Link
+	Deduplicated bool  // Link est un lien existant réutilisé
+	Err          error // Raison de l'échec (Link est alors nil)
 }
 
-// GenerateShortCode génère un code court aléatoire d'une longueur spécifiée.
-func (s *LinkService) GenerateShortCode(length int) (string, error) {
-	if length <= 0 {
-		return "", errors.New("length must be positive")
+// dedupeKey identifie les liens considérés comme identiques par la déduplication.
+type dedupeKey struct {
+	domainID     uint
+	owner        string
+	canonicalURL string
+}
+
+// CreateLinks crée un lot de liens et renvoie un résultat par requête, dans l'ordre.
+// Une requête invalide n'empêche pas la création des autres. Les codes de tout le lot sont générés
+// ensemble (une requête d'unicité par tentative et par domaine, au lieu d'une par code),
+// puis les liens sont insérés dans une seule transaction.
+// Une erreur n'est renvoyée que si le lot n'a pas pu être traité (génération des codes, base indisponible).
+func (s *LinkService) CreateLinks(requests []BatchLinkRequest) ([]BatchLinkResult, error) {
+	results := make([]BatchLinkResult, len(requests))
+	links := make([]*models.Link, len(requests))
+	sameAs := make(map[int]int)        // requête dédupliquée -> requête du lot qui crée le lien
+	firstOf := make(map[dedupeKey]int) // lien du lot -> requête qui le crée
+
+	for i, request := range requests {
+		link, err := s.PrepareLink(request.LongURL, request.Options)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		key := dedupeKey{link.DomainID, link.Owner, link.CanonicalURL}
+
+		if request.Dedupe {
+			// Comme pour un lien seul, le plus ancien lien enregistré est prioritaire
+			existing, err := s.linkRepo.FindLinkByCanonicalURL(link.DomainID, link.Owner, link.CanonicalURL)
+			if err == nil {
+				results[i] = BatchLinkResult{Link: existing, Deduplicated: true}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("failed to look up duplicate link: %w", err)
+			}
+			if first, ok := firstOf[key]; ok {
+				sameAs[i] = first
+				continue
+			}
+		}
+		if _, ok := firstOf[key]; !ok {
+			firstOf[key] = i
+		}
+
+		links[i] = link
 	}
 
-	result := make([]byte, length)
-	for i := 0; i < length; i++ {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err := s.assignShortCodes(links); err != nil {
+		return nil, err
+	}
+	for i, err := range s.insertLinks(links, len(links)) {
 		if err != nil {
-			return "", fmt.Errorf("failed to generate random number: %w", err)
+			results[i].Err = err
+			links[i] = nil
 		}
-		result[i] = charset[num.Int64()]
 	}
 
-	return string(result), nil
+	for i, link := range links {
+		if link != nil {
+			results[i].Link = link
+		}
+	}
+	for i, first := range sameAs {
+		if results[first].Err != nil {
+			results[i].Err = results[first].Err
+			continue
+		}
+		results[i] = BatchLinkResult{Link: results[first].Link, Deduplicated: true}
+	}
+	return results, nil
 }
 
-// CreateLink crée un nouveau lien raccourci.
-// Il génère un code court unique, puis persiste le lien dans la base de données.
-func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
-	var shortCode string
-	const maxRetries = 5
+// CreatePreparedLinks enregistre des liens construits par PrepareLink. Les liens sans code court en reçoivent
+// un, généré sans reprendre ceux déjà attribués aux autres liens ; les codes fournis doivent être libres.
+// En mode atomique, tous les liens sont insérés dans une seule transaction et l'erreur éventuelle est renvoyée.
+// Sinon, ils sont insérés par paquets de chunkSize : errs[i] est la raison de l'échec de links[i], et seule
+// l'erreur de génération des codes est renvoyée. Les entrées nil de links sont ignorées.
+func (s *LinkService) CreatePreparedLinks(links []*models.Link, atomic bool, chunkSize int) ([]error, error) {
+	if err := s.assignShortCodes(links); err != nil {
+		return nil, err
+	}
+	if !atomic {
+		return s.insertLinks(links, chunkSize), nil
+	}
 
-	for i := 0; i < maxRetries; i++ {
-		// Génère un code de 6 caractères
-		code, err := s.GenerateShortCode(6)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate short code: %w", err)
+	toCreate := make([]*models.Link, 0, len(links))
+	for _, link := range links {
+		if link != nil {
+			toCreate = append(toCreate, link)
+		}
+	}
+	if err := s.linkRepo.CreateLinks(toCreate); err != nil {
+		return nil, fmt.Errorf("failed to create links in database: %w", err)
+	}
+	return make([]error, len(links)), nil
+}
+
+// assignShortCodes génère, domaine par domaine, les codes des liens qui n'en ont pas,
+// en écartant les codes déjà attribués aux autres liens du même domaine.
+func (s *LinkService) assignShortCodes(links []*models.Link) error {
+	pending := make(map[uint][]int) // domaine -> liens en attente d'un code
+	reserved := make(map[uint]map[string]bool)
+	var domainOrder []uint
+	for i, link := range links {
+		switch {
+		case link == nil:
+		case link.ShortCode != "":
+			if reserved[link.DomainID] == nil {
+				reserved[link.DomainID] = make(map[string]bool)
+			}
+			reserved[link.DomainID][s.FoldCode(link.ShortCode)] = true
+		default:
+			if _, ok := pending[link.DomainID]; !ok {
+				domainOrder = append(domainOrder, link.DomainID)
+			}
+			pending[link.DomainID] = append(pending[link.DomainID], i)
 		}
+	}
 
-		// Vérifie si le code existe déjà en base de données
-		_, err = s.GetLinkByShortCode(code)
+	for _, domainID := range domainOrder {
+		indexes := pending[domainID]
+		taken := reserved[domainID]
+		codes, err := s.generateShortCodes(domainID, len(indexes), func(code string) bool {
+			return taken[s.FoldCode(code)]
+		})
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				shortCode = code
-				break
+			return err
+		}
+		for k, i := range indexes {
+			links[i].ShortCode = codes[k]
+		}
+	}
+	return nil
+}
+
+// insertLinks insère les liens par paquets de chunkSize, chacun dans sa transaction, et renvoie l'erreur de chaque lien.
+// Un code pris entre-temps fait échouer la transaction d'un paquet : ses liens sont alors créés séparément
+// pour que l'erreur ne concerne que le lien fautif. Les entrées nil de links sont ignorées.
+func (s *LinkService) insertLinks(links []*models.Link, chunkSize int) []error {
+	errs := make([]error, len(links))
+	if chunkSize <= 0 {
+		chunkSize = len(links)
+	}
+	for start := 0; start < len(links); start += chunkSize {
+		end := min(start+chunkSize, len(links))
+		var chunk []*models.Link
+		for _, link := range links[start:end] {
+			if link != nil {
+				chunk = append(chunk, link)
 			}
-			return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
+		}
+		if len(chunk) == 0 {
+			continue
+		}
+		err := s.linkRepo.CreateLinks(chunk)
+		if err == nil {
+			continue
 		}
 
-		log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, i+1, maxRetries)
+		log.Printf("Batch insert of %d link(s) failed, creating them one by one: %v", len(chunk), err)
+		for i := start; i < end; i++ {
+			if links[i] == nil {
+				continue
+			}
+			resetLinkIDs(links[i])
+			if err := s.linkRepo.CreateLink(links[i]); err != nil {
+				errs[i] = fmt.Errorf("failed to create link in database: %w", err)
+			}
+		}
 	}
+	return errs
+}
 
-	// Vérifie si un code unique a été trouvé
-	if shortCode == "" {
+// resetLinkIDs efface les IDs attribués à un lien et à ses règles et variantes par une insertion annulée.
+func resetLinkIDs(link *models.Link) {
+	link.ID = 0
+	for i := range link.TargetingRules {
+		link.TargetingRules[i].ID, link.TargetingRules[i].LinkID = 0, 0
+	}
+	for i := range link.Variants {
+		link.Variants[i].ID, link.Variants[i].LinkID = 0, 0
+	}
+}
+
+// PrepareLink valide les options de création et construit le lien correspondant, sans code court
+// ni enregistrement. Les liens préparés sont enregistrés par CreatePreparedLinks.
+func (s *LinkService) PrepareLink(longURL string, opts CreateLinkOptions) (*models.Link, error) {
+	if opts.MaxUses < 0 {
+		return nil, errors.New("max uses must not be negative")
+	}
+	if opts.RedirectStatus == 0 {
+		opts.RedirectStatus = models.DefaultRedirectStatus
+	}
+	if !models.IsValidRedirectStatus(opts.RedirectStatus) {
+		return nil, ErrInvalidRedirectStatus
+	}
+	if opts.QueryConflict == "" {
+		opts.QueryConflict = models.QueryConflictLink
+	}
+	if !models.IsValidQueryConflict(opts.QueryConflict) {
+		return nil, ErrInvalidQueryConflict
+	}
+	if err := normalizeTargetingRules(opts.TargetingRules); err != nil {
+		return nil, err
+	}
+	if err := validateVariants(opts.Variants); err != nil {
+		return nil, err
+	}
+	if !IsWebURL(longURL) {
+		return nil, ErrInvalidLongURL
+	}
+	canonicalURL, err := s.canonicalizer.Canonicalize(longURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Link{
+		DomainID: opts.DomainID,
+		LongURL:  longURL,
+		MaxUses:  opts.MaxUses,
+
+		CanonicalURL: canonicalURL,
+		Owner:        opts.Owner,
+
+		RedirectStatus: opts.RedirectStatus,
+		Interstitial:   opts.Interstitial,
+
+		PassQuery:     opts.PassQuery,
+		QueryConflict: opts.QueryConflict,
+		PassPath:      opts.PassPath,
+		UTM:           opts.UTM,
+
+		TargetingRules: opts.TargetingRules,
+		Variants:       opts.Variants,
+		StickyVariant:  opts.StickyVariant,
+	}, nil
+}
+
+// generateShortCodes génère count codes courts distincts et libres sur le domaine selon la stratégie configurée.
+// Les candidats d'une même tentative sont vérifiés en une seule requête ; seuls les codes en collision
+// sont régénérés à la tentative suivante. taken (optionnel) écarte en plus les codes déjà réservés
+// par l'appelant mais pas encore enregistrés.
+func (s *LinkService) generateShortCodes(domainID uint, count int, taken func(code string) bool) ([]string, error) {
+	codes := make([]string, 0, count)
+	seen := make(map[string]bool, count)
+	for attempt := 0; attempt < maxCodeAttempts && len(codes) < count; attempt++ {
+		// Génère les codes candidats manquants selon la stratégie configurée
+		missing := count - len(codes)
+		candidates := make([]string, 0, missing)
+		for i := 0; i < missing; i++ {
+			code, err := s.codeGenerator.Generate(attempt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate short code: %w", err)
+			}
+
+			// Les codes réservés ou contenant un mot bloqué ne sont jamais enregistrés
+			if err := s.codePolicy.Check(code); err != nil {
+				log.Printf("Short code '%s' is blocked, retrying generation (%d/%d)...", code, attempt+1, maxCodeAttempts)
+				continue
+			}
+			if seen[s.FoldCode(code)] || (taken != nil && taken(code)) {
+				log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, attempt+1, maxCodeAttempts)
+				continue
+			}
+			seen[s.FoldCode(code)] = true
+			candidates = append(candidates, code)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// Vérifie en une fois quels candidats existent déjà sur ce domaine
+		existing, err := s.linkRepo.FindShortCodes(domainID, candidates, s.codePolicy.CaseInsensitive())
+		if err != nil {
+			return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
+		}
+		exists := make(map[string]bool, len(existing))
+		for _, code := range existing {
+			exists[s.FoldCode(code)] = true
+		}
+		for _, code := range candidates {
+			if exists[s.FoldCode(code)] {
+				log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, attempt+1, maxCodeAttempts)
+				continue
+			}
+			codes = append(codes, code)
+		}
+	}
+
+	// Vérifie si assez de codes uniques ont été trouvés
+	if len(codes) < count {
 		return nil, errors.New("failed to generate unique short code after maximum retries")
 	}
+	return codes, nil
+}
 
-	// Création du nouveau lien
-	link := &models.Link{
-		ShortCode: shortCode,
-		LongURL:   longURL,
+// FoldCode renvoie la forme sous laquelle deux codes sont comparés : sans casse si la politique le demande.
+func (s *LinkService) FoldCode(code string) string {
+	if s.codePolicy.CaseInsensitive() {
+		return strings.ToLower(code)
 	}
+	return code
+}
 
-	// Persiste le nouveau lien dans la base de données via le repository
-	err := s.linkRepo.CreateLink(link)
+// CheckCustomCode vérifie qu'un code choisi par l'utilisateur est bien formé et autorisé par la politique des codes.
+// Il ne vérifie pas que le code est libre (voir TakenShortCodes).
+func (s *LinkService) CheckCustomCode(code string) error {
+	if err := shortcode.ValidateCode(code); err != nil {
+		return err
+	}
+	if err := s.codePolicy.Check(code); err != nil {
+		return fmt.Errorf("short code '%s': %w", code, err)
+	}
+	return nil
+}
+
+// TakenShortCodes renvoie, en une requête, ceux des codes déjà utilisés sur le domaine, tels qu'ils ont été demandés.
+func (s *LinkService) TakenShortCodes(domainID uint, codes []string) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	if len(codes) == 0 {
+		return taken, nil
+	}
+	existing, err := s.linkRepo.FindShortCodes(domainID, codes, s.codePolicy.CaseInsensitive())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create link in database: %w", err)
+		return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
 	}
-	return link, nil
+	stored := make(map[string]bool, len(existing))
+	for _, code := range existing {
+		stored[s.FoldCode(code)] = true
+	}
+	for _, code := range codes {
+		if stored[s.FoldCode(code)] {
+			taken[code] = true
+		}
+	}
+	return taken, nil
 }
 
-// GetLinkByShortCode récupère un lien via son code court.
-func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error) {
-	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+// FindDuplicateLink renvoie le lien existant du propriétaire, sur ce domaine, dont l'URL longue
+// est identique à longURL une fois canonicalisée. Il renvoie gorm.ErrRecordNotFound s'il n'y en a pas.
+func (s *LinkService) FindDuplicateLink(domainID uint, owner, longURL string) (*models.Link, error) {
+	if !IsWebURL(longURL) {
+		return nil, ErrInvalidLongURL
+	}
+	canonicalURL, err := s.canonicalizer.Canonicalize(longURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkRepo.FindLinkByCanonicalURL(domainID, owner, canonicalURL)
+}
+
+// GetLinkByShortCode récupère un lien via son domaine et son code court.
+// En mode insensible à la casse, la correspondance exacte reste prioritaire sur les codes anciens
+// qui ne diffèrent que par la casse.
+func (s *LinkService) GetLinkByShortCode(domainID uint, shortCode string) (*models.Link, error) {
+	link, err := s.findLink(domainID, shortCode)
 	if err != nil {
 		// On laisse passer l'erreur gorm.ErrRecordNotFound pour la vérification d'unicité
 		return nil, err
@@ -104,31 +502,236 @@ func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error)
 }
 
 // GetLinkByShortCodeWithMessage récupère un lien via son code court avec un message d'erreur personnalisé.
-func (s *LinkService) GetLinkByShortCodeWithMessage(shortCode string) (*models.Link, error) {
-	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+func (s *LinkService) GetLinkByShortCodeWithMessage(domainID uint, shortCode string) (*models.Link, error) {
+	link, err := s.findLink(domainID, shortCode)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("link with short code '%s' not found", shortCode)
+			return nil, fmt.Errorf("link with short code '%s' not found: %w", shortCode, err)
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+// findLink recherche un lien par code exact puis, si la politique le demande, sans tenir compte de la casse.
+func (s *LinkService) findLink(domainID uint, shortCode string) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(domainID, shortCode)
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) && s.codePolicy.CaseInsensitive() {
+		return s.linkRepo.GetLinkByShortCodeFold(domainID, shortCode)
+	}
+	return link, err
+}
+
+// UpdateLink modifie les réglages de redirection et de transmission d'un lien existant et renvoie le lien mis à jour.
+func (s *LinkService) UpdateLink(domainID uint, shortCode string, opts UpdateLinkOptions) (*models.Link, error) {
+	link, err := s.GetLinkByShortCodeWithMessage(domainID, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link: %w", err)
+	}
+
+	fields := make(map[string]interface{})
+	if opts.RedirectStatus != nil {
+		if !models.IsValidRedirectStatus(*opts.RedirectStatus) {
+			return nil, ErrInvalidRedirectStatus
+		}
+		fields["redirect_status"] = *opts.RedirectStatus
+		link.RedirectStatus = *opts.RedirectStatus
+	}
+	if opts.Interstitial != nil {
+		fields["interstitial"] = *opts.Interstitial
+		link.Interstitial = *opts.Interstitial
+	}
+	if opts.PassQuery != nil {
+		fields["pass_query"] = *opts.PassQuery
+		link.PassQuery = *opts.PassQuery
+	}
+	if opts.QueryConflict != nil {
+		if !models.IsValidQueryConflict(*opts.QueryConflict) {
+			return nil, ErrInvalidQueryConflict
+		}
+		fields["query_conflict"] = *opts.QueryConflict
+		link.QueryConflict = *opts.QueryConflict
+	}
+	if opts.PassPath != nil {
+		fields["pass_path"] = *opts.PassPath
+		link.PassPath = *opts.PassPath
+	}
+	if opts.StickyVariant != nil {
+		fields["sticky_variant"] = *opts.StickyVariant
+		link.StickyVariant = *opts.StickyVariant
+	}
+	if len(fields) == 0 {
+		return link, nil
+	}
+
+	if err := s.linkRepo.UpdateLinkFields(link.ID, fields); err != nil {
+		return nil, fmt.Errorf("failed to update link in database: %w", err)
+	}
+	return link, nil
+}
+
+// SetTargetingRules remplace les règles de ciblage d'un lien existant par la liste fournie (dans l'ordre d'évaluation).
+// Une liste vide supprime toutes les règles.
+func (s *LinkService) SetTargetingRules(domainID uint, shortCode string, rules []models.TargetingRule) (*models.Link, error) {
+	link, err := s.GetLinkByShortCodeWithMessage(domainID, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link: %w", err)
+	}
+	if err := normalizeTargetingRules(rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.linkRepo.ReplaceTargetingRules(link.ID, rules); err != nil {
+		return nil, fmt.Errorf("failed to save targeting rules: %w", err)
+	}
+	link.TargetingRules = rules
+	return link, nil
+}
+
+// normalizeTargetingRules valide les règles et fixe leur Position selon leur ordre dans la liste.
+func normalizeTargetingRules(rules []models.TargetingRule) error {
+	for i := range rules {
+		if err := targeting.Normalize(&rules[i]); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
 		}
+		rules[i].Position = i
+	}
+	return nil
+}
+
+// SetVariants remplace les variantes A/B d'un lien existant. Une liste vide supprime toutes les variantes
+// et le lien redirige à nouveau vers son URL longue.
+func (s *LinkService) SetVariants(domainID uint, shortCode string, variants []models.LinkVariant) (*models.Link, error) {
+	link, err := s.GetLinkByShortCodeWithMessage(domainID, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link: %w", err)
+	}
+	if err := validateVariants(variants); err != nil {
 		return nil, err
 	}
+
+	if err := s.linkRepo.ReplaceVariants(link.ID, variants); err != nil {
+		return nil, fmt.Errorf("failed to save variants: %w", err)
+	}
+	link.Variants = variants
 	return link, nil
 }
 
-// GetLinkStats récupère les statistiques pour un lien donné (nombre total de clics).
+// validateVariants vérifie les URLs et les poids des variantes : au moins une variante doit avoir un poids positif.
+func validateVariants(variants []models.LinkVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	totalWeight := 0
+	for i, variant := range variants {
+		if variant.Weight < 0 {
+			return fmt.Errorf("%w %d: weight must not be negative", ErrInvalidVariant, i+1)
+		}
+		if !IsWebURL(variant.TargetURL) {
+			return fmt.Errorf("%w %d: invalid target url '%s'", ErrInvalidVariant, i+1, variant.TargetURL)
+		}
+		totalWeight += variant.Weight
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("%w: at least one variant must have a positive weight", ErrInvalidVariant)
+	}
+	return nil
+}
+
+// IsWebURL indique si rawURL est une URL http ou https absolue, seules destinations de redirection acceptées.
+func IsWebURL(rawURL string) bool {
+	target, err := url.ParseRequestURI(rawURL)
+	return err == nil && (target.Scheme == "http" || target.Scheme == "https") && target.Host != ""
+}
+
+// ClaimLink consomme une utilisation d'un lien avant la redirection.
+// Les liens illimités ne sont pas modifiés. Pour les liens limités, la réservation est atomique
+// en base de données (et non basée sur le comptage asynchrone des clics, qui est en retard),
+// et ErrLinkExhausted est renvoyée si la limite est déjà atteinte. Le lien épuisé est conservé (voir models.Link.IsExhausted).
+func (s *LinkService) ClaimLink(link *models.Link) error {
+	if !link.IsLimited() {
+		return nil
+	}
+	claimed, err := s.linkRepo.ClaimLinkUse(link.ID)
+	if err != nil {
+		return fmt.Errorf("failed to claim link use: %w", err)
+	}
+	if !claimed {
+		return ErrLinkExhausted
+	}
+	link.UseCount++
+	return nil
+}
+
+// GetLinkStats récupère les statistiques pour un lien donné (nombre total de clics, y compris les clics historiques importés).
 // Il interagit avec le LinkRepository pour obtenir le lien, puis avec le ClickRepository
-func (s *LinkService) GetLinkStats(shortCode string) (*models.Link, int, error) {
+func (s *LinkService) GetLinkStats(domainID uint, shortCode string) (*models.Link, int, error) {
 	// Récupérer le lien par son shortCode
-	link, err := s.GetLinkByShortCodeWithMessage(shortCode)
+	link, err := s.GetLinkByShortCodeWithMessage(domainID, shortCode)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get link: %w", err)
 	}
 
-	// Compter le nombre de clics pour ce LinkID
+	// Compter le nombre de clics pour ce LinkID, clics historiques importés compris
 	clickCount, err := s.clickService.GetClicksCountByLinkID(link.ID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count clicks: %w", err)
 	}
 
-	return link, clickCount, nil
+	return link, clickCount + link.ImportedClicks, nil
+}
+
+// GetGeoBreakdown récupère la répartition géographique des clics d'un lien.
+func (s *LinkService) GetGeoBreakdown(linkID uint) (*GeoBreakdown, error) {
+	return s.clickService.GetGeoBreakdown(linkID)
+}
+
+// VariantClicks associe une variante A/B à son nombre de clics.
+type VariantClicks struct {
+	Variant models.LinkVariant
+	Clicks  int
+}
+
+// GetVariantStats récupère le nombre de clics de chaque variante A/B d'un lien.
+func (s *LinkService) GetVariantStats(link *models.Link) ([]VariantClicks, error) {
+	if len(link.Variants) == 0 {
+		return []VariantClicks{}, nil
+	}
+	counts, err := s.clickService.GetClicksCountByVariant(link.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VariantClicks, len(link.Variants))
+	for i, variant := range link.Variants {
+		results[i] = VariantClicks{Variant: variant, Clicks: counts[variant.ID]}
+	}
+	return results, nil
+}
+
+// GetUTMStats récupère les liens correspondant aux critères UTM et leur nombre de clics,
+// ainsi que le total des clics sur l'ensemble de ces liens.
+func (s *LinkService) GetUTMStats(filter models.UTMParams) ([]LinkClicks, int, error) {
+	links, err := s.linkRepo.FindLinksByUTM(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find links by UTM: %w", err)
+	}
+
+	ids := make([]uint, len(links))
+	for i, link := range links {
+		ids[i] = link.ID
+	}
+	counts, err := s.clickService.GetClicksCountByLinkIDs(ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	results := make([]LinkClicks, len(links))
+	total := 0
+	for i, link := range links {
+		clicks := counts[link.ID] + link.ImportedClicks
+		results[i] = LinkClicks{Link: link, Clicks: clicks}
+		total += clicks
+	}
+	return results, total, nil
 }
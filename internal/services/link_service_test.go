@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/canonical"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"gorm.io/gorm"
+)
+
+func TestCreateLinkRejectsNegativeMaxUses(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	if _, err := s.linkService.CreateLink("https://example.com", CreateLinkOptions{MaxUses: -1}); err == nil {
+		t.Error("CreateLink accepted a negative max uses")
+	}
+}
+
+func TestClaimLink(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+
+	unlimited, err := s.linkService.CreateLink("https://example.com/unlimited", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.linkService.ClaimLink(unlimited); err != nil {
+			t.Fatalf("ClaimLink on an unlimited link: %v", err)
+		}
+	}
+	if unlimited.UseCount != 0 {
+		t.Errorf("unlimited link UseCount = %d, want 0 (uses are not counted)", unlimited.UseCount)
+	}
+
+	limited, err := s.linkService.CreateLink("https://example.com/twice", CreateLinkOptions{MaxUses: 2})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err := s.linkService.ClaimLink(limited); err != nil {
+			t.Fatalf("claim %d: %v", i, err)
+		}
+		if limited.UseCount != i {
+			t.Errorf("UseCount after claim %d = %d", i, limited.UseCount)
+		}
+	}
+	if err := s.linkService.ClaimLink(limited); !errors.Is(err, ErrLinkExhausted) {
+		t.Errorf("third claim error = %v, want ErrLinkExhausted", err)
+	}
+
+	stored, err := s.linkService.GetLinkByShortCode(0, limited.ShortCode)
+	if err != nil {
+		t.Fatalf("GetLinkByShortCode: %v", err)
+	}
+	if stored.UseCount != 2 || !stored.IsExhausted() {
+		t.Errorf("stored link UseCount = %d, exhausted = %v, want 2 and true", stored.UseCount, stored.IsExhausted())
+	}
+}
+
+func TestClaimLinkConcurrently(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	link, err := s.linkService.CreateLink("https://example.com/secret", CreateLinkOptions{MaxUses: 3})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	// Chaque requête a lu le lien avant toute réservation, comme des redirections simultanées
+	var wg sync.WaitGroup
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		visit := *link
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- s.linkService.ClaimLink(&visit)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	claimed := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			claimed++
+		case !errors.Is(err, ErrLinkExhausted):
+			t.Errorf("ClaimLink: %v", err)
+		}
+	}
+	if claimed != 3 {
+		t.Errorf("%d concurrent claims succeeded, want 3", claimed)
+	}
+}
+
+func TestCreateLinkValidatesDestination(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	for _, longURL := range []string{
+		"javascript://example.com/%0Aalert(document.domain)",
+		"data://example.com/text/html,<script>alert(1)</script>",
+		"ftp://example.com/file",
+		"https:///path",
+		"/relative",
+	} {
+		if _, err := s.linkService.CreateLink(longURL, CreateLinkOptions{}); !errors.Is(err, ErrInvalidLongURL) {
+			t.Errorf("CreateLink(%q) error = %v, want ErrInvalidLongURL", longURL, err)
+		}
+	}
+	if _, err := s.linkService.CreateLink("https://example.com", CreateLinkOptions{RedirectStatus: 303}); !errors.Is(err, ErrInvalidRedirectStatus) {
+		t.Errorf("CreateLink with status 303 error = %v, want ErrInvalidRedirectStatus", err)
+	}
+
+	link, err := s.linkService.CreateLink("http://example.com/page", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if link.RedirectStatus != models.DefaultRedirectStatus || link.Interstitial {
+		t.Errorf("link redirect status = %d, interstitial = %v, want the defaults", link.RedirectStatus, link.Interstitial)
+	}
+}
+
+func TestUpdateLink(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	link, err := s.linkService.CreateLink("https://example.com", CreateLinkOptions{RedirectStatus: 307})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	interstitial := true
+	if _, err := s.linkService.UpdateLink(0, link.ShortCode, UpdateLinkOptions{Interstitial: &interstitial}); err != nil {
+		t.Fatalf("UpdateLink: %v", err)
+	}
+	stored, err := s.linkService.GetLinkByShortCode(0, link.ShortCode)
+	if err != nil {
+		t.Fatalf("GetLinkByShortCode: %v", err)
+	}
+	// Les réglages absents de la modification sont conservés
+	if !stored.Interstitial || stored.RedirectStatus != 307 {
+		t.Errorf("stored link interstitial = %v, redirect status = %d, want true and 307", stored.Interstitial, stored.RedirectStatus)
+	}
+
+	invalid := 303
+	if _, err := s.linkService.UpdateLink(0, link.ShortCode, UpdateLinkOptions{RedirectStatus: &invalid}); !errors.Is(err, ErrInvalidRedirectStatus) {
+		t.Errorf("UpdateLink with status 303 error = %v, want ErrInvalidRedirectStatus", err)
+	}
+	if _, err := s.linkService.UpdateLink(0, "missing", UpdateLinkOptions{Interstitial: &interstitial}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("UpdateLink of an unknown link error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func TestGetUTMStats(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+
+	newsletter, err := s.linkService.CreateLink("https://example.com/a", CreateLinkOptions{UTM: models.UTMParams{Source: "newsletter", Campaign: "spring"}})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	other, err := s.linkService.CreateLink("https://example.com/b", CreateLinkOptions{UTM: models.UTMParams{Source: "newsletter", Campaign: "summer"}})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if _, err := s.linkService.CreateLink("https://example.com/c", CreateLinkOptions{UTM: models.UTMParams{Source: "ads"}}); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	for _, linkID := range []uint{newsletter.ID, newsletter.ID, other.ID} {
+		if err := s.repos.Clicks.CreateClick(&models.Click{LinkID: linkID, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+
+	results, total, err := s.linkService.GetUTMStats(models.UTMParams{Source: "newsletter"})
+	if err != nil {
+		t.Fatalf("GetUTMStats: %v", err)
+	}
+	if len(results) != 2 || total != 3 {
+		t.Fatalf("GetUTMStats(newsletter) = %d links, %d clicks, want 2 and 3", len(results), total)
+	}
+	if results[0].Link.ID != newsletter.ID || results[0].Clicks != 2 || results[1].Clicks != 1 {
+		t.Errorf("GetUTMStats(newsletter) = %+v", results)
+	}
+
+	results, total, err = s.linkService.GetUTMStats(models.UTMParams{Source: "newsletter", Campaign: "summer"})
+	if err != nil || len(results) != 1 || total != 1 {
+		t.Errorf("GetUTMStats(newsletter, summer) = %d links, %d clicks, %v, want 1 and 1", len(results), total, err)
+	}
+}
+
+func TestSetVariants(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	link, err := s.linkService.CreateLink("https://example.com", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	for _, variants := range [][]models.LinkVariant{
+		{{TargetURL: "https://example.com/a", Weight: -1}},
+		{{TargetURL: "javascript:alert(1)", Weight: 1}},
+		{{TargetURL: "https://example.com/a"}, {TargetURL: "https://example.com/b"}},
+	} {
+		if _, err := s.linkService.SetVariants(0, link.ShortCode, variants); !errors.Is(err, ErrInvalidVariant) {
+			t.Errorf("SetVariants(%+v) error = %v, want ErrInvalidVariant", variants, err)
+		}
+	}
+	if _, err := s.linkService.SetVariants(0, "missing", nil); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("SetVariants on unknown link error = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	if _, err := s.linkService.SetVariants(0, link.ShortCode, []models.LinkVariant{
+		{Name: "a", TargetURL: "https://example.com/a", Weight: 1},
+		{Name: "b", TargetURL: "https://example.com/b", Weight: 1},
+	}); err != nil {
+		t.Fatalf("SetVariants: %v", err)
+	}
+	stored, err := s.linkService.GetLinkByShortCode(0, link.ShortCode)
+	if err != nil {
+		t.Fatalf("GetLinkByShortCode: %v", err)
+	}
+	if len(stored.Variants) != 2 {
+		t.Fatalf("stored variants = %+v, want 2", stored.Variants)
+	}
+
+	second := stored.Variants[1].ID
+	for i := 0; i < 3; i++ {
+		if err := s.repos.Clicks.CreateClick(&models.Click{LinkID: link.ID, Timestamp: time.Now(), VariantID: &second}); err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+	stats, err := s.linkService.GetVariantStats(stored)
+	if err != nil {
+		t.Fatalf("GetVariantStats: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Clicks != 0 || stats[1].Clicks != 3 || stats[1].Variant.Name != "b" {
+		t.Errorf("GetVariantStats = %+v, want 0 clicks on a and 3 on b", stats)
+	}
+
+	// Une liste vide supprime les variantes
+	if _, err := s.linkService.SetVariants(0, link.ShortCode, nil); err != nil {
+		t.Fatalf("SetVariants(nil): %v", err)
+	}
+	stored, _ = s.linkService.GetLinkByShortCode(0, link.ShortCode)
+	if len(stored.Variants) != 0 {
+		t.Errorf("variants after clearing = %+v", stored.Variants)
+	}
+}
+
+func TestFindDuplicateLink(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	service := NewLinkService(repos.Links, NewClickService(repos.Clicks), nil, nil, canonical.New([]string{"utm_*"}))
+
+	link, err := service.CreateLink("https://Example.com/docs/?b=2&a=1", CreateLinkOptions{Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if link.CanonicalURL != "https://example.com/docs?a=1&b=2" {
+		t.Errorf("CanonicalURL = %q", link.CanonicalURL)
+	}
+
+	found, err := service.FindDuplicateLink(0, "alice", "https://example.com:443/docs?a=1&b=2&utm_source=mail")
+	if err != nil || found.ID != link.ID {
+		t.Errorf("FindDuplicateLink = %+v, %v, want link %d", found, err, link.ID)
+	}
+	// La déduplication est limitée au propriétaire et au domaine
+	if _, err := service.FindDuplicateLink(0, "bob", "https://example.com/docs?a=1&b=2"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("other owner error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if _, err := service.FindDuplicateLink(7, "alice", "https://example.com/docs?a=1&b=2"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("other domain error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if _, err := service.FindDuplicateLink(0, "alice", "javascript:alert(1)"); !errors.Is(err, ErrInvalidLongURL) {
+		t.Errorf("non-web URL error = %v, want ErrInvalidLongURL", err)
+	}
+}
+
+func TestCreateLinksGeneratesCodesTogether(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	brand, err := s.domainService.CreateDomain("brand.test", "")
+	if err != nil {
+		t.Fatalf("CreateDomain: %v", err)
+	}
+
+	requests := make([]BatchLinkRequest, 200)
+	for i := range requests {
+		requests[i] = BatchLinkRequest{LongURL: fmt.Sprintf("https://example.com/%d", i)}
+		if i%2 == 1 {
+			requests[i].Options.DomainID = brand.ID
+		}
+	}
+	requests[7].LongURL = "ftp://example.com/file"
+	requests[8].Options.MaxUses = -1
+
+	results, err := s.linkService.CreateLinks(requests)
+	if err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	if !errors.Is(results[7].Err, ErrInvalidLongURL) || results[7].Link != nil || results[8].Err == nil {
+		t.Errorf("invalid requests = %+v and %+v, want errors", results[7], results[8])
+	}
+
+	codes := make(map[string]bool)
+	for i, result := range results {
+		if i == 7 || i == 8 {
+			continue
+		}
+		if result.Err != nil || result.Link == nil || result.Link.ID == 0 {
+			t.Fatalf("result %d = %+v, want a stored link", i, result)
+		}
+		key := fmt.Sprintf("%d/%s", result.Link.DomainID, result.Link.ShortCode)
+		if codes[key] {
+			t.Errorf("code %s given twice", key)
+		}
+		codes[key] = true
+	}
+	// Une requête d'unicité par domaine, et une seule insertion pour tout le lot
+	if s.links.findShortCodes != 2 || s.links.createLinks != 1 || s.links.createLink != 0 || s.links.getByCode != 0 {
+		t.Errorf("repository calls: %d FindShortCodes, %d CreateLinks, %d CreateLink, %d GetLinkByShortCode, want 2, 1, 0, 0",
+			s.links.findShortCodes, s.links.createLinks, s.links.createLink, s.links.getByCode)
+	}
+}
+
+func TestCreateLinksDedupe(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	existing, err := s.linkService.CreateLink("https://example.com/existing", CreateLinkOptions{Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	results, err := s.linkService.CreateLinks([]BatchLinkRequest{
+		{LongURL: "https://EXAMPLE.com/existing/", Options: CreateLinkOptions{Owner: "alice"}, Dedupe: true},
+		{LongURL: "https://example.com/new", Options: CreateLinkOptions{Owner: "alice"}, Dedupe: true},
+		{LongURL: "https://example.com/new/", Options: CreateLinkOptions{Owner: "alice"}, Dedupe: true},
+		{LongURL: "https://example.com/new", Options: CreateLinkOptions{Owner: "alice"}},
+		{LongURL: "https://example.com/new", Options: CreateLinkOptions{Owner: "bob"}, Dedupe: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateLinks: %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("result %d: %v", i, result.Err)
+		}
+	}
+	if results[0].Link.ID != existing.ID || !results[0].Deduplicated {
+		t.Errorf("stored duplicate = %+v, want link %d", results[0], existing.ID)
+	}
+	// Un doublon d'un lien créé plus tôt dans le lot reprend ce lien
+	if results[1].Deduplicated || results[2].Link != results[1].Link || !results[2].Deduplicated {
+		t.Errorf("batch duplicate = %+v, want the link of request 1", results[2])
+	}
+	if results[3].Deduplicated || results[3].Link.ID == results[1].Link.ID {
+		t.Error("request without dedupe reused a link")
+	}
+	if results[4].Deduplicated || results[4].Link.ID == results[1].Link.ID {
+		t.Error("dedupe reused another owner's link")
+	}
+
+	links, err := s.repos.Links.GetAllLinks()
+	if err != nil || len(links) != 4 {
+		t.Errorf("%d links stored, %v, want 4", len(links), err)
+	}
+}
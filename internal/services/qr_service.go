@@ -0,0 +1,102 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/qr"
+)
+
+// QRService dessine le QR code de l'URL courte complète d'un lien.
+// Les images rendues sont gardées dans un cache LRU en mémoire : un même QR code imprimé
+// puis redemandé ne coûte qu'une recherche du lien.
+type QRService struct {
+	linkService   *LinkService
+	domainService *DomainService
+	cache         *qrCache // nil si le cache est désactivé
+}
+
+// NewQRService crée et retourne une nouvelle instance de QRService.
+// cacheSize est le nombre d'images gardées en cache (0 = pas de cache).
+func NewQRService(linkService *LinkService, domainService *DomainService, cacheSize int) *QRService {
+	s := &QRService{linkService: linkService, domainService: domainService}
+	if cacheSize > 0 {
+		s.cache = newQRCache(cacheSize)
+	}
+	return s
+}
+
+// LinkQRCode renvoie le lien et le QR code de son URL courte complète, rendu selon opts (validées par l'appelant).
+// L'image est mise en cache sous l'URL courte et les options : un changement d'URL de base produit une nouvelle image.
+// Il renvoie gorm.ErrRecordNotFound si le lien n'existe pas.
+func (s *QRService) LinkQRCode(domainID uint, shortCode string, opts qr.Options) (*models.Link, []byte, error) {
+	link, err := s.linkService.GetLinkByShortCode(domainID, shortCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullShortURL, err := s.domainService.FullShortURL(link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := opts.Key(fullShortURL)
+	if image, ok := s.cache.get(key); ok {
+		return link, image, nil
+	}
+	image, err := qr.Render(fullShortURL, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cache.add(key, image)
+	return link, image, nil
+}
+
+// qrCache est un cache LRU des images rendues, sûr pour un usage concurrent. Un cache nil ne garde rien.
+type qrCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List               // Entrées de la plus récemment utilisée à la plus ancienne
+	entries  map[string]*list.Element // Clé -> élément de order (valeur *qrCacheEntry)
+}
+
+type qrCacheEntry struct {
+	key   string
+	image []byte
+}
+
+func newQRCache(capacity int) *qrCache {
+	return &qrCache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *qrCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*qrCacheEntry).image, true
+}
+
+func (c *qrCache) add(key string, image []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&qrCacheEntry{key: key, image: image})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*qrCacheEntry).key)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/qr"
+	"github.com/armanceau/go-url-shortener/internal/shortcode"
+	"gorm.io/gorm"
+)
+
+func TestLinkQRCode(t *testing.T) {
+	s := newTestServices(t, shortcode.Options{})
+	link, err := s.linkService.CreateLink("https://example.com/qr", CreateLinkOptions{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	service := NewQRService(s.linkService, s.domainService, 1)
+	opts := qr.DefaultOptions()
+
+	got, image, err := service.LinkQRCode(0, link.ShortCode, opts)
+	if err != nil {
+		t.Fatalf("LinkQRCode: %v", err)
+	}
+	want, err := qr.Render("http://sho.rt/"+link.ShortCode, opts)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got.ID != link.ID || !bytes.Equal(image, want) {
+		t.Fatalf("LinkQRCode = link %d, %d bytes, want the QR code of the full short URL", got.ID, len(image))
+	}
+
+	// Le second appel sert l'image du cache sans la redessiner
+	_, cached, err := service.LinkQRCode(0, link.ShortCode, opts)
+	if err != nil || &cached[0] != &image[0] {
+		t.Errorf("second LinkQRCode = %v, want the cached image", err)
+	}
+	svg := opts
+	svg.Format = qr.FormatSVG
+	if _, other, err := service.LinkQRCode(0, link.ShortCode, svg); err != nil || bytes.Equal(other, image) {
+		t.Errorf("LinkQRCode in SVG = %v, want another image", err)
+	}
+
+	if _, _, err := service.LinkQRCode(0, "missing", opts); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("LinkQRCode of an unknown link error = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	tooSmall := opts
+	tooSmall.Size, tooSmall.Margin = qr.MinSize, qr.MaxMargin
+	if _, _, err := service.LinkQRCode(0, link.ShortCode, tooSmall); !errors.Is(err, qr.ErrSizeTooSmall) {
+		t.Errorf("LinkQRCode error = %v, want qr.ErrSizeTooSmall", err)
+	}
+
+	// Sans cache, chaque appel redessine l'image
+	uncached := NewQRService(s.linkService, s.domainService, 0)
+	_, first, _ := uncached.LinkQRCode(0, link.ShortCode, opts)
+	_, second, _ := uncached.LinkQRCode(0, link.ShortCode, opts)
+	if &first[0] == &second[0] || !bytes.Equal(first, second) {
+		t.Error("uncached LinkQRCode did not render the same image twice")
+	}
+}
+
+func TestQRCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newQRCache(2)
+	cache.add("a", []byte("a"))
+	cache.add("b", []byte("b"))
+	cache.get("a") // b devient la plus ancienne
+	cache.add("c", []byte("c"))
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if image, ok := cache.get(key); !ok || string(image) != key {
+			t.Errorf("get(%q) = %q, %v, want the cached image", key, image, ok)
+		}
+	}
+
+	var disabled *qrCache
+	disabled.add("a", []byte("a"))
+	if _, ok := disabled.get("a"); ok {
+		t.Error("nil cache returned an image")
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
+)
+
+// ErrPathPassthroughDisabled est renvoyée lorsqu'un chemin supplémentaire est demandé
+// sur un lien qui n'autorise pas la transmission du chemin.
+var ErrPathPassthroughDisabled = errors.New("path passthrough is disabled for this link")
+
+// Resolution décrit la cible choisie pour une visite et ce qui a motivé ce choix.
+type Resolution struct {
+	Target  string                // URL cible, avant application des UTM et de la transmission
+	Rule    *models.TargetingRule // Règle de ciblage appliquée (nil sinon)
+	Variant *models.LinkVariant   // Variante A/B choisie (nil sinon)
+}
+
+// ResolveTarget détermine l'URL cible d'une visite, par ordre de priorité :
+//  1. la première règle de ciblage correspondant au visiteur ;
+//  2. sinon une variante A/B tirée selon les poids (ou la variante stickyVariantID si elle est encore active) ;
+//  3. sinon l'URL longue du lien.
+//
+// La règle ou la variante retenue est renvoyée pour être enregistrée sur le clic.
+func ResolveTarget(link *models.Link, visitor targeting.Visitor, stickyVariantID uint) Resolution {
+	if rule := targeting.Match(link.TargetingRules, visitor); rule != nil {
+		return Resolution{Target: rule.TargetURL, Rule: rule}
+	}
+	if variant := chooseVariant(link.Variants, stickyVariantID); variant != nil {
+		return Resolution{Target: variant.TargetURL, Variant: variant}
+	}
+	return Resolution{Target: link.LongURL}
+}
+
+// chooseVariant renvoie la variante stickyVariantID si elle existe et est active,
+// sinon une variante tirée aléatoirement proportionnellement aux poids (nil si aucune variante active).
+func chooseVariant(variants []models.LinkVariant, stickyVariantID uint) *models.LinkVariant {
+	totalWeight := 0
+	for i := range variants {
+		if variants[i].Weight <= 0 {
+			continue
+		}
+		if stickyVariantID != 0 && variants[i].ID == stickyVariantID {
+			return &variants[i]
+		}
+		totalWeight += variants[i].Weight
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	pick := rand.IntN(totalWeight)
+	for i := range variants {
+		if variants[i].Weight <= 0 {
+			continue
+		}
+		if pick < variants[i].Weight {
+			return &variants[i]
+		}
+		pick -= variants[i].Weight
+	}
+	return nil
+}
+
+// BuildDestination construit l'URL de destination finale d'une redirection à partir de l'URL cible
+// (URL longue du lien, cible d'une règle de ciblage ou d'une variante A/B), du chemin supplémentaire
+// demandé (ex: "/docs/page" pour "/abc123/docs/page") et des paramètres de requête entrants.
+// Les paramètres UTM du lien sont appliqués à l'URL cible (ils remplacent ceux déjà présents), puis
+// les réglages de transmission (PassPath, PassQuery, QueryConflict) déterminent ce qui est conservé de la requête.
+func BuildDestination(link *models.Link, target string, extraPath string, incoming url.Values) (string, error) {
+	// Un "/" final seul ("/abc123/") est traité comme une absence de chemin
+	hasExtraPath := extraPath != "" && extraPath != "/"
+	if hasExtraPath && !link.PassPath {
+		return "", ErrPathPassthroughDisabled
+	}
+	passQuery := link.PassQuery && len(incoming) > 0
+	if !hasExtraPath && !passQuery && link.UTM.IsEmpty() {
+		return target, nil
+	}
+
+	dest, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target URL for link %s: %w", link.ShortCode, err)
+	}
+
+	if hasExtraPath {
+		appendPath(dest, extraPath)
+	}
+	if !link.UTM.IsEmpty() || passQuery {
+		query := dest.Query()
+		for key, values := range link.UTM.Values() {
+			query[key] = values
+		}
+		if passQuery {
+			query = mergeQuery(query, incoming, link.QueryConflict)
+		}
+		dest.RawQuery = query.Encode()
+	}
+	return dest.String(), nil
+}
+
+// appendPath ajoute le chemin supplémentaire au chemin de l'URL de destination.
+// Les segments "." et ".." sont résolus à l'intérieur du suffixe seulement,
+// afin qu'un visiteur ne puisse pas remonter au-dessus du chemin configuré sur le lien.
+func appendPath(dest *url.URL, extraPath string) {
+	suffix := path.Clean("/" + extraPath)
+	if strings.HasSuffix(extraPath, "/") && suffix != "/" {
+		suffix += "/"
+	}
+	dest.Path = strings.TrimSuffix(dest.Path, "/") + suffix
+	dest.RawPath = ""
+}
+
+// mergeQuery fusionne les paramètres entrants dans ceux de l'URL longue selon la politique de conflit.
+func mergeQuery(base, incoming url.Values, policy string) url.Values {
+	for key, values := range incoming {
+		_, exists := base[key]
+		switch {
+		case !exists:
+			base[key] = values
+		case policy == models.QueryConflictRequest:
+			base[key] = values
+		case policy == models.QueryConflictAppend:
+			base[key] = append(base[key], values...)
+		default:
+			// models.QueryConflictLink : la valeur de l'URL longue l'emporte
+		}
+	}
+	return base
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/targeting"
+)
+
+func TestBuildDestination(t *testing.T) {
+	tests := []struct {
+		name      string
+		link      models.Link
+		target    string
+		extraPath string
+		query     string
+		want      string
+	}{
+		{"no passthrough", models.Link{}, "https://example.com/a?x=1", "", "y=2", "https://example.com/a?x=1"},
+		{"trailing slash only", models.Link{}, "https://example.com/a", "/", "", "https://example.com/a"},
+		{"pass query", models.Link{PassQuery: true}, "https://example.com/a?x=1", "", "y=2", "https://example.com/a?x=1&y=2"},
+		{"pass query without incoming", models.Link{PassQuery: true}, "https://example.com/a?x=1", "", "", "https://example.com/a?x=1"},
+		{"conflict link", models.Link{PassQuery: true, QueryConflict: models.QueryConflictLink}, "https://example.com/?x=1", "", "x=2", "https://example.com/?x=1"},
+		{"conflict request", models.Link{PassQuery: true, QueryConflict: models.QueryConflictRequest}, "https://example.com/?x=1", "", "x=2", "https://example.com/?x=2"},
+		{"conflict append", models.Link{PassQuery: true, QueryConflict: models.QueryConflictAppend}, "https://example.com/?x=1", "", "x=2", "https://example.com/?x=1&x=2"},
+		{"pass path", models.Link{PassPath: true}, "https://example.com/docs/", "/guide/intro", "", "https://example.com/docs/guide/intro"},
+		{"pass path keeps trailing slash", models.Link{PassPath: true}, "https://example.com/docs", "/guide/", "", "https://example.com/docs/guide/"},
+		{"pass path stays below the link path", models.Link{PassPath: true}, "https://example.com/docs", "/../../admin", "", "https://example.com/docs/admin"},
+		{"utm added", models.Link{UTM: models.UTMParams{Source: "newsletter"}}, "https://example.com/a?x=1", "", "", "https://example.com/a?utm_source=newsletter&x=1"},
+		{"utm replaces the target's", models.Link{UTM: models.UTMParams{Source: "newsletter"}}, "https://example.com/?utm_source=old", "", "", "https://example.com/?utm_source=newsletter"},
+		{"utm kept on link conflict", models.Link{PassQuery: true, UTM: models.UTMParams{Medium: "email"}}, "https://example.com/", "", "utm_medium=sms", "https://example.com/?utm_medium=email"},
+		{"pass path and query", models.Link{PassPath: true, PassQuery: true}, "https://example.com/docs?x=1", "/page", "y=2", "https://example.com/docs/page?x=1&y=2"},
+	}
+	for _, tt := range tests {
+		incoming, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: ParseQuery: %v", tt.name, err)
+		}
+		got, err := BuildDestination(&tt.link, tt.target, tt.extraPath, incoming)
+		if err != nil {
+			t.Errorf("%s: BuildDestination: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: BuildDestination = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildDestinationRejectsPathWithoutPassthrough(t *testing.T) {
+	_, err := BuildDestination(&models.Link{PassQuery: true}, "https://example.com/", "/docs", nil)
+	if !errors.Is(err, ErrPathPassthroughDisabled) {
+		t.Errorf("error = %v, want ErrPathPassthroughDisabled", err)
+	}
+}
+
+func TestChooseVariant(t *testing.T) {
+	variants := []models.LinkVariant{
+		{ID: 1, TargetURL: "https://example.com/a", Weight: 3},
+		{ID: 2, TargetURL: "https://example.com/b", Weight: 1},
+		{ID: 3, TargetURL: "https://example.com/off", Weight: 0},
+	}
+
+	counts := make(map[uint]int)
+	for i := 0; i < 4000; i++ {
+		counts[chooseVariant(variants, 0).ID]++
+	}
+	if counts[3] != 0 {
+		t.Errorf("variant with weight 0 chosen %d times", counts[3])
+	}
+	// Répartition attendue 3:1, avec une large marge pour éviter les faux échecs
+	if counts[1] < 2700 || counts[1] > 3300 {
+		t.Errorf("variant weights 3:1 gave %v", counts)
+	}
+
+	if got := chooseVariant(variants, 2); got.ID != 2 {
+		t.Errorf("sticky variant 2: chose %d", got.ID)
+	}
+	// Une variante mémorisée désactivée ou supprimée est remplacée par un nouveau tirage
+	for _, sticky := range []uint{3, 42} {
+		if got := chooseVariant(variants, sticky); got.ID == 3 {
+			t.Errorf("sticky variant %d: chose the disabled variant", sticky)
+		}
+	}
+
+	if got := chooseVariant(variants[2:], 0); got != nil {
+		t.Errorf("only disabled variants: chose %+v, want none", got)
+	}
+	if got := chooseVariant(nil, 0); got != nil {
+		t.Errorf("no variants: chose %+v, want none", got)
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	link := &models.Link{
+		LongURL:        "https://example.com",
+		TargetingRules: []models.TargetingRule{{ID: 7, OS: models.OSIOS, TargetURL: "https://apps.apple.com"}},
+		Variants:       []models.LinkVariant{{ID: 9, TargetURL: "https://example.com/b", Weight: 1}},
+	}
+
+	// Une règle de ciblage l'emporte sur les variantes
+	got := ResolveTarget(link, targeting.Visitor{OS: models.OSIOS}, 0)
+	if got.Target != "https://apps.apple.com" || got.Rule == nil || got.Rule.ID != 7 || got.Variant != nil {
+		t.Errorf("iOS visitor resolved to %+v, want rule 7", got)
+	}
+	got = ResolveTarget(link, targeting.Visitor{OS: models.OSAndroid}, 0)
+	if got.Target != "https://example.com/b" || got.Variant == nil || got.Rule != nil {
+		t.Errorf("Android visitor resolved to %+v, want variant 9", got)
+	}
+
+	link.Variants[0].Weight = 0
+	got = ResolveTarget(link, targeting.Visitor{OS: models.OSAndroid}, 0)
+	if got.Target != link.LongURL || got.Rule != nil || got.Variant != nil {
+		t.Errorf("without active variants resolved to %+v, want the long URL", got)
+	}
+}
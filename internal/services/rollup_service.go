@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+)
+
+// rollupBatchHours est le nombre d'heures agrégées par transaction lors du rattrapage d'un historique.
+const rollupBatchHours = 24
+
+// day est la durée d'une période journalière, alignée sur minuit UTC par time.Truncate.
+const day = 24 * time.Hour
+
+// RollupService maintient les agrégats horaires et journaliers des clics.
+// Les heures terminées (moins un délai laissé aux workers de clics) sont agrégées depuis la table des clics,
+// puis les jours terminés depuis les agrégats horaires. Chaque granularité reprend à sa position d'agrégation.
+type RollupService struct {
+	rollupRepo repository.RollupRepository
+	delay      time.Duration
+}
+
+// NewRollupService crée un RollupService. delay est le retard toléré entre un clic et son enregistrement :
+// une heure n'est agrégée qu'une fois ce délai écoulé après sa fin.
+func NewRollupService(rollupRepo repository.RollupRepository, delay time.Duration) *RollupService {
+	return &RollupService{rollupRepo: rollupRepo, delay: delay}
+}
+
+// Compact agrège les heures et les jours terminés à l'instant now, et renvoie le nombre de périodes agrégées.
+func (s *RollupService) Compact(now time.Time) (hours, days int, err error) {
+	hours, err = s.compactHours(s.hourlyLimit(now))
+	if err != nil {
+		return hours, 0, fmt.Errorf("failed to roll up hourly clicks: %w", err)
+	}
+
+	hourly, err := s.rollupRepo.GetWatermark(models.RollupHourly)
+	if err != nil {
+		return hours, 0, fmt.Errorf("failed to read hourly rollup position: %w", err)
+	}
+	days, err = s.compactDays(hourly.Truncate(day))
+	if err != nil {
+		return hours, days, fmt.Errorf("failed to roll up daily clicks: %w", err)
+	}
+	return hours, days, nil
+}
+
+// PurgeClicks supprime les clics bruts antérieurs à olderThan (arrondi à l'heure), après les avoir agrégés
+// pour que les totaux restent exacts. Seuls les clics d'heures déjà agrégées sont supprimés : la limite effective
+// (renvoyée) ne dépasse jamais la position d'agrégation horaire. La purge procède heure par heure : chaque heure
+// est ré-agrégée puis supprimée dans une transaction, si bien que les clics tardifs (enregistrés après l'agrégation,
+// voire la purge, de leur heure) sont comptés avant d'être supprimés.
+// En essai à blanc (dryRun), rien n'est agrégé ni supprimé et seuls les clics concernés sont comptés.
+func (s *RollupService) PurgeClicks(now, olderThan time.Time, dryRun bool) (int64, time.Time, error) {
+	cutoff := minTime(olderThan.UTC().Truncate(time.Hour), s.hourlyLimit(now))
+	if dryRun {
+		count, err := s.rollupRepo.CountClicksBefore(cutoff)
+		if err != nil {
+			return 0, cutoff, fmt.Errorf("failed to count clicks to purge: %w", err)
+		}
+		return count, cutoff, nil
+	}
+
+	if _, _, err := s.Compact(now); err != nil {
+		return 0, cutoff, err
+	}
+	hourly, err := s.rollupRepo.GetWatermark(models.RollupHourly)
+	if err != nil {
+		return 0, cutoff, fmt.Errorf("failed to read hourly rollup position: %w", err)
+	}
+	cutoff = minTime(cutoff, hourly)
+	purged, err := s.rollupRepo.GetWatermark(models.RollupPurged)
+	if err != nil {
+		return 0, cutoff, fmt.Errorf("failed to read purge position: %w", err)
+	}
+
+	// Seules les heures qui ont encore des clics bruts sont parcourues
+	var deleted int64
+	var from time.Time
+	for {
+		next, err := s.rollupRepo.NextClickTime(from)
+		if err != nil {
+			return deleted, cutoff, fmt.Errorf("failed to find clicks to purge: %w", err)
+		}
+		hour := next.Truncate(time.Hour)
+		if next.IsZero() || !hour.Before(cutoff) {
+			return deleted, cutoff, nil
+		}
+		count, err := s.rollupRepo.PurgeClickHour(hour, hour.Before(purged))
+		deleted += count
+		if err != nil {
+			return deleted, cutoff, fmt.Errorf("failed to purge clicks of %s: %w", hour.Format(time.RFC3339), err)
+		}
+		from = hour.Add(time.Hour)
+	}
+}
+
+// hourlyLimit renvoie la fin de la dernière heure agrégeable à l'instant now.
+func (s *RollupService) hourlyLimit(now time.Time) time.Time {
+	return now.Add(-s.delay).UTC().Truncate(time.Hour)
+}
+
+// compactHours agrège les clics bruts heure par heure jusqu'à until (exclu).
+func (s *RollupService) compactHours(until time.Time) (int, error) {
+	from, err := s.startOf(models.RollupHourly, time.Hour)
+	if err != nil || from.IsZero() {
+		return 0, err
+	}
+
+	hours := 0
+	for batchStart := from; batchStart.Before(until); {
+		batchEnd := minTime(batchStart.Add(rollupBatchHours*time.Hour), until)
+		var rollups []models.ClickRollup
+		for bucket := batchStart; bucket.Before(batchEnd); bucket = bucket.Add(time.Hour) {
+			sums, err := s.rollupRepo.SumClicks(bucket, bucket.Add(time.Hour))
+			if err != nil {
+				return hours, err
+			}
+			rollups = append(rollups, withBucket(sums, bucket)...)
+			hours++
+		}
+		if err := s.rollupRepo.ReplaceRollups(models.RollupHourly, batchStart, batchEnd, rollups); err != nil {
+			return hours, err
+		}
+		batchStart = batchEnd
+	}
+	return hours, nil
+}
+
+// compactDays agrège les agrégats horaires jour par jour jusqu'à until (exclu).
+func (s *RollupService) compactDays(until time.Time) (int, error) {
+	from, err := s.startOf(models.RollupDaily, day)
+	if err != nil || from.IsZero() {
+		return 0, err
+	}
+
+	days := 0
+	for bucket := from; bucket.Before(until); bucket = bucket.Add(day) {
+		sums, err := s.rollupRepo.SumHourlyRollups(bucket, bucket.Add(day))
+		if err != nil {
+			return days, err
+		}
+		if err := s.rollupRepo.ReplaceRollups(models.RollupDaily, bucket, bucket.Add(day), withBucket(sums, bucket)); err != nil {
+			return days, err
+		}
+		days++
+	}
+	return days, nil
+}
+
+// startOf renvoie le début de la prochaine période à agréger : la position d'agrégation,
+// ou à défaut la période du plus ancien clic. Elle renvoie zéro s'il n'y a encore aucun clic.
+func (s *RollupService) startOf(granularity string, period time.Duration) (time.Time, error) {
+	watermark, err := s.rollupRepo.GetWatermark(granularity)
+	if err != nil || !watermark.IsZero() {
+		return watermark, err
+	}
+	earliest, err := s.rollupRepo.EarliestClickTime()
+	if err != nil || earliest.IsZero() {
+		return time.Time{}, err
+	}
+	return earliest.Truncate(period), nil
+}
+
+// withBucket affecte la période bucket aux agrégats.
+func withBucket(rollups []models.ClickRollup, bucket time.Time) []models.ClickRollup {
+	for i := range rollups {
+		rollups[i].Bucket = bucket
+	}
+	return rollups
+}
+
+// minTime renvoie le plus ancien des deux instants.
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/migrations"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRollups ouvre une base SQLite vide, migrée, et renvoie ses repositories et un service d'agrégation sans délai.
+func newTestRollups(t *testing.T) (repository.Repositories, *RollupService) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := migrations.Up(db, false); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	repos := repository.NewGormRepositories(db)
+	return repos, NewRollupService(repos.Rollups, 0)
+}
+
+func newRollupTestLink(t *testing.T, repos repository.Repositories) *models.Link {
+	t.Helper()
+	link := &models.Link{ShortCode: "roll", LongURL: "https://example.com", RedirectStatus: models.DefaultRedirectStatus, QueryConflict: models.QueryConflictLink}
+	if err := repos.Links.CreateLink(link); err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	return link
+}
+
+func recordClick(t *testing.T, repos repository.Repositories, linkID uint, at time.Time, country string) {
+	t.Helper()
+	if err := repos.Clicks.CreateClick(&models.Click{LinkID: linkID, Timestamp: at, Country: country}); err != nil {
+		t.Fatalf("CreateClick: %v", err)
+	}
+}
+
+func assertTotal(t *testing.T, repos repository.Repositories, linkID uint, want int) {
+	t.Helper()
+	got, err := repos.Clicks.CountClicksByLinkID(linkID)
+	if err != nil {
+		t.Fatalf("CountClicksByLinkID: %v", err)
+	}
+	if got != want {
+		t.Errorf("total clicks = %d, want %d", got, want)
+	}
+}
+
+func TestCompactKeepsTotals(t *testing.T) {
+	repos, service := newTestRollups(t)
+	link := newRollupTestLink(t, repos)
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 8, 9, 10, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 9, 23, 59, 0, 0, time.UTC), "BE")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 10, 11, 0, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 10, 12, 15, 0, 0, time.UTC), "FR")
+
+	hours, days, err := service.Compact(now)
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	// Du 8 mai 9h au 10 mai 12h, et les jours complets des 8 et 9 mai
+	if hours != 51 || days != 2 {
+		t.Errorf("Compact = %d hours, %d days, want 51 and 2", hours, days)
+	}
+	assertTotal(t, repos, link.ID, 4)
+
+	locations, err := repos.Clicks.CountClicksByLocation(link.ID)
+	if err != nil {
+		t.Fatalf("CountClicksByLocation: %v", err)
+	}
+	byCountry := make(map[string]int)
+	for _, location := range locations {
+		byCountry[location.Country] += location.Count
+	}
+	if byCountry["FR"] != 3 || byCountry["BE"] != 1 {
+		t.Errorf("clicks by country = %v, want FR:3 BE:1", byCountry)
+	}
+
+	// Une nouvelle agrégation ne compte rien deux fois
+	if _, _, err := service.Compact(now.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	assertTotal(t, repos, link.ID, 4)
+}
+
+func TestPurgeCountsLateClicks(t *testing.T) {
+	repos, service := newTestRollups(t)
+	link := newRollupTestLink(t, repos)
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 8, 9, 10, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 8, 9, 20, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 9, 10, 0, 0, 0, time.UTC), "FR")
+	if _, _, err := service.Compact(now); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// Clic tardif : son heure, et même son jour, sont déjà agrégés
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 8, 9, 40, 0, 0, time.UTC), "BE")
+
+	deleted, cutoff, err := service.PurgeClicks(now, time.Date(2024, 5, 9, 0, 20, 0, 0, time.UTC), false)
+	if err != nil {
+		t.Fatalf("PurgeClicks: %v", err)
+	}
+	if want := time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC); !cutoff.Equal(want) {
+		t.Errorf("cutoff = %s, want %s (rounded down to the hour)", cutoff, want)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted %d clicks, want 3", deleted)
+	}
+	assertTotal(t, repos, link.ID, 4)
+
+	// Clics tardifs dans une heure déjà purgée et dans une heure agrégée mais pas encore purgée
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 8, 9, 50, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 9, 10, 30, 0, 0, time.UTC), "FR")
+
+	deleted, _, err = service.PurgeClicks(now, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), false)
+	if err != nil {
+		t.Fatalf("PurgeClicks: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted %d clicks, want 3", deleted)
+	}
+	assertTotal(t, repos, link.ID, 6)
+
+	daily, err := repos.Rollups.SumHourlyRollups(time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SumHourlyRollups: %v", err)
+	}
+	total := 0
+	for _, rollup := range daily {
+		total += rollup.Clicks
+	}
+	if total != 4 {
+		t.Errorf("hourly rollups of May 8 = %d clicks, want 4", total)
+	}
+
+	// Une purge sans nouveau clic tardif ne change rien
+	if deleted, _, err := service.PurgeClicks(now, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), false); err != nil || deleted != 0 {
+		t.Errorf("second PurgeClicks = %d, %v, want 0 deleted", deleted, err)
+	}
+	assertTotal(t, repos, link.ID, 6)
+}
+
+func TestPurgeNeverPassesTheRollupPosition(t *testing.T) {
+	repos, service := newTestRollups(t)
+	link := newRollupTestLink(t, repos)
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 10, 11, 0, 0, 0, time.UTC), "FR")
+	recordClick(t, repos, link.ID, time.Date(2024, 5, 10, 12, 10, 0, 0, time.UTC), "FR")
+
+	count, cutoff, err := service.PurgeClicks(now, now, true)
+	if err != nil {
+		t.Fatalf("PurgeClicks dry run: %v", err)
+	}
+	if count != 1 || !cutoff.Equal(time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("dry run = %d clicks before %s, want 1 before 12:00", count, cutoff)
+	}
+	assertTotal(t, repos, link.ID, 2)
+
+	deleted, _, err := service.PurgeClicks(now, now, false)
+	if err != nil || deleted != 1 {
+		t.Fatalf("PurgeClicks = %d, %v, want 1 deleted", deleted, err)
+	}
+	assertTotal(t, repos, link.ID, 2)
+}
+
+func TestPurgeKeepsClicksWithinRetention(t *testing.T) {
+	repos, service := newTestRollups(t)
+	link := newRollupTestLink(t, repos)
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	recordClick(t, repos, link.ID, now.AddDate(0, 0, -10), "FR")
+	recordClick(t, repos, link.ID, now.AddDate(0, 0, -10).Add(time.Minute), "BE")
+	recordClick(t, repos, link.ID, now.AddDate(0, 0, -1), "FR")
+
+	deleted, cutoff, err := service.PurgeClicks(now, now.AddDate(0, 0, -5), false)
+	if err != nil {
+		t.Fatalf("PurgeClicks: %v", err)
+	}
+	if deleted != 2 || !cutoff.Equal(time.Date(2024, 5, 5, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("PurgeClicks = %d clicks before %s, want 2 before 2024-05-05 12:00", deleted, cutoff)
+	}
+	remaining, err := repos.Rollups.CountClicksBefore(now)
+	if err != nil || remaining != 1 {
+		t.Errorf("raw clicks left = %d, %v, want 1", remaining, err)
+	}
+	assertTotal(t, repos, link.ID, 3)
+}
+
+func TestPurgeKeepsErasedClicksCounted(t *testing.T) {
+	repos, service := newTestRollups(t)
+	link := newRollupTestLink(t, repos)
+	now := time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)
+	hour := time.Date(2024, 5, 8, 9, 0, 0, 0, time.UTC)
+	for i, ip := range []string{"203.0.113.7", "203.0.113.7", "198.51.100.1"} {
+		click := &models.Click{LinkID: link.ID, Timestamp: hour.Add(time.Duration(i) * time.Minute), Country: "FR", IPAddress: ip}
+		if err := repos.Clicks.CreateClick(click); err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+	}
+	if _, _, err := service.Compact(now); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// L'effacement d'une adresse supprime ses clics bruts, mais pas ses clics déjà agrégés
+	if deleted, err := repos.Clicks.DeleteClicksByIPAddresses([]string{"203.0.113.7"}); err != nil || deleted != 2 {
+		t.Fatalf("DeleteClicksByIPAddresses = %d, %v, want 2", deleted, err)
+	}
+	// Clic tardif d'une autre localisation de la même heure
+	recordClick(t, repos, link.ID, hour.Add(30*time.Minute), "BE")
+
+	if _, _, err := service.PurgeClicks(now, now.AddDate(0, 0, -1), false); err != nil {
+		t.Fatalf("PurgeClicks: %v", err)
+	}
+	assertTotal(t, repos, link.ID, 4)
+
+	daily, err := repos.Rollups.SumHourlyRollups(hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SumHourlyRollups: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, rollup := range daily {
+		counts[rollup.Country] += rollup.Clicks
+	}
+	if counts["FR"] != 3 || counts["BE"] != 1 {
+		t.Errorf("hourly rollups = %v, want 3 FR and 1 BE", counts)
+	}
+}
@@ -0,0 +1,99 @@
+package shortcode
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Hashids génère des codes obfusqués à partir du compteur partagé, à la manière de hashids :
+// le nombre est d'abord permuté selon le sel, puis un caractère "loterie" choisit un mélange
+// de l'alphabet pour l'encoder, si bien que des valeurs consécutives donnent des codes sans lien apparent.
+type Hashids struct {
+	seq       Sequence
+	alphabet  string
+	salt      string
+	minLength int
+
+	// Permutation affine x -> (multiplier*x + increment) mod modulus des nombres qui tiennent en minLength-1 chiffres
+	modulus    uint64
+	multiplier uint64
+	increment  uint64
+}
+
+// NewHashids crée un générateur hashids.
+func NewHashids(seq Sequence, alphabet, salt string, minLength int) *Hashids {
+	g := &Hashids{seq: seq, alphabet: shuffle(alphabet, salt), salt: salt, minLength: minLength}
+
+	base := uint64(len(alphabet))
+	g.modulus = base
+	for i := 1; i < minLength-1 && g.modulus <= math.MaxUint64/2/base; i++ {
+		g.modulus *= base
+	}
+
+	// Le multiplicateur doit être premier avec la base (donc avec le modulo) pour que la permutation soit bijective
+	g.multiplier = hashSalt(salt, 0)%g.modulus | 1
+	for gcd(g.multiplier, base) != 1 {
+		g.multiplier = (g.multiplier + 2) % g.modulus
+	}
+	g.increment = hashSalt(salt, 1) % g.modulus
+	return g
+}
+
+// Generate renvoie le code obfusqué de la prochaine valeur du compteur.
+func (g *Hashids) Generate(attempt int) (string, error) {
+	n, err := g.seq.NextValue(sequenceName)
+	if err != nil {
+		return "", err
+	}
+	return g.Encode(n), nil
+}
+
+// Encode calcule le code d'un nombre. Le résultat est unique pour chaque nombre :
+// la permutation est bijective sur [0, modulus) et laisse les plus grands nombres inchangés,
+// puis le premier caractère (loterie) fixe l'alphabet utilisé pour encoder le reste.
+func (g *Hashids) Encode(n uint64) string {
+	value := n
+	if n < g.modulus {
+		hi, lo := bits.Mul64(g.multiplier, n)
+		_, value = bits.Div64(hi, lo, g.modulus)
+		value = (value + g.increment) % g.modulus
+	}
+
+	base := uint64(len(g.alphabet))
+	lottery := g.alphabet[value%base]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.salt)
+	return string(lottery) + padLeft(encode(value, alphabet), alphabet, g.minLength-1)
+}
+
+// shuffle mélange l'alphabet de façon déterministe selon le sel (algorithme "consistent shuffle" de hashids).
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+	result := []byte(alphabet)
+	for i, v, p := len(result)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		integer := int(salt[v])
+		p += integer
+		j := (integer + v + p) % i
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
+
+// hashSalt dérive un entier déterministe du sel.
+func hashSalt(salt string, seed byte) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{seed})
+	h.Write([]byte(salt))
+	return h.Sum64()
+}
+
+// gcd renvoie le plus grand diviseur commun de a et b.
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
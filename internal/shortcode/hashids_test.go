@@ -0,0 +1,61 @@
+package shortcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashidsEncodeIsUnique(t *testing.T) {
+	g := NewHashids(&counterSequence{}, DefaultAlphabet, "salt", 6)
+	seen := make(map[string]uint64)
+	for n := uint64(0); n < 20000; n++ {
+		code := g.Encode(n)
+		if len(code) < 6 {
+			t.Fatalf("Encode(%d) = %q, shorter than the minimum length", n, code)
+		}
+		if strings.Trim(code, DefaultAlphabet) != "" {
+			t.Fatalf("Encode(%d) = %q uses characters outside the alphabet", n, code)
+		}
+		if other, ok := seen[code]; ok {
+			t.Fatalf("Encode(%d) = Encode(%d) = %q", n, other, code)
+		}
+		seen[code] = n
+	}
+	// Les grands nombres, hors de la permutation, restent distincts
+	if g.Encode(1<<40) == g.Encode(1<<40+1) {
+		t.Error("large values share a code")
+	}
+}
+
+func TestHashidsDependsOnSalt(t *testing.T) {
+	a := NewHashids(&counterSequence{}, DefaultAlphabet, "one", 6)
+	b := NewHashids(&counterSequence{}, DefaultAlphabet, "two", 6)
+	same := 0
+	for n := uint64(1); n <= 100; n++ {
+		if a.Encode(n) == b.Encode(n) {
+			same++
+		}
+		if a.Encode(n) != a.Encode(n) {
+			t.Fatalf("Encode(%d) is not deterministic", n)
+		}
+	}
+	if same > 5 {
+		t.Errorf("%d of 100 codes are identical with two different salts", same)
+	}
+}
+
+func TestHashidsGenerate(t *testing.T) {
+	g := NewHashids(&counterSequence{}, DefaultAlphabet, "salt", 6)
+	first, err := g.Generate(0)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	second, _ := g.Generate(0)
+	if first != g.Encode(1) || second != g.Encode(2) {
+		t.Errorf("Generate = %q, %q, want the codes of the sequence values 1 and 2", first, second)
+	}
+	// Des valeurs consécutives ne donnent pas des codes consécutifs
+	if first[1:] == second[1:] || first[:len(first)-1] == second[:len(second)-1] {
+		t.Errorf("consecutive codes %q and %q look alike", first, second)
+	}
+}
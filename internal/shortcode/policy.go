@@ -0,0 +1,73 @@
+package shortcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// UnambiguousAlphabet exclut les caractères qui se confondent à la lecture (0/O/o, 1/l/I/i)
+// ainsi que les majuscules, pour des codes lisibles sur papier et insensibles à la casse.
+const UnambiguousAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// ErrBlockedCode est renvoyée lorsqu'un code est réservé ou contient un mot de la liste de blocage.
+var ErrBlockedCode = errors.New("short code is blocked")
+
+// reservedCodes sont des chemins servis par le routeur : un lien portant ce code serait inaccessible.
+var reservedCodes = []string{"api", "health"}
+
+// leetReplacer ramène les substitutions courantes (4 -> a, 3 -> e, ...) à leur lettre,
+// pour qu'un mot bloqué ne passe pas le filtre sous une forme déguisée.
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "@", "a", "$", "s",
+	"-", "", "_", "", "~", "",
+)
+
+// Policy est la couche de sécurité appliquée aux codes courts avant leur enregistrement et lors de leur recherche.
+// Une Policy nil est valide : elle n'applique aucune restriction et la recherche reste sensible à la casse.
+type Policy struct {
+	caseInsensitive bool
+	blocklist       []string
+}
+
+// NewPolicy construit la politique de sécurité des codes à partir de la configuration.
+func NewPolicy(opts Options) *Policy {
+	policy := &Policy{caseInsensitive: opts.CaseInsensitive}
+	for _, word := range opts.Blocklist {
+		if word = normalizeForBlocklist(word); word != "" {
+			policy.blocklist = append(policy.blocklist, word)
+		}
+	}
+	return policy
+}
+
+// CaseInsensitive indique si les codes doivent être recherchés sans tenir compte de la casse.
+func (p *Policy) CaseInsensitive() bool {
+	return p != nil && p.caseInsensitive
+}
+
+// Check renvoie ErrBlockedCode si le code est réservé ou contient un mot de la liste de blocage,
+// y compris sous forme "leet" (ex: b4d) ou avec des séparateurs.
+func (p *Policy) Check(code string) error {
+	lower := strings.ToLower(code)
+	for _, reserved := range reservedCodes {
+		if lower == reserved {
+			return ErrBlockedCode
+		}
+	}
+	if p == nil {
+		return nil
+	}
+
+	normalized := normalizeForBlocklist(code)
+	for _, word := range p.blocklist {
+		if strings.Contains(normalized, word) {
+			return ErrBlockedCode
+		}
+	}
+	return nil
+}
+
+// normalizeForBlocklist met un code ou un mot bloqué sous la forme comparée par Check.
+func normalizeForBlocklist(s string) string {
+	return leetReplacer.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
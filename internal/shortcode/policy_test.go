@@ -0,0 +1,55 @@
+package shortcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := NewPolicy(Options{Blocklist: []string{"bad", " Evil ", "", "-"}})
+	tests := []struct {
+		code    string
+		blocked bool
+	}{
+		{"abc123", false},
+		{"api", true},
+		{"API", true},
+		{"Health", true},
+		{"apis", false},
+		{"xbadx", true},
+		{"XBADX", true},
+		{"b4d", true},
+		{"b-a_d", true},
+		{"3v1l", true},
+		{"ev1l-twin", true},
+		{"bed", false},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.code)
+		if tt.blocked != errors.Is(err, ErrBlockedCode) {
+			t.Errorf("Check(%q) = %v, want blocked = %v", tt.code, err, tt.blocked)
+		}
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var policy *Policy
+	if policy.CaseInsensitive() {
+		t.Error("nil policy is case-insensitive")
+	}
+	if err := policy.Check("api"); !errors.Is(err, ErrBlockedCode) {
+		t.Errorf("nil policy Check(api) = %v, want reserved codes still blocked", err)
+	}
+	if err := policy.Check("anything"); err != nil {
+		t.Errorf("nil policy Check(anything) = %v", err)
+	}
+}
+
+func TestPolicyCaseInsensitive(t *testing.T) {
+	if NewPolicy(Options{}).CaseInsensitive() {
+		t.Error("policy is case-insensitive by default")
+	}
+	if !NewPolicy(Options{CaseInsensitive: true}).CaseInsensitive() {
+		t.Error("case_insensitive option is ignored")
+	}
+}
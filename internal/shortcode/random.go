@@ -0,0 +1,35 @@
+package shortcode
+
+// defaultGrowAfter est le nombre de collisions tolérées avant d'allonger un code aléatoire.
+const defaultGrowAfter = 2
+
+// Random génère des codes aléatoires et allonge les codes d'un caractère toutes les growAfter collisions,
+// pour que la génération continue de réussir lorsque la table se remplit.
+type Random struct {
+	length    int
+	alphabet  string
+	growAfter int
+}
+
+// NewRandom crée un générateur aléatoire. growAfter <= 0 utilise la valeur par défaut.
+func NewRandom(length int, alphabet string, growAfter int) *Random {
+	if growAfter <= 0 {
+		growAfter = defaultGrowAfter
+	}
+	return &Random{length: length, alphabet: alphabet, growAfter: growAfter}
+}
+
+// Generate renvoie un code aléatoire, plus long d'un caractère toutes les growAfter tentatives.
+func (g *Random) Generate(attempt int) (string, error) {
+	length := min(g.length+attempt/g.growAfter, MaxLength)
+
+	result := make([]byte, length)
+	for i := range result {
+		idx, err := randomIndex(len(g.alphabet))
+		if err != nil {
+			return "", err
+		}
+		result[i] = g.alphabet[idx]
+	}
+	return string(result), nil
+}
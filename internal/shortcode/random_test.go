@@ -0,0 +1,47 @@
+package shortcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandom(t *testing.T) {
+	g := NewRandom(4, "xy", 0)
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		code, err := g.Generate(0)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if len(code) != 4 || strings.Trim(code, "xy") != "" {
+			t.Fatalf("code %q, want 4 characters from the alphabet", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 2 {
+		t.Error("50 random codes are all identical")
+	}
+}
+
+func TestRandomGrowsAfterCollisions(t *testing.T) {
+	tests := []struct {
+		growAfter, attempt, want int
+	}{
+		{0, 0, 6},
+		{0, 1, 6},
+		{0, 2, 7}, // Valeur par défaut : un caractère de plus toutes les 2 collisions
+		{0, 5, 8},
+		{3, 2, 6},
+		{3, 3, 7},
+		{1, 100, MaxLength},
+	}
+	for _, tt := range tests {
+		code, err := NewRandom(6, DefaultAlphabet, tt.growAfter).Generate(tt.attempt)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if len(code) != tt.want {
+			t.Errorf("growAfter %d, attempt %d: length = %d, want %d", tt.growAfter, tt.attempt, len(code), tt.want)
+		}
+	}
+}
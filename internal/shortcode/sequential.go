@@ -0,0 +1,24 @@
+package shortcode
+
+// Sequential génère des codes à partir d'un compteur partagé encodé dans la base de l'alphabet (base62 par défaut).
+// Les codes sont courts et sans collision entre eux, mais prévisibles.
+type Sequential struct {
+	seq       Sequence
+	alphabet  string
+	minLength int
+}
+
+// NewSequential crée un générateur séquentiel.
+func NewSequential(seq Sequence, alphabet string, minLength int) *Sequential {
+	return &Sequential{seq: seq, alphabet: alphabet, minLength: minLength}
+}
+
+// Generate renvoie le code de la prochaine valeur du compteur.
+// Après une collision (code importé ou issu d'une autre stratégie), la valeur suivante est simplement utilisée.
+func (g *Sequential) Generate(attempt int) (string, error) {
+	n, err := g.seq.NextValue(sequenceName)
+	if err != nil {
+		return "", err
+	}
+	return padLeft(encode(n, g.alphabet), g.alphabet, g.minLength), nil
+}
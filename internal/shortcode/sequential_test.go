@@ -0,0 +1,32 @@
+package shortcode
+
+import "testing"
+
+func TestSequential(t *testing.T) {
+	g := NewSequential(&counterSequence{}, DefaultAlphabet, 0)
+	for _, want := range []string{"b", "c", "d"} {
+		code, err := g.Generate(0)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if code != want {
+			t.Errorf("code = %q, want %q", code, want)
+		}
+	}
+
+	padded := NewSequential(&counterSequence{}, DefaultAlphabet, 4)
+	if code, _ := padded.Generate(0); code != "aaab" {
+		t.Errorf("padded code = %q, want aaab", code)
+	}
+}
+
+func TestSequentialSharesTheSequence(t *testing.T) {
+	seq := &counterSequence{}
+	a := NewSequential(seq, DefaultAlphabet, 0)
+	b := NewSequential(seq, DefaultAlphabet, 0)
+	first, _ := a.Generate(0)
+	second, _ := b.Generate(0)
+	if first == second {
+		t.Errorf("two instances sharing a sequence produced the same code %q", first)
+	}
+}
@@ -0,0 +1,178 @@
+// Package shortcode fournit les stratégies de génération des codes courts.
+package shortcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Stratégies de génération disponibles (clé shortcode.strategy de la configuration).
+const (
+	StrategyRandom     = "random"
+	StrategySequential = "sequential"
+	StrategyHashids    = "hashids"
+	StrategyWords      = "words"
+)
+
+// DefaultAlphabet est le jeu de 62 caractères utilisé par défaut.
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// DefaultLength est la longueur par défaut des codes aléatoires.
+const DefaultLength = 6
+
+// MaxLength est la longueur maximale d'un code court (taille de la colonne links.short_code).
+const MaxLength = 32
+
+// sequenceName est le nom du compteur partagé par les stratégies sequential et hashids.
+const sequenceName = "short_codes"
+
+// ErrInvalidOptions est renvoyée lorsque la configuration d'une stratégie est invalide.
+var ErrInvalidOptions = errors.New("invalid short code options")
+
+// ErrInvalidCode est renvoyée lorsqu'un code fourni (et non généré) ne peut pas être enregistré tel quel.
+var ErrInvalidCode = errors.New("invalid short code")
+
+// CodeGenerator produit des codes courts candidats.
+// attempt est le numéro de la tentative (0 pour la première) : il est incrémenté après chaque collision,
+// ce qui permet à une stratégie d'allonger ses codes lorsque l'espace se remplit.
+type CodeGenerator interface {
+	Generate(attempt int) (string, error)
+}
+
+// Sequence fournit des valeurs entières strictement croissantes, partagées entre les instances.
+// Elle est implémentée par repository.CounterRepository.
+type Sequence interface {
+	NextValue(name string) (uint64, error)
+}
+
+// Options regroupe la configuration des stratégies de génération (section shortcode de config.yaml).
+type Options struct {
+	Strategy  string `mapstructure:"strategy"`   // random, sequential, hashids ou words
+	Length    int    `mapstructure:"length"`     // Longueur des codes (minimale pour sequential et hashids) ; 0 = valeur par défaut
+	Alphabet  string `mapstructure:"alphabet"`   // Caractères autorisés (DefaultAlphabet si vide)
+	GrowAfter int    `mapstructure:"grow_after"` // random : nombre de collisions avant d'allonger le code d'un caractère
+	Salt      string `mapstructure:"salt"`       // hashids : sel qui rend les codes imprévisibles
+	WordCount int    `mapstructure:"word_count"` // words : nombre de mots par code
+	Separator string `mapstructure:"separator"`  // words : séparateur entre les mots
+
+	Unambiguous     bool     `mapstructure:"unambiguous"`      // Remplace l'alphabet par UnambiguousAlphabet
+	CaseInsensitive bool     `mapstructure:"case_insensitive"` // Recherche des codes sans tenir compte de la casse
+	Blocklist       []string `mapstructure:"blocklist"`        // Mots interdits dans les codes générés
+}
+
+// New construit le générateur correspondant à la stratégie configurée.
+// La séquence n'est requise que par les stratégies sequential et hashids.
+// Une longueur nulle désigne la valeur par défaut : DefaultLength pour random, aucune longueur minimale pour sequential et hashids.
+func New(opts Options, seq Sequence) (CodeGenerator, error) {
+	alphabet := opts.Alphabet
+	if opts.Unambiguous {
+		alphabet = UnambiguousAlphabet
+	}
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	if opts.Length < 0 || opts.Length > MaxLength {
+		return nil, fmt.Errorf("%w: length must be between 0 (default) and %d", ErrInvalidOptions, MaxLength)
+	}
+
+	switch opts.Strategy {
+	case "", StrategyRandom:
+		length := opts.Length
+		if length == 0 {
+			length = DefaultLength
+		}
+		return NewRandom(length, alphabet, opts.GrowAfter), nil
+	case StrategySequential:
+		if seq == nil {
+			return nil, fmt.Errorf("%w: sequential strategy requires a sequence", ErrInvalidOptions)
+		}
+		return NewSequential(seq, alphabet, opts.Length), nil
+	case StrategyHashids:
+		if seq == nil {
+			return nil, fmt.Errorf("%w: hashids strategy requires a sequence", ErrInvalidOptions)
+		}
+		return NewHashids(seq, alphabet, opts.Salt, opts.Length), nil
+	case StrategyWords:
+		return NewWords(opts.WordCount, opts.Separator)
+	default:
+		return nil, fmt.Errorf("%w: unknown strategy %q", ErrInvalidOptions, opts.Strategy)
+	}
+}
+
+// validateAlphabet vérifie qu'un alphabet contient au moins deux caractères ASCII distincts et sûrs dans une URL.
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return fmt.Errorf("%w: alphabet must contain at least 2 characters", ErrInvalidOptions)
+	}
+	seen := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		if !isURLSafe(r) {
+			return fmt.Errorf("%w: alphabet character %q is not URL-safe", ErrInvalidOptions, r)
+		}
+		if seen[r] {
+			return fmt.Errorf("%w: alphabet character %q is duplicated", ErrInvalidOptions, r)
+		}
+		seen[r] = true
+	}
+	return nil
+}
+
+// ValidateCode vérifie qu'un code fourni par l'utilisateur (import d'un autre service, par exemple)
+// tient dans la colonne links.short_code et ne contient que des caractères utilisables dans un chemin.
+func ValidateCode(code string) error {
+	if code == "" || len(code) > MaxLength {
+		return fmt.Errorf("%w: length must be between 1 and %d", ErrInvalidCode, MaxLength)
+	}
+	for _, r := range code {
+		if !isURLSafe(r) {
+			return fmt.Errorf("%w: character %q is not URL-safe", ErrInvalidCode, r)
+		}
+	}
+	return nil
+}
+
+// isURLSafe indique si un caractère peut figurer tel quel dans un segment de chemin.
+func isURLSafe(r rune) bool {
+	return r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_~", r))
+}
+
+// randomIndex renvoie un entier aléatoire cryptographiquement sûr dans [0, n).
+func randomIndex(n int) (int, error) {
+	num, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return int(num.Int64()), nil
+}
+
+// encode convertit n dans la base de l'alphabet, chiffre le plus significatif en premier.
+func encode(n uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	if n == 0 {
+		return alphabet[:1]
+	}
+	var buf []byte
+	for n > 0 {
+		buf = append(buf, alphabet[n%base])
+		n /= base
+	}
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
+
+// padLeft complète un code par la gauche avec le premier caractère de l'alphabet (le "zéro")
+// jusqu'à la longueur minimale. Un nombre encodé ne commençant jamais par zéro, le résultat reste unique.
+func padLeft(code string, alphabet string, minLength int) string {
+	if len(code) >= minLength {
+		return code
+	}
+	return strings.Repeat(alphabet[:1], minLength-len(code)) + code
+}
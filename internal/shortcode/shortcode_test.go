@@ -0,0 +1,133 @@
+package shortcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// counterSequence est une Sequence en mémoire, qui commence à 1 comme repository.CounterRepository.
+type counterSequence struct {
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+func (s *counterSequence) NextValue(name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]uint64)
+	}
+	s.values[name]++
+	return s.values[name], nil
+}
+
+func TestNew(t *testing.T) {
+	seq := &counterSequence{}
+	tests := []struct {
+		opts Options
+		want string // Type du générateur attendu (vide = options invalides)
+	}{
+		{Options{}, "*shortcode.Random"},
+		{Options{Strategy: StrategyRandom, Length: MaxLength}, "*shortcode.Random"},
+		{Options{Strategy: StrategySequential}, "*shortcode.Sequential"},
+		{Options{Strategy: StrategyHashids, Salt: "s"}, "*shortcode.Hashids"},
+		{Options{Strategy: StrategyWords}, "*shortcode.Words"},
+		{Options{Strategy: "uuid"}, ""},
+		{Options{Length: -1}, ""},
+		{Options{Length: MaxLength + 1}, ""},
+		{Options{Alphabet: "a"}, ""},
+		{Options{Alphabet: "aba"}, ""},
+		{Options{Alphabet: "ab/"}, ""},
+		{Options{Strategy: StrategyWords, Separator: "."}, ""},
+		{Options{Strategy: StrategyWords, WordCount: 4}, ""},
+	}
+	for _, tt := range tests {
+		generator, err := New(tt.opts, seq)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("New(%+v) error = %v, want ErrInvalidOptions", tt.opts, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%+v): %v", tt.opts, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", generator); got != tt.want {
+			t.Errorf("New(%+v) = %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+
+	for _, strategy := range []string{StrategySequential, StrategyHashids} {
+		if _, err := New(Options{Strategy: strategy}, nil); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("New(%s) without sequence error = %v, want ErrInvalidOptions", strategy, err)
+		}
+	}
+}
+
+func TestNewDefaultLength(t *testing.T) {
+	generator, err := New(Options{}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	code, err := generator.Generate(0)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(code) != DefaultLength {
+		t.Errorf("code %q has %d characters, want the default length %d", code, len(code), DefaultLength)
+	}
+}
+
+func TestNewUnambiguousAlphabet(t *testing.T) {
+	generator, err := New(Options{Unambiguous: true, Alphabet: "ab", Length: 20}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	code, _ := generator.Generate(0)
+	for _, r := range code {
+		if !strings.ContainsRune(UnambiguousAlphabet, r) {
+			t.Fatalf("code %q uses %q, outside the unambiguous alphabet", code, r)
+		}
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	for _, code := range []string{"a", "abc-DEF_123~", strings.Repeat("x", MaxLength)} {
+		if err := ValidateCode(code); err != nil {
+			t.Errorf("ValidateCode(%q): %v", code, err)
+		}
+	}
+	for _, code := range []string{"", strings.Repeat("x", MaxLength+1), "a/b", "a b", "café", "a?b"} {
+		if err := ValidateCode(code); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("ValidateCode(%q) error = %v, want ErrInvalidCode", code, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "a"},
+		{1, "b"},
+		{61, "9"},
+		{62, "ba"},
+		{62*62 + 1, "bab"},
+	}
+	for _, tt := range tests {
+		if got := encode(tt.n, DefaultAlphabet); got != tt.want {
+			t.Errorf("encode(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if got := padLeft("b", DefaultAlphabet, 4); got != "aaab" {
+		t.Errorf("padLeft = %q, want aaab", got)
+	}
+	if got := padLeft("bcdef", DefaultAlphabet, 4); got != "bcdef" {
+		t.Errorf("padLeft of a longer code = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,93 @@
+package shortcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Valeurs par défaut de la stratégie words.
+const (
+	defaultWordCount = 3
+	defaultSeparator = "-"
+)
+
+// adjectives et nouns composent les codes lisibles (ex: brave-green-otter).
+// Les mots sont courts, en minuscules ASCII et sans ambiguïté à l'oral.
+var adjectives = []string{
+	"able", "bold", "brave", "bright", "calm", "clever", "cool", "cosy",
+	"crisp", "eager", "early", "fair", "fancy", "fast", "fresh", "gentle",
+	"glad", "golden", "grand", "green", "happy", "jolly", "keen", "kind",
+	"lively", "lucky", "merry", "mighty", "neat", "nice", "noble", "proud",
+	"quick", "quiet", "rapid", "ready", "royal", "shiny", "silent", "simple",
+	"smart", "snowy", "solid", "sunny", "super", "swift", "tidy", "tiny",
+	"vivid", "warm", "wise", "witty", "young", "zesty", "amber", "azure",
+	"coral", "ivory", "lemon", "mint", "olive", "pearl", "ruby", "silver",
+}
+
+var nouns = []string{
+	"apple", "badger", "beach", "bear", "bird", "brook", "cactus", "canyon",
+	"cedar", "cloud", "comet", "coral", "daisy", "dolphin", "eagle", "falcon",
+	"fern", "field", "forest", "fox", "garden", "harbor", "hawk", "hill",
+	"island", "koala", "lake", "lemur", "lion", "maple", "meadow", "moon",
+	"otter", "owl", "panda", "parrot", "peach", "pine", "planet", "pony",
+	"rabbit", "river", "robin", "rocket", "salmon", "seal", "shore", "sky",
+	"star", "stone", "storm", "sun", "tiger", "tulip", "valley", "violet",
+	"wave", "whale", "willow", "wind", "wolf", "yak", "zebra", "lotus",
+}
+
+// Words génère des codes lisibles composés d'adjectifs suivis d'un nom (ex: swift-golden-otter).
+// Après une collision, un suffixe numérique de plus en plus long est ajouté.
+type Words struct {
+	count     int
+	separator string
+}
+
+// NewWords crée un générateur de combinaisons de mots. count <= 0 et separator vide utilisent les valeurs par défaut.
+func NewWords(count int, separator string) (*Words, error) {
+	if count <= 0 {
+		count = defaultWordCount
+	}
+	if separator == "" {
+		separator = defaultSeparator
+	}
+	for _, r := range separator {
+		if !isURLSafe(r) {
+			return nil, fmt.Errorf("%w: separator character %q is not URL-safe", ErrInvalidOptions, r)
+		}
+	}
+	// Le plus long code possible (mots de 7 lettres, suffixe compris) doit tenir dans la colonne
+	if count*(7+len(separator))+8 > MaxLength {
+		return nil, fmt.Errorf("%w: word_count %d produces codes longer than %d characters", ErrInvalidOptions, count, MaxLength)
+	}
+	return &Words{count: count, separator: separator}, nil
+}
+
+// Generate renvoie une combinaison de mots, suivie d'un nombre de attempt+1 chiffres après une collision.
+func (g *Words) Generate(attempt int) (string, error) {
+	parts := make([]string, 0, g.count+1)
+	for i := 0; i < g.count; i++ {
+		list := adjectives
+		if i == g.count-1 {
+			list = nouns
+		}
+		idx, err := randomIndex(len(list))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, list[idx])
+	}
+
+	if attempt > 0 {
+		digits := min(attempt+1, 7)
+		var suffix strings.Builder
+		for i := 0; i < digits; i++ {
+			idx, err := randomIndex(10)
+			if err != nil {
+				return "", err
+			}
+			suffix.WriteByte(byte('0' + idx))
+		}
+		parts = append(parts, suffix.String())
+	}
+	return strings.Join(parts, g.separator), nil
+}
@@ -0,0 +1,55 @@
+package shortcode
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	g, err := NewWords(0, "")
+	if err != nil {
+		t.Fatalf("NewWords: %v", err)
+	}
+	code, err := g.Generate(0)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	parts := strings.Split(code, defaultSeparator)
+	if len(parts) != defaultWordCount {
+		t.Fatalf("code %q has %d words, want %d", code, len(parts), defaultWordCount)
+	}
+	if !slices.Contains(adjectives, parts[0]) || !slices.Contains(adjectives, parts[1]) || !slices.Contains(nouns, parts[2]) {
+		t.Errorf("code %q is not adjectives followed by a noun", code)
+	}
+	if err := ValidateCode(code); err != nil {
+		t.Errorf("generated code %q is not a valid code: %v", code, err)
+	}
+}
+
+func TestWordsSuffixAfterCollision(t *testing.T) {
+	g, err := NewWords(2, "_")
+	if err != nil {
+		t.Fatalf("NewWords: %v", err)
+	}
+	for attempt, digits := range map[int]int{1: 2, 3: 4, 20: 7} {
+		code, _ := g.Generate(attempt)
+		parts := strings.Split(code, "_")
+		if len(parts) != 3 || len(parts[2]) != digits || strings.Trim(parts[2], "0123456789") != "" {
+			t.Errorf("attempt %d: code %q, want 2 words and a %d-digit suffix", attempt, code, digits)
+		}
+		if len(code) > MaxLength {
+			t.Errorf("attempt %d: code %q is longer than %d characters", attempt, code, MaxLength)
+		}
+	}
+}
+
+func TestNewWordsRejectsInvalidOptions(t *testing.T) {
+	if _, err := NewWords(2, "/"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("separator '/' error = %v, want ErrInvalidOptions", err)
+	}
+	if _, err := NewWords(5, "-"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("5 words error = %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,52 @@
+package targeting
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PreferredLanguage renvoie la langue préférée (en minuscules, ex: "fr-fr") d'un en-tête Accept-Language,
+// c'est-à-dire celle de plus forte pondération q. Elle renvoie "" si l'en-tête est vide ou ne contient que "*".
+func PreferredLanguage(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if value, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+
+	// Tri stable : à pondération égale, l'ordre de l'en-tête est conservé
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	return tags[0].tag
+}
+
+// matchLanguage indique si la langue du visiteur correspond à la langue d'une règle.
+// Une règle "fr" correspond à "fr", "fr-fr" ou "fr-ca" ; une règle "fr-ca" ne correspond qu'à "fr-ca".
+func matchLanguage(ruleLanguage, visitorLanguage string) bool {
+	rule := strings.ToLower(ruleLanguage)
+	return visitorLanguage == rule || strings.HasPrefix(visitorLanguage, rule+"-")
+}
@@ -0,0 +1,45 @@
+package targeting
+
+import "testing"
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"*", ""},
+		{"fr-FR", "fr-fr"},
+		{"fr-FR,fr;q=0.9,en;q=0.8", "fr-fr"},
+		{"en;q=0.5, de;q=0.8", "de"},
+		{"en, fr", "en"},
+		{"fr;q=0, en;q=0.1", "en"},
+		{"*;q=1, es;q=0.3", "es"},
+		{"it;q=abc", "it"},
+	}
+	for _, tt := range tests {
+		if got := PreferredLanguage(tt.header); got != tt.want {
+			t.Errorf("PreferredLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		rule, visitor string
+		want          bool
+	}{
+		{"fr", "fr", true},
+		{"fr", "fr-ca", true},
+		{"FR-ca", "fr-ca", true},
+		{"fr-ca", "fr", false},
+		{"fr-ca", "fr-fr", false},
+		{"fr", "fry", false},
+		{"fr", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchLanguage(tt.rule, tt.visitor); got != tt.want {
+			t.Errorf("matchLanguage(%q, %q) = %v, want %v", tt.rule, tt.visitor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package targeting
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+// ErrInvalidRule est l'erreur de base renvoyée lorsqu'une règle de ciblage est invalide.
+var ErrInvalidRule = errors.New("invalid targeting rule")
+
+// countryPattern valide un code pays ISO 3166-1 alpha-2.
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// languagePattern valide un code de langue simple (ex: fr, en-US, zh-Hant).
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Visitor décrit les caractéristiques d'un visiteur utilisées pour évaluer les règles de ciblage.
+type Visitor struct {
+	OS       string // Système d'exploitation (voir models.OS*)
+	Device   string // Classe d'appareil (voir models.Device*)
+	Language string // Langue préférée, en minuscules (ex: fr-fr)
+	Country  string // Code pays ISO résolu par GeoIP ("" si inconnu ou non résolu)
+}
+
+// NewVisitor construit un Visitor à partir des en-têtes User-Agent et Accept-Language de la requête.
+func NewVisitor(userAgent, acceptLanguage string) Visitor {
+	os, device := ParseUserAgent(userAgent)
+	return Visitor{
+		OS:       os,
+		Device:   device,
+		Language: PreferredLanguage(acceptLanguage),
+	}
+}
+
+// NeedsCountry indique si au moins une règle a une condition de pays,
+// ce qui permet de n'effectuer la résolution GeoIP que lorsqu'elle est utile.
+func NeedsCountry(rules []models.TargetingRule) bool {
+	for _, rule := range rules {
+		if rule.Countries != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Match renvoie la première règle (dans l'ordre de Position) correspondant au visiteur, ou nil.
+func Match(rules []models.TargetingRule, visitor Visitor) *models.TargetingRule {
+	ordered := make([]models.TargetingRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Position < ordered[j].Position })
+
+	for i := range ordered {
+		if matches(&ordered[i], visitor) {
+			return &ordered[i]
+		}
+	}
+	return nil
+}
+
+// matches indique si toutes les conditions renseignées d'une règle correspondent au visiteur.
+func matches(rule *models.TargetingRule, visitor Visitor) bool {
+	if rule.OS != "" && !strings.EqualFold(rule.OS, visitor.OS) {
+		return false
+	}
+	if rule.Device != "" && !strings.EqualFold(rule.Device, visitor.Device) {
+		return false
+	}
+	if rule.Language != "" && !matchLanguage(rule.Language, visitor.Language) {
+		return false
+	}
+	if rule.Countries != "" && !matchCountry(rule.Countries, visitor.Country) {
+		return false
+	}
+	return true
+}
+
+// matchCountry indique si le pays du visiteur fait partie de la liste de pays d'une règle.
+// Un visiteur dont le pays est inconnu ne correspond à aucune règle géographique.
+func matchCountry(countries, visitorCountry string) bool {
+	if visitorCountry == "" {
+		return false
+	}
+	for _, country := range strings.Split(countries, ",") {
+		if strings.EqualFold(country, visitorCountry) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize valide une règle de ciblage et normalise ses conditions (minuscules).
+func Normalize(rule *models.TargetingRule) error {
+	rule.OS = strings.ToLower(strings.TrimSpace(rule.OS))
+	rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+	rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+
+	switch rule.OS {
+	case "", models.OSIOS, models.OSAndroid, models.OSWindows, models.OSMacOS, models.OSLinux, models.OSOther:
+	default:
+		return fmt.Errorf("%w: unknown os '%s'", ErrInvalidRule, rule.OS)
+	}
+	switch rule.Device {
+	case "", models.DeviceMobile, models.DeviceTablet, models.DeviceDesktop, models.DeviceBot:
+	default:
+		return fmt.Errorf("%w: unknown device '%s'", ErrInvalidRule, rule.Device)
+	}
+	if rule.Language != "" && !languagePattern.MatchString(rule.Language) {
+		return fmt.Errorf("%w: invalid language '%s'", ErrInvalidRule, rule.Language)
+	}
+
+	// Les pays peuvent être séparés par des virgules, des espaces ou des "|" ; ils sont stockés "FR,BE"
+	var countries []string
+	for _, country := range strings.FieldsFunc(rule.Countries, func(r rune) bool {
+		return r == ',' || r == '|' || r == ' '
+	}) {
+		country = strings.ToUpper(country)
+		if !countryPattern.MatchString(country) {
+			return fmt.Errorf("%w: invalid country code '%s'", ErrInvalidRule, country)
+		}
+		countries = append(countries, country)
+	}
+	rule.Countries = strings.Join(countries, ",")
+
+	if rule.OS == "" && rule.Device == "" && rule.Language == "" && rule.Countries == "" {
+		return fmt.Errorf("%w: at least one condition (os, device, language, country) is required", ErrInvalidRule)
+	}
+
+	target, err := url.ParseRequestURI(rule.TargetURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: invalid target url '%s'", ErrInvalidRule, rule.TargetURL)
+	}
+	return nil
+}
+
+// ParseRuleSpec construit une règle à partir d'une spécification textuelle utilisée par la CLI,
+// de la forme "os=ios,device=mobile,lang=fr,country=FR|BE,url=https://...". La clé url doit être la dernière :
+// tout ce qui la suit est considéré comme faisant partie de l'URL. Plusieurs pays sont séparés par "|".
+func ParseRuleSpec(spec string) (models.TargetingRule, error) {
+	var rule models.TargetingRule
+
+	rest := spec
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "url=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("%w: expected key=value, got '%s'", ErrInvalidRule, part)
+		}
+		switch strings.TrimSpace(key) {
+		case "os":
+			rule.OS = value
+		case "device":
+			rule.Device = value
+		case "lang", "language":
+			rule.Language = value
+		case "country", "countries":
+			rule.Countries = value
+		case "url":
+			rule.TargetURL = value
+		default:
+			return rule, fmt.Errorf("%w: unknown key '%s'", ErrInvalidRule, key)
+		}
+	}
+	return rule, nil
+}
@@ -0,0 +1,100 @@
+package targeting
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+func TestMatch(t *testing.T) {
+	// Règles volontairement dans le désordre : seule Position compte
+	rules := []models.TargetingRule{
+		{ID: 3, Position: 2, Language: "fr", TargetURL: "https://example.fr"},
+		{ID: 1, Position: 0, OS: models.OSIOS, TargetURL: "https://apps.apple.com"},
+		{ID: 2, Position: 1, OS: models.OSAndroid, Device: models.DeviceMobile, TargetURL: "https://play.google.com"},
+		{ID: 4, Position: 3, Countries: "BE,CH", TargetURL: "https://example.be"},
+	}
+	tests := []struct {
+		name    string
+		visitor Visitor
+		want    uint // 0 = aucune règle
+	}{
+		{"ios", Visitor{OS: models.OSIOS, Device: models.DeviceMobile, Language: "fr-fr"}, 1},
+		{"android mobile", Visitor{OS: models.OSAndroid, Device: models.DeviceMobile}, 2},
+		{"android tablet falls through", Visitor{OS: models.OSAndroid, Device: models.DeviceTablet, Language: "fr-ca"}, 3},
+		{"country", Visitor{OS: models.OSWindows, Country: "CH"}, 4},
+		{"unknown country", Visitor{OS: models.OSWindows}, 0},
+		{"no match", Visitor{OS: models.OSLinux, Language: "en-us", Country: "US"}, 0},
+	}
+	for _, tt := range tests {
+		rule := Match(rules, tt.visitor)
+		switch {
+		case tt.want == 0 && rule != nil:
+			t.Errorf("%s: matched rule %d, want none", tt.name, rule.ID)
+		case tt.want != 0 && (rule == nil || rule.ID != tt.want):
+			t.Errorf("%s: matched %+v, want rule %d", tt.name, rule, tt.want)
+		}
+	}
+	if rules[0].ID != 3 {
+		t.Error("Match reordered the link's rules")
+	}
+}
+
+func TestNewVisitor(t *testing.T) {
+	visitor := NewVisitor("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", "de-DE,de;q=0.9")
+	want := Visitor{OS: models.OSIOS, Device: models.DeviceMobile, Language: "de-de"}
+	if visitor != want {
+		t.Errorf("NewVisitor = %+v, want %+v", visitor, want)
+	}
+}
+
+func TestNeedsCountry(t *testing.T) {
+	if NeedsCountry([]models.TargetingRule{{OS: models.OSIOS}, {Language: "fr"}}) {
+		t.Error("NeedsCountry = true without country rules")
+	}
+	if !NeedsCountry([]models.TargetingRule{{OS: models.OSIOS}, {Countries: "FR"}}) {
+		t.Error("NeedsCountry = false with a country rule")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	rule := models.TargetingRule{OS: " iOS ", Device: "Mobile", Language: "FR-ca", Countries: "fr | be,ch", TargetURL: "https://example.com"}
+	if err := Normalize(&rule); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if rule.OS != models.OSIOS || rule.Device != models.DeviceMobile || rule.Language != "fr-ca" || rule.Countries != "FR,BE,CH" {
+		t.Errorf("Normalize = %+v", rule)
+	}
+
+	for _, invalid := range []models.TargetingRule{
+		{OS: "beos", TargetURL: "https://example.com"},
+		{Device: "watch", TargetURL: "https://example.com"},
+		{Language: "french!", TargetURL: "https://example.com"},
+		{Countries: "FRA", TargetURL: "https://example.com"},
+		{TargetURL: "https://example.com"},
+		{OS: models.OSIOS, TargetURL: "javascript:alert(1)"},
+		{OS: models.OSIOS, TargetURL: "/relative"},
+	} {
+		if err := Normalize(&invalid); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Normalize(%+v) error = %v, want ErrInvalidRule", invalid, err)
+		}
+	}
+}
+
+func TestParseRuleSpec(t *testing.T) {
+	rule, err := ParseRuleSpec("os=ios,device=mobile,lang=fr,country=FR|BE,url=https://example.com/?a=1,b=2")
+	if err != nil {
+		t.Fatalf("ParseRuleSpec: %v", err)
+	}
+	want := models.TargetingRule{OS: "ios", Device: "mobile", Language: "fr", Countries: "FR|BE", TargetURL: "https://example.com/?a=1,b=2"}
+	if rule != want {
+		t.Errorf("ParseRuleSpec = %+v, want %+v", rule, want)
+	}
+
+	for _, spec := range []string{"os", "color=red,url=https://example.com"} {
+		if _, err := ParseRuleSpec(spec); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("ParseRuleSpec(%q) error = %v, want ErrInvalidRule", spec, err)
+		}
+	}
+}
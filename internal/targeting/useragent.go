@@ -0,0 +1,56 @@
+package targeting
+
+import (
+	"strings"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+// botMarkers sont des fragments de User-Agent caractéristiques des robots et crawlers.
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// ParseUserAgent déduit le système d'exploitation et la classe d'appareil à partir d'un User-Agent.
+// L'analyse est volontairement simple (recherche de marqueurs connus) : elle suffit pour le ciblage
+// des grandes familles d'appareils sans dépendre d'une base de signatures externe.
+func ParseUserAgent(userAgent string) (os string, device string) {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		os = models.OSIOS
+	case strings.Contains(ua, "android"):
+		os = models.OSAndroid
+	case strings.Contains(ua, "windows"):
+		os = models.OSWindows
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		os = models.OSMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"), strings.Contains(ua, "cros"):
+		os = models.OSLinux
+	default:
+		os = models.OSOther
+	}
+
+	switch {
+	case ua == "" || containsAny(ua, botMarkers):
+		device = models.DeviceBot
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		os == models.OSAndroid && !strings.Contains(ua, "mobile"):
+		device = models.DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		device = models.DeviceMobile
+	default:
+		device = models.DeviceDesktop
+	}
+
+	return os, device
+}
+
+// containsAny indique si s contient au moins un des fragments fournis.
+func containsAny(s string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(s, fragment) {
+			return true
+		}
+	}
+	return false
+}
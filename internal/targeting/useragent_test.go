@@ -0,0 +1,32 @@
+package targeting
+
+import (
+	"testing"
+
+	"github.com/armanceau/go-url-shortener/internal/models"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		os        string
+		device    string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", models.OSIOS, models.DeviceMobile},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15", models.OSIOS, models.DeviceTablet},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", models.OSAndroid, models.DeviceMobile},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", models.OSAndroid, models.DeviceTablet},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0", models.OSWindows, models.DeviceDesktop},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15", models.OSMacOS, models.DeviceDesktop},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", models.OSLinux, models.DeviceDesktop},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", models.OSOther, models.DeviceBot},
+		{"curl/8.4.0", models.OSOther, models.DeviceBot},
+		{"", models.OSOther, models.DeviceBot},
+	}
+	for _, tt := range tests {
+		os, device := ParseUserAgent(tt.userAgent)
+		if os != tt.os || device != tt.device {
+			t.Errorf("ParseUserAgent(%q) = %s, %s, want %s, %s", tt.userAgent, os, device, tt.os, tt.device)
+		}
+	}
+}
@@ -3,36 +3,54 @@ package workers
 import (
 	"log"
 
+	"github.com/armanceau/go-url-shortener/internal/geoip"
 	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
 	"github.com/armanceau/go-url-shortener/internal/repository"
 )
 
 // StartClickWorkers lance un pool de goroutines "workers" pour traiter les événements de clic.
 // Chaque worker lira depuis le même 'clickEventsChan' et utilisera le 'clickRepo' pour la persistance.
-func StartClickWorkers(workerCount int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
+// Le 'geoResolver' (optionnel, peut être nil) enrichit chaque clic avec sa localisation avant l'enregistrement,
+// puis l'anonymizer (optionnel, nil = adresse complète) transforme l'adresse IP enregistrée.
+func StartClickWorkers(workerCount int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository, geoResolver *geoip.Resolver, anonymizer *privacy.Anonymizer) {
 	log.Printf("Starting %d click worker(s)...", workerCount)
 	for i := 0; i < workerCount; i++ {
 		// Lance chaque worker dans sa propre goroutine.
 		// Le channel est passé en lecture seule (<-chan) pour renforcer l'immutabilité du channel à l'intérieur du worker.
-		go clickWorker(clickEventsChan, clickRepo)
+		go clickWorker(clickEventsChan, clickRepo, geoResolver, anonymizer)
 	}
 }
 
 // clickWorker est la fonction exécutée par chaque goroutine worker.
 // Elle tourne indéfiniment, lisant les événements de clic dès qu'ils sont disponibles dans le channel.
-func clickWorker(clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
+func clickWorker(clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository, geoResolver *geoip.Resolver, anonymizer *privacy.Anonymizer) {
 	for event := range clickEventsChan {
+		// Enrichissement GeoIP hors du chemin de redirection (sans effet si aucune base n'est configurée),
+		// sauf pour les visiteurs qui refusent le suivi
+		var location geoip.Location
+		if !event.DoNotTrack {
+			location = geoResolver.Lookup(event.IPAddress)
+		}
+
 		click := &models.Click{
 			LinkID:    event.LinkID,
 			Timestamp: event.Timestamp,
 			UserAgent: event.UserAgent,
-			IPAddress: event.IPAddress,
+			IPAddress: anonymizer.Anonymize(event.IPAddress, event.Timestamp),
+			RuleID:    event.RuleID,
+			VariantID: event.VariantID,
+			Country:   location.Country,
+			Region:    location.Region,
+			City:      location.City,
+			ASN:       location.ASN,
+			ASOrg:     location.ASOrg,
 		}
 
 		err := clickRepo.CreateClick(click)
 		if err != nil {
 			log.Printf("ERROR: Failed to save click for LinkID %d (UserAgent: %s, IP: %s): %v",
-				event.LinkID, event.UserAgent, event.IPAddress, err)
+				event.LinkID, event.UserAgent, click.IPAddress, err)
 		} else {
 			log.Printf("Click recorded successfully for LinkID %d", event.LinkID)
 		}
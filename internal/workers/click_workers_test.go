@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/geoip"
+	"github.com/armanceau/go-url-shortener/internal/migrations"
+	"github.com/armanceau/go-url-shortener/internal/models"
+	"github.com/armanceau/go-url-shortener/internal/privacy"
+	"github.com/armanceau/go-url-shortener/internal/repository"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// processClicks fait traiter les événements par un worker et renvoie les clics enregistrés, dans l'ordre.
+func processClicks(t *testing.T, geoResolver *geoip.Resolver, anonymizer *privacy.Anonymizer, events ...models.ClickEvent) []models.Click {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := migrations.Up(db, false); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	eventsChan := make(chan models.ClickEvent, len(events))
+	for _, event := range events {
+		eventsChan <- event
+	}
+	close(eventsChan)
+	// Le worker rend la main une fois le channel fermé et vidé
+	clickWorker(eventsChan, repository.NewClickRepository(db), geoResolver, anonymizer)
+
+	var clicks []models.Click
+	if err := db.Order("id").Find(&clicks).Error; err != nil {
+		t.Fatalf("find clicks: %v", err)
+	}
+	if len(clicks) != len(events) {
+		t.Fatalf("%d clicks recorded, want %d", len(clicks), len(events))
+	}
+	return clicks
+}
+
+func TestClickWorkerEnrichesClicks(t *testing.T) {
+	resolver, err := geoip.Open("../geoip/testdata/GeoIP2-City-Test.mmdb", "../geoip/testdata/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatalf("geoip.Open: %v", err)
+	}
+	defer resolver.Close()
+
+	at := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	clicks := processClicks(t, resolver, nil,
+		models.ClickEvent{LinkID: 1, Timestamp: at, IPAddress: "81.2.69.160", UserAgent: "test"},
+		models.ClickEvent{LinkID: 1, Timestamp: at.Add(time.Second), IPAddress: "81.2.69.161", DoNotTrack: true},
+		models.ClickEvent{LinkID: 1, Timestamp: at.Add(2 * time.Second), IPAddress: "192.0.2.1"},
+	)
+
+	paris := clicks[0]
+	if paris.Country != "FR" || paris.Region != "Île-de-France" || paris.City != "Paris" || paris.ASN != 64500 || paris.ASOrg != "Example Transit" {
+		t.Errorf("enriched click = %+v, want Paris on AS64500", paris)
+	}
+	if paris.IPAddress != "81.2.69.160" || paris.UserAgent != "test" {
+		t.Errorf("click IP = %q, User-Agent = %q", paris.IPAddress, paris.UserAgent)
+	}
+	if dnt := clicks[1]; dnt.Country != "" || dnt.City != "" || dnt.ASN != 0 {
+		t.Errorf("Do Not Track click was located: %+v", dnt)
+	}
+	if unknown := clicks[2]; unknown.Country != "" || unknown.ASN != 0 {
+		t.Errorf("address missing from the database was located: %+v", unknown)
+	}
+}
+
+func TestClickWorkerWithoutGeoDatabase(t *testing.T) {
+	anonymizer, err := privacy.New(privacy.Options{IPMode: privacy.ModeTruncate, IPv4Prefix: 24, IPv6Prefix: 48})
+	if err != nil {
+		t.Fatalf("privacy.New: %v", err)
+	}
+	clicks := processClicks(t, nil, anonymizer, models.ClickEvent{LinkID: 1, Timestamp: time.Now(), IPAddress: "81.2.69.160"})
+	if clicks[0].Country != "" || clicks[0].IPAddress != "81.2.69.0" {
+		t.Errorf("click = %+v, want no location and a truncated address", clicks[0])
+	}
+}
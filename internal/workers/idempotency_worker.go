@@ -0,0 +1,27 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/services"
+)
+
+// StartIdempotencyPurgeWorker lance dans sa propre goroutine la suppression périodique des clés d'idempotence expirées.
+// Une clé expirée est déjà ignorée à la lecture : la purge ne fait que libérer de la place.
+func StartIdempotencyPurgeWorker(idempotencyService *services.IdempotencyService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			deleted, err := idempotencyService.PurgeExpired(time.Now())
+			if err != nil {
+				log.Printf("ERROR: Idempotency key purge failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Idempotency key purge: %d expired key(s) deleted", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/services"
+)
+
+// StartRetentionWorker lance dans sa propre goroutine la purge périodique des clics bruts plus anciens que retention.
+// Les clics sont agrégés avant d'être supprimés, les statistiques restent donc exactes.
+func StartRetentionWorker(rollupService *services.RollupService, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			now := time.Now()
+			deleted, cutoff, err := rollupService.PurgeClicks(now, now.Add(-retention), false)
+			if err != nil {
+				log.Printf("ERROR: Click purge failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Click purge: %d click(s) recorded before %s deleted", deleted, cutoff.Format(time.RFC3339))
+			}
+			<-ticker.C
+		}
+	}()
+}
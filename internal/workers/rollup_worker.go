@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"log"
+	"time"
+
+	"github.com/armanceau/go-url-shortener/internal/services"
+)
+
+// StartRollupWorker lance dans sa propre goroutine l'agrégation périodique des clics.
+// Une première agrégation a lieu au démarrage pour rattraper les heures écoulées depuis le dernier arrêt.
+// Plusieurs instances peuvent l'exécuter : chaque période est remplacée dans une transaction.
+func StartRollupWorker(rollupService *services.RollupService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			hours, days, err := rollupService.Compact(time.Now())
+			if err != nil {
+				log.Printf("ERROR: Click rollup failed: %v", err)
+			} else if hours > 0 || days > 0 {
+				log.Printf("Click rollup: %d hour(s) and %d day(s) aggregated", hours, days)
+			}
+			<-ticker.C
+		}
+	}()
+}